@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"zbz/catalog"
 )
 
 // TestUser for testing query generation
@@ -540,4 +542,36 @@ func normalizeSQL(sql string) string {
 	// Replace multiple spaces with single space
 	parts := strings.Fields(sql)
 	return strings.Join(parts, " ")
-}
\ No newline at end of file
+}
+type searchableAccount struct {
+	ID    string `json:"id"`
+	Email string `json:"email" encrypt:"owner" encrypt_algo:"blind-index"`
+	SSN   string `json:"ssn" db:"tax_id" encrypt:"owner" encrypt_algo:"AES-SIV"`
+}
+
+func TestSearchableEncryptionFields(t *testing.T) {
+	ast, err := ParseFromMetadata(catalog.Select[searchableAccount](), OpSelect)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	hasField := false
+	for _, field := range ast.Fields {
+		if field.Name == "email_bidx" {
+			hasField = true
+		}
+	}
+	if !hasField {
+		t.Errorf("Expected blind index companion column, got %v", ast.Fields)
+	}
+
+	hints := map[string]bool{}
+	for _, hint := range ast.Hints {
+		if hint.Type == "index" {
+			hints[hint.Value] = true
+		}
+	}
+	if !hints["email_bidx:hash"] || !hints["tax_id:hash"] {
+		t.Errorf("Expected equality indexes on searchable columns, got %v", ast.Hints)
+	}
+}
@@ -53,6 +53,25 @@ func ParseFromMetadata(metadata catalog.ModelMetadata, operation OperationType)
 				}
 			}
 		}
+
+		// Searchable encryption is matched by equality on the stored value:
+		// AES-SIV on the column itself, blind indexes on a companion column
+		if field.Encryption.BlindIndex != "" {
+			ast.Fields = append(ast.Fields, Field{
+				Name: field.Encryption.BlindIndex,
+			})
+			ast.Hints = append(ast.Hints, Hint{
+				Provider: "sql",
+				Type:     "index",
+				Value:    field.Encryption.BlindIndex + ":hash",
+			})
+		} else if field.Encryption.Deterministic {
+			ast.Hints = append(ast.Hints, Hint{
+				Provider: "sql",
+				Type:     "index",
+				Value:    ast.Fields[len(ast.Fields)-1].Name + ":hash",
+			})
+		}
 	}
 
 	// Add default conditions based on operation
@@ -0,0 +1,37 @@
+package capitan
+
+import (
+	"encoding/json"
+
+	"zbz/cereal"
+)
+
+// AccessAuditedHook is the hook type cereal access audit records are emitted under
+const AccessAuditedHook = "CerealAccessAudited"
+
+// AuditSink returns a cereal audit sink that emits every record as an
+// AccessAuditedHook event. Auditing is opt-in:
+//
+//	cereal.SetAuditSink(capitan.AuditSink())
+func AuditSink() cereal.AuditSink {
+	return cereal.AuditFunc(func(record cereal.AuditRecord) error {
+		// encoding/json, not cereal: marshaling through cereal would audit the audit
+		eventBytes, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return serviceManager.emitBytes(AccessAuditedHook, eventBytes)
+	})
+}
+
+// OnAccessAudit registers a handler for cereal access audit records
+func OnAccessAudit(handler func(record cereal.AuditRecord)) {
+	RegisterByteHandler(AccessAuditedHook, func(data []byte) error {
+		var record cereal.AuditRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		handler(record)
+		return nil
+	})
+}
@@ -28,7 +28,7 @@ func ExampleSimplifiedAPI() {
 	}
 	
 	// Type discovery
-	allTypes := Browse() // []string{"User", "Order", ...}
+	allTypes := Browse() // []TypeRef{{Name: "User", QualifiedName: "zbz/catalog.User"}, ...}
 	
 	// Type name extraction
 	userTypeName := GetTypeName[User]() // "User"
@@ -2,6 +2,7 @@ package catalog
 
 import (
 	"reflect"
+	"sort"
 )
 
 // PUBLIC API - Only two functions exposed
@@ -11,37 +12,26 @@ import (
 // This is the ONLY way to get metadata - always works
 func Select[T any]() ModelMetadata {
 	var zero T
-	t := reflect.TypeOf(zero)
-	typeName := getTypeName(t)
-	
-	// Check cache first
-	cacheMutex.RLock()
-	if cached, exists := metadataCache[typeName]; exists {
-		cacheMutex.RUnlock()
-		return cached
-	}
-	cacheMutex.RUnlock()
-	
-	// Extract and cache metadata
-	metadata := extractMetadata(t, zero)
-	
-	cacheMutex.Lock()
-	metadataCache[typeName] = metadata
-	cacheMutex.Unlock()
-	
-	return metadata
+	return loadMetadata(reflect.TypeOf(zero), zero)
 }
 
-// Browse returns all type names that have been registered in the catalog
+// TypeRef names a catalogued type in both short and fully-qualified form
+type TypeRef struct {
+	Name          string `json:"name"`           // Short name, e.g. "User"
+	QualifiedName string `json:"qualified_name"` // Package path, name and type arguments, e.g. "example.com/billing.User"
+}
+
+// Browse returns all types that have been registered in the catalog
 // Useful for type discovery and debugging
-func Browse() []string {
+func Browse() []TypeRef {
 	cacheMutex.RLock()
 	defer cacheMutex.RUnlock()
 	
-	var types []string
-	for typeName := range metadataCache {
-		types = append(types, typeName)
+	types := make([]TypeRef, 0, len(metadataCache))
+	for identity, metadata := range metadataCache {
+		types = append(types, TypeRef{Name: metadata.TypeName, QualifiedName: identity})
 	}
+	sort.Slice(types, func(i, j int) bool { return types[i].QualifiedName < types[j].QualifiedName })
 	return types
 }
 
@@ -52,6 +42,12 @@ func GetTypeName[T any]() string {
 	return getTypeName(reflect.TypeOf(zero))
 }
 
+// GetQualifiedTypeName returns the fully-qualified identity used as the catalog key
+func GetQualifiedTypeName[T any]() string {
+	var zero T
+	return typeIdentity(reflect.TypeOf(zero))
+}
+
 // Internal helper functions (not exported)
 
 // ensureMetadata is now incorporated directly into Select[T]()
@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Convention is a named interface contract that models can implement.
+// Packages register the conventions they care about; catalog detects them
+// for every type and reports them in ModelMetadata.Functions.
+type Convention struct {
+	Name      string       `json:"name"`
+	Owner     string       `json:"owner"` // Registering package, e.g. "cereal"
+	Interface reflect.Type `json:"-"`
+	Methods   []string     `json:"methods"` // Method signatures, e.g. "GetRequiredScopes() []string"
+}
+
+// Convention registry
+var (
+	conventions     = make(map[string]Convention)
+	conventionMutex sync.RWMutex
+)
+
+// RegisterConvention registers interface type I as a named convention.
+// Types already in the catalog are re-scanned so registration order does not matter.
+func RegisterConvention[I any](name, owner string) error {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		return fmt.Errorf("convention %s: %s is not an interface", name, iface)
+	}
+	if iface.NumMethod() == 0 {
+		return fmt.Errorf("convention %s: interface has no methods", name)
+	}
+
+	conventionMutex.Lock()
+	if existing, exists := conventions[name]; exists && existing.Interface != iface {
+		conventionMutex.Unlock()
+		return fmt.Errorf("convention %s already registered by %s", name, existing.Owner)
+	}
+	convention := Convention{
+		Name:      name,
+		Owner:     owner,
+		Interface: iface,
+	}
+	for i := 0; i < iface.NumMethod(); i++ {
+		method := iface.Method(i)
+		convention.Methods = append(convention.Methods, method.Name+methodSignature(method.Type))
+	}
+	conventions[name] = convention
+	conventionMutex.Unlock()
+
+	refreshConventions()
+	return nil
+}
+
+// Conventions returns all registered conventions sorted by name
+func Conventions() []Convention {
+	conventionMutex.RLock()
+	defer conventionMutex.RUnlock()
+
+	result := make([]Convention, 0, len(conventions))
+	for _, convention := range conventions {
+		result = append(result, convention)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Implements reports whether T (or *T) implements the named convention
+func Implements[T any](name string) bool {
+	return Select[T]().HasConvention(name)
+}
+
+// HasConvention reports whether the model implements the named convention
+func (m ModelMetadata) HasConvention(name string) bool {
+	for _, function := range m.Functions {
+		if function.Convention == name {
+			return true
+		}
+	}
+	return false
+}
+
+// extractFunctionMetadata discovers registered conventions implemented by the
+// type, checking both value and pointer receivers
+func extractFunctionMetadata(t reflect.Type) []FunctionInfo {
+	var functions []FunctionInfo
+	if t == nil {
+		return functions
+	}
+
+	base := derefType(t)
+	pointer := reflect.PointerTo(base)
+
+	for _, convention := range Conventions() {
+		var receiver string
+		switch {
+		case base.Implements(convention.Interface):
+			receiver = "value"
+		case pointer.Implements(convention.Interface):
+			receiver = "pointer"
+		default:
+			continue
+		}
+
+		for i := 0; i < convention.Interface.NumMethod(); i++ {
+			method := convention.Interface.Method(i)
+			functions = append(functions, FunctionInfo{
+				Name:       method.Name,
+				Type:       "method",
+				Receiver:   receiver,
+				Signature:  methodSignature(method.Type),
+				Convention: convention.Name,
+			})
+		}
+	}
+
+	return functions
+}
+
+// refreshConventions re-detects conventions for every cached type
+func refreshConventions() {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	for typeName, metadata := range metadataCache {
+		if t, exists := typeRegistry[typeName]; exists {
+			metadata.Functions = extractFunctionMetadata(t)
+			metadataCache[typeName] = metadata
+		}
+	}
+}
+
+// methodSignature renders a method type without the func keyword: "() []string"
+func methodSignature(t reflect.Type) string {
+	return strings.TrimPrefix(t.String(), "func")
+}
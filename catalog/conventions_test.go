@@ -0,0 +1,78 @@
+package catalog
+
+import "testing"
+
+type testAuditable interface {
+	AuditTrail() []string
+}
+
+type testValueAudited struct {
+	Name string `json:"name"`
+}
+
+func (testValueAudited) AuditTrail() []string { return nil }
+
+type testPointerAudited struct {
+	Name string `json:"name"`
+}
+
+func (*testPointerAudited) AuditTrail() []string { return nil }
+
+type testLateConvention interface {
+	LateHook() error
+}
+
+type testLateModel struct{}
+
+func (testLateModel) LateHook() error { return nil }
+
+func TestConventions_ValueAndPointerReceivers(t *testing.T) {
+	if err := RegisterConvention[testAuditable]("TestAuditable", "catalog"); err != nil {
+		t.Fatalf("Unexpected registration error: %v", err)
+	}
+
+	metadata := Select[testValueAudited]()
+	if !metadata.HasConvention("TestAuditable") {
+		t.Fatal("Expected value receiver to satisfy convention")
+	}
+	if metadata.Functions[0].Receiver != "value" || metadata.Functions[0].Signature != "() []string" {
+		t.Errorf("Unexpected function info: %+v", metadata.Functions[0])
+	}
+
+	if !Implements[testPointerAudited]("TestAuditable") {
+		t.Fatal("Expected pointer receiver to satisfy convention")
+	}
+	for _, function := range Select[testPointerAudited]().Functions {
+		if function.Convention == "TestAuditable" && function.Receiver != "pointer" {
+			t.Errorf("Expected pointer receiver, got '%s'", function.Receiver)
+		}
+	}
+
+	if Implements[testAddress]("TestAuditable") {
+		t.Error("testAddress should not implement TestAuditable")
+	}
+}
+
+func TestConventions_RegistrationRescansCache(t *testing.T) {
+	if Implements[testLateModel]("TestLate") {
+		t.Fatal("Convention should not be detected before registration")
+	}
+	if err := RegisterConvention[testLateConvention]("TestLate", "catalog"); err != nil {
+		t.Fatalf("Unexpected registration error: %v", err)
+	}
+	if !Implements[testLateModel]("TestLate") {
+		t.Error("Cached metadata should be re-scanned when a convention is registered")
+	}
+}
+
+func TestConventions_RegistrationErrors(t *testing.T) {
+	if err := RegisterConvention[testAddress]("NotAnInterface", "catalog"); err == nil {
+		t.Error("Expected error for non-interface convention")
+	}
+	if err := RegisterConvention[testAuditable]("TestConflict", "catalog"); err != nil {
+		t.Fatalf("Unexpected registration error: %v", err)
+	}
+	if err := RegisterConvention[testLateConvention]("TestConflict", "other"); err == nil {
+		t.Error("Expected error when a name is reused for a different interface")
+	}
+}
@@ -0,0 +1,267 @@
+package catalog
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ChangeKind classifies a difference between two versions of a model
+type ChangeKind string
+
+const (
+	FieldAdded          ChangeKind = "field_added"
+	FieldRemoved        ChangeKind = "field_removed"
+	FieldRetyped        ChangeKind = "field_retyped"
+	ScopeTightened      ChangeKind = "scope_tightened"
+	ScopeLoosened       ChangeKind = "scope_loosened"
+	ValidationTightened ChangeKind = "validation_tightened"
+	ValidationLoosened  ChangeKind = "validation_loosened"
+	EncryptionChanged   ChangeKind = "encryption_changed"
+	ConventionAdded     ChangeKind = "convention_added"
+	ConventionRemoved   ChangeKind = "convention_removed"
+	ModelAdded          ChangeKind = "model_added"
+)
+
+// Change is a single classified difference, keyed by field path
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	Path     string     `json:"path,omitempty"`
+	Old      string     `json:"old,omitempty"`
+	New      string     `json:"new,omitempty"`
+	Breaking bool       `json:"breaking"`
+}
+
+// String renders a change for CI output
+func (c Change) String() string {
+	severity := "compatible"
+	if c.Breaking {
+		severity = "BREAKING"
+	}
+	return fmt.Sprintf("%s %s %s: %q -> %q", severity, c.Kind, c.Path, c.Old, c.New)
+}
+
+// SchemaDiff is the result of comparing two versions of a model
+type SchemaDiff struct {
+	TypeName string   `json:"type_name"`
+	Changes  []Change `json:"changes"`
+}
+
+// IsBreaking reports whether any change breaks existing clients or stored data
+func (d SchemaDiff) IsBreaking() bool {
+	for _, change := range d.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking returns only the breaking changes
+func (d SchemaDiff) Breaking() []Change {
+	var breaking []Change
+	for _, change := range d.Changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+// Diff compares two versions of a model's metadata field by field, walking
+// the whole type graph. Fields are matched by path, so a JSON rename shows up
+// as a removal plus an addition.
+func Diff(old, new ModelMetadata) SchemaDiff {
+	diff := SchemaDiff{TypeName: new.QualifiedName}
+	if diff.TypeName == "" {
+		diff.TypeName = old.QualifiedName
+	}
+
+	oldFields := indexFields(old)
+	newFields := indexFields(new)
+
+	for _, path := range sortedPaths(oldFields) {
+		oldField := oldFields[path]
+		newField, exists := newFields[path]
+		if !exists {
+			diff.Changes = append(diff.Changes, Change{Kind: FieldRemoved, Path: path, Old: oldField.Type, Breaking: true})
+			continue
+		}
+		diff.Changes = append(diff.Changes, diffField(path, oldField, newField)...)
+	}
+
+	for _, path := range sortedPaths(newFields) {
+		if _, exists := oldFields[path]; exists {
+			continue
+		}
+		newField := newFields[path]
+		// New optional fields are ignored by old clients; required ones reject their payloads
+		diff.Changes = append(diff.Changes, Change{Kind: FieldAdded, Path: path, New: newField.Type, Breaking: newField.Validation.Required})
+	}
+
+	diff.Changes = append(diff.Changes, diffConventions(old, new)...)
+	return diff
+}
+
+// diffField compares two versions of the same field
+func diffField(path string, old, new FieldMetadata) []Change {
+	var changes []Change
+
+	if old.Type != new.Type {
+		changes = append(changes, Change{Kind: FieldRetyped, Path: path, Old: old.Type, New: new.Type, Breaking: true})
+	}
+
+	if change, changed := diffScopes(path, old.Scopes, new.Scopes); changed {
+		changes = append(changes, change)
+	}
+
+	changes = append(changes, diffValidation(path, old.Validation, new.Validation)...)
+
+	if old.Encryption.Type != new.Encryption.Type || old.Encryption.Algorithm != new.Encryption.Algorithm {
+		// Stored ciphertext no longer matches what readers expect
+		changes = append(changes, Change{
+			Kind:     EncryptionChanged,
+			Path:     path,
+			Old:      encryptionLabel(old.Encryption),
+			New:      encryptionLabel(new.Encryption),
+			Breaking: true,
+		})
+	}
+
+	return changes
+}
+
+// diffScopes classifies scope changes. Scope entries are OR'd groups, so
+// removing a group (or scoping a public field) can lock existing callers out,
+// while adding a group (or unscoping a field) only grants access.
+func diffScopes(path string, old, new []string) (Change, bool) {
+	oldLabel, newLabel := strings.Join(old, ","), strings.Join(new, ",")
+	if oldLabel == newLabel {
+		return Change{}, false
+	}
+
+	// Unscoping a field never tightens; scoping a public one always does
+	tightened := len(old) == 0 && len(new) > 0
+	if len(old) > 0 && len(new) > 0 {
+		for _, group := range old {
+			if !slices.Contains(new, group) {
+				tightened = true
+				break
+			}
+		}
+	}
+
+	if tightened {
+		return Change{Kind: ScopeTightened, Path: path, Old: oldLabel, New: newLabel, Breaking: true}, true
+	}
+	return Change{Kind: ScopeLoosened, Path: path, Old: oldLabel, New: newLabel}, true
+}
+
+// diffValidation classifies validation changes. Anything that can reject
+// previously valid input is breaking; removing rules is compatible.
+func diffValidation(path string, old, new ValidationInfo) []Change {
+	var changes []Change
+
+	if old.Required != new.Required {
+		kind := ValidationLoosened
+		if new.Required {
+			kind = ValidationTightened
+		}
+		changes = append(changes, Change{Kind: kind, Path: path, Old: fmt.Sprint("required=", old.Required), New: fmt.Sprint("required=", new.Required), Breaking: new.Required})
+	}
+
+	for _, rule := range sortedKeys(old.Constraints, new.Constraints) {
+		oldValue, hadOld := old.Constraints[rule]
+		newValue, hasNew := new.Constraints[rule]
+		switch {
+		case hadOld && !hasNew:
+			changes = append(changes, Change{Kind: ValidationLoosened, Path: path, Old: rule + "=" + oldValue})
+		case !hadOld && hasNew:
+			changes = append(changes, Change{Kind: ValidationTightened, Path: path, New: rule + "=" + newValue, Breaking: true})
+		case oldValue != newValue:
+			// Direction depends on the rule; treat any changed bound as breaking
+			changes = append(changes, Change{Kind: ValidationTightened, Path: path, Old: rule + "=" + oldValue, New: rule + "=" + newValue, Breaking: true})
+		}
+	}
+
+	for _, rule := range old.CustomRules {
+		if !slices.Contains(new.CustomRules, rule) {
+			changes = append(changes, Change{Kind: ValidationLoosened, Path: path, Old: rule})
+		}
+	}
+	for _, rule := range new.CustomRules {
+		if !slices.Contains(old.CustomRules, rule) {
+			changes = append(changes, Change{Kind: ValidationTightened, Path: path, New: rule, Breaking: true})
+		}
+	}
+
+	return changes
+}
+
+// diffConventions reports conventions gained or lost by the model
+func diffConventions(old, new ModelMetadata) []Change {
+	var changes []Change
+	oldConventions, newConventions := conventionNames(old), conventionNames(new)
+
+	for _, name := range oldConventions {
+		if !slices.Contains(newConventions, name) {
+			changes = append(changes, Change{Kind: ConventionRemoved, Old: name, Breaking: true})
+		}
+	}
+	for _, name := range newConventions {
+		if !slices.Contains(oldConventions, name) {
+			changes = append(changes, Change{Kind: ConventionAdded, New: name})
+		}
+	}
+	return changes
+}
+
+func indexFields(metadata ModelMetadata) map[string]FieldMetadata {
+	index := make(map[string]FieldMetadata)
+	for _, field := range metadata.AllFields() {
+		index[field.Path] = field
+	}
+	return index
+}
+
+func sortedPaths(fields map[string]FieldMetadata) []string {
+	paths := make([]string, 0, len(fields))
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func sortedKeys(maps ...map[string]string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func conventionNames(metadata ModelMetadata) []string {
+	var names []string
+	for _, function := range metadata.Functions {
+		if function.Convention != "" && !slices.Contains(names, function.Convention) {
+			names = append(names, function.Convention)
+		}
+	}
+	return names
+}
+
+func encryptionLabel(info EncryptionInfo) string {
+	if info.Algorithm == "" {
+		return info.Type
+	}
+	return info.Type + "/" + info.Algorithm
+}
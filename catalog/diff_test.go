@@ -0,0 +1,158 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testAccountV1 struct {
+	ID      string      `json:"id"`
+	Email   string      `json:"email" scope:"user,admin" validate:"email"`
+	Age     int         `json:"age" validate:"min=13"`
+	Nick    string      `json:"nick"`
+	Address testAddress `json:"address"`
+}
+
+type testAccountV2 struct {
+	ID      int64       `json:"id"`
+	Email   string      `json:"email" scope:"admin" validate:"required,email"`
+	Age     int         `json:"age"`
+	Bio     string      `json:"bio"`
+	Address testAddress `json:"address"`
+}
+
+func findChange(diff SchemaDiff, kind ChangeKind, path string) (Change, bool) {
+	for _, change := range diff.Changes {
+		if change.Kind == kind && change.Path == path {
+			return change, true
+		}
+	}
+	return Change{}, false
+}
+
+func TestDiff_ClassifiesChanges(t *testing.T) {
+	diff := Diff(Select[testAccountV1](), Select[testAccountV2]())
+
+	cases := []struct {
+		kind     ChangeKind
+		path     string
+		breaking bool
+	}{
+		{FieldRetyped, "id", true},
+		{ScopeTightened, "email", true},
+		{ValidationTightened, "email", true},
+		{ValidationLoosened, "age", false},
+		{FieldRemoved, "nick", true},
+		{FieldAdded, "bio", false},
+	}
+	for _, tc := range cases {
+		change, ok := findChange(diff, tc.kind, tc.path)
+		if !ok {
+			t.Errorf("Expected %s change on %s, got %v", tc.kind, tc.path, diff.Changes)
+			continue
+		}
+		if change.Breaking != tc.breaking {
+			t.Errorf("%s on %s: expected breaking=%v", tc.kind, tc.path, tc.breaking)
+		}
+	}
+
+	for _, change := range diff.Changes {
+		if change.Path == "address" || change.Path == "address.street" {
+			t.Errorf("Unchanged nested fields should not be reported: %v", change)
+		}
+	}
+	if !diff.IsBreaking() {
+		t.Error("Expected diff to be breaking")
+	}
+}
+
+func TestDiff_IdenticalAndLoosened(t *testing.T) {
+	if diff := Diff(Select[testAccountV1](), Select[testAccountV1]()); len(diff.Changes) != 0 {
+		t.Errorf("Expected no changes for identical metadata, got %v", diff.Changes)
+	}
+
+	diff := Diff(Select[testAccountV2](), Select[testAccountV1]())
+	change, ok := findChange(diff, ScopeLoosened, "email")
+	if !ok || change.Breaking {
+		t.Errorf("Adding a scope group should be a compatible loosening, got %v", diff.Changes)
+	}
+}
+
+func TestDiff_AgainstStoredSnapshot(t *testing.T) {
+	stored, err := json.Marshal(Select[testAccountV1]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot ModelMetadata
+	if err := json.Unmarshal(stored, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := Diff(snapshot, Select[testAccountV1]()); len(diff.Changes) != 0 {
+		t.Errorf("Round-tripped snapshot should match live metadata, got %v", diff.Changes)
+	}
+}
+
+type testRegistered struct {
+	Name    string      `json:"name"`
+	Address testAddress `json:"address"`
+}
+
+func TestRegister_AppliesOptions(t *testing.T) {
+	metadata, err := Register[testRegistered](RegisterOptions{
+		Description:       "A registered model",
+		Examples:          map[string]any{"basic": testRegistered{Name: "Ada"}},
+		FieldDescriptions: map[string]string{"address.city": "City name"},
+		FieldExamples:     map[string]any{"name": "Ada"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	selected := Select[testRegistered]()
+	if selected.Description != "A registered model" || metadata.Description != selected.Description {
+		t.Error("Select should return the registered metadata")
+	}
+	city := selected.Fields[1].Fields[1]
+	if city.Path != "address.city" || city.Description != "City name" {
+		t.Errorf("Expected nested field description, got %+v", city)
+	}
+	if selected.JSONSchema["description"] != "A registered model" {
+		t.Error("Schema should be regenerated with registered options")
+	}
+	if examples, ok := selected.JSONSchema["examples"].([]any); !ok || len(examples) != 1 {
+		t.Errorf("Expected model examples in schema, got %v", selected.JSONSchema["examples"])
+	}
+
+	found := false
+	for _, ref := range Browse() {
+		found = found || ref.QualifiedName == GetQualifiedTypeName[testRegistered]()
+	}
+	if !found {
+		t.Error("Registered type should be browsable")
+	}
+}
+
+func TestRegister_UnknownFieldPath(t *testing.T) {
+	_, err := Register[testRegistered](RegisterOptions{FieldDescriptions: map[string]string{"missing": "x"}})
+	if err == nil {
+		t.Error("Expected error for unknown field path")
+	}
+}
+
+type testRegisteredPointer struct {
+	Name string `json:"name"`
+}
+
+func TestRegister_SurvivesPointerExtraction(t *testing.T) {
+	MustRegister[testRegisteredPointer](RegisterOptions{Description: "registered"})
+
+	value := testRegisteredPointer{Name: "Ada"}
+	if metadata := ExtractAndCacheMetadata(&value); metadata.Description != "registered" {
+		t.Errorf("Expected a pointer extraction to reuse the registered metadata, got %q", metadata.Description)
+	}
+	if metadata := Select[testRegisteredPointer](); metadata.Description != "registered" {
+		t.Errorf("Expected registered options to survive a pointer extraction, got %q", metadata.Description)
+	}
+}
@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"errors"
+	"testing"
+
+	"zbz/catalog/internal/fixtures/auth"
+	"zbz/catalog/internal/fixtures/billing"
+)
+
+type testPage[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestIdentity_SameNameDifferentPackages(t *testing.T) {
+	billingUser := Select[billing.User]()
+	authUser := Select[auth.User]()
+
+	if billingUser.QualifiedName != "zbz/catalog/internal/fixtures/billing.User" {
+		t.Errorf("Unexpected qualified name: %s", billingUser.QualifiedName)
+	}
+	if billingUser.TypeName != "User" || authUser.TypeName != "User" {
+		t.Error("Short names should remain the bare type name")
+	}
+	if len(billingUser.Fields) != 1 || billingUser.Fields[0].Name != "AccountID" {
+		t.Error("billing.User metadata was overwritten by a colliding type")
+	}
+	if len(authUser.Fields) != 1 || authUser.Fields[0].Name != "Subject" {
+		t.Error("auth.User metadata was overwritten by a colliding type")
+	}
+
+	if _, err := Lookup("User"); !errors.Is(err, ErrAmbiguousType) {
+		t.Errorf("Expected ambiguity error for short name, got %v", err)
+	}
+	if _, ok := GetModelMetadata("User"); ok {
+		t.Error("Ambiguous short name should not resolve")
+	}
+	metadata, err := Lookup(authUser.QualifiedName)
+	if err != nil || metadata.Fields[0].Name != "Subject" {
+		t.Errorf("Qualified lookup failed: %v", err)
+	}
+	if _, err := Lookup("NoSuchType"); !errors.Is(err, ErrTypeNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestIdentity_GenericsAndAnonymous(t *testing.T) {
+	userPage := GetQualifiedTypeName[testPage[billing.User]]()
+	authPage := GetQualifiedTypeName[testPage[auth.User]]()
+	if userPage == authPage {
+		t.Errorf("Generic instantiations should have distinct identities, both %s", userPage)
+	}
+
+	anonymous := Select[struct {
+		Name string `json:"name"`
+	}]()
+	if anonymous.QualifiedName == "" {
+		t.Error("Anonymous structs should have a non-empty identity")
+	}
+}
+
+func TestIdentity_BrowseReturnsBothForms(t *testing.T) {
+	Select[billing.User]()
+
+	for _, ref := range Browse() {
+		if ref.QualifiedName == "zbz/catalog/internal/fixtures/billing.User" {
+			if ref.Name != "User" {
+				t.Errorf("Expected short name 'User', got '%s'", ref.Name)
+			}
+			return
+		}
+	}
+	t.Error("Expected billing.User in Browse results")
+}
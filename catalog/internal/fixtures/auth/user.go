@@ -0,0 +1,7 @@
+// Package auth provides a User type whose short name collides with billing.User
+package auth
+
+// User is an authenticated principal
+type User struct {
+	Subject string `json:"subject"`
+}
@@ -0,0 +1,7 @@
+// Package billing provides a User type whose short name collides with auth.User
+package billing
+
+// User is a billing account holder
+type User struct {
+	AccountID string `json:"account_id"`
+}
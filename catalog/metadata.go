@@ -1,7 +1,11 @@
 package catalog
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -9,13 +13,14 @@ import (
 // ModelMetadata contains comprehensive information about a user model
 // This defines what struct tags and capabilities the system supports
 type ModelMetadata struct {
-	TypeName    string            `json:"type_name"`
-	PackageName string            `json:"package_name"`
-	Fields      []FieldMetadata   `json:"fields"`
-	Functions   []FunctionInfo    `json:"functions"`
-	Examples    map[string]any    `json:"examples,omitempty"`
-	Description string            `json:"description,omitempty"`
-	JSONSchema  map[string]any    `json:"json_schema,omitempty"`
+	TypeName      string          `json:"type_name"`
+	QualifiedName string          `json:"qualified_name"` // Cache key: package path, name and type arguments
+	PackageName   string          `json:"package_name"`
+	Fields        []FieldMetadata `json:"fields"`
+	Functions     []FunctionInfo  `json:"functions"`
+	Examples      map[string]any  `json:"examples,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	JSONSchema    map[string]any  `json:"json_schema,omitempty"`
 }
 
 // FieldMetadata captures all supported field-level metadata
@@ -39,8 +44,33 @@ type FieldMetadata struct {
 	// Data Handling
 	Redaction    RedactionInfo     `json:"redaction,omitempty"`
 	
-	// Additional tag-based metadata
+	// Additional tag-based metadata - every tag on the field, raw
 	Tags         map[string]string `json:"tags,omitempty"`
+	
+	// Typed values from registered tag extractors, keyed by tag name
+	Extensions      map[string]any    `json:"extensions,omitempty"`
+	ExtensionErrors map[string]string `json:"extension_errors,omitempty"` // Tags whose parser rejected the value
+	
+	// Type graph - nested structs, containers and embedded fields
+	Path         string            `json:"path"`                  // Dotted path from the model root, e.g. "address.street"
+	Index        []int             `json:"index,omitempty"`       // reflect index relative to the containing struct
+	Kind         string            `json:"kind"`                  // reflect kind after pointer dereference
+	Nullable     bool              `json:"nullable,omitempty"`    // Field is a pointer
+	Elem         *TypeDescriptor   `json:"elem,omitempty"`        // Element type for slices, arrays and maps
+	Key          *TypeDescriptor   `json:"key,omitempty"`         // Key type for maps
+	EmbeddedIn   string            `json:"embedded_in,omitempty"` // Embedded type this field was promoted from
+	Fields       []FieldMetadata   `json:"fields,omitempty"`      // Nested fields of struct (or struct element) types
+	Recursive    bool              `json:"recursive,omitempty"`   // Type already appears higher in the graph; Fields not expanded
+	Ref          string            `json:"ref,omitempty"`         // Qualified name of the struct behind Fields (or the repeated type when Recursive)
+}
+
+// TypeDescriptor describes container element and key types
+type TypeDescriptor struct {
+	Type     string          `json:"type"`
+	Kind     string          `json:"kind"`
+	Nullable bool            `json:"nullable,omitempty"`
+	Elem     *TypeDescriptor `json:"elem,omitempty"`
+	Key      *TypeDescriptor `json:"key,omitempty"`
 }
 
 // ValidationInfo captures validation requirements from tags
@@ -48,26 +78,87 @@ type ValidationInfo struct {
 	Required     bool              `json:"required,omitempty"`
 	CustomRules  []string          `json:"custom_rules,omitempty"`
 	Constraints  map[string]string `json:"constraints,omitempty"`
+	Conditions   []ValidationCondition `json:"conditions,omitempty"` // Cross-field rules such as required_if
+}
+
+// ValidationCondition is one clause of a cross-field rule: for
+// required_if=Status active the field is required when Status is "active".
+// Clauses of a *_with or *_without rule name fields only.
+type ValidationCondition struct {
+	Rule  string `json:"rule"`            // "required_if", "excluded_unless", ...
+	Field string `json:"field"`           // Go name of the field the rule depends on
+	Value string `json:"value,omitempty"` // Value compared against, for *_if and *_unless
+}
+
+// conditionalRules are the validate rules that depend on other fields; the
+// bool is true for rules whose parameters are field/value pairs
+var conditionalRules = map[string]bool{
+	"required_if":          true,
+	"required_unless":      true,
+	"required_with":        false,
+	"required_with_all":    false,
+	"required_without":     false,
+	"required_without_all": false,
+	"excluded_if":          true,
+	"excluded_unless":      true,
+	"excluded_with":        false,
+	"excluded_with_all":    false,
+	"excluded_without":     false,
+	"excluded_without_all": false,
 }
 
 // EncryptionInfo defines field-level encryption requirements  
 type EncryptionInfo struct {
 	Type         string   `json:"type,omitempty"`         // "pii", "financial", "medical", "homomorphic"
-	Algorithm    string   `json:"algorithm,omitempty"`    // "AES-256", "ChaCha20", "homomorphic"
-	KeyRotation  bool     `json:"key_rotation,omitempty"`
+	Algorithm    string   `json:"algorithm,omitempty"`    // "AES-256-GCM", "AES-SIV", "blind-index"
+	KeyRotation  bool     `json:"key_rotation,omitempty"` // Re-encrypted when the master key rotates
 	DataResidency []string `json:"data_residency,omitempty"` // "us-west", "eu-central"
+	
+	// Searchable modes trade secrecy for queryability; Leaks says what an
+	// observer of the stored data learns beyond the ciphertext
+	Deterministic bool   `json:"deterministic,omitempty"` // Equal values store equal ciphertext
+	BlindIndex    string `json:"blind_index,omitempty"`   // Companion column holding the HMAC index
+	Leaks         string `json:"leaks,omitempty"`
+}
+
+// Encryption algorithms selectable with encrypt_algo
+const (
+	// AlgorithmRandomized is the default: AES-256-GCM with a fresh data key
+	// per value. Equal values encrypt differently, so the field can't be queried.
+	AlgorithmRandomized = "AES-256-GCM"
+	
+	// AlgorithmDeterministic is AES-SIV: equal values under the same master
+	// key encrypt identically, so the ciphertext column supports equality
+	// lookups, joins and unique indexes.
+	AlgorithmDeterministic = "AES-SIV"
+	
+	// AlgorithmBlindIndex stores the value randomized and writes a keyed
+	// HMAC of it to a companion column for equality lookups.
+	AlgorithmBlindIndex = "blind-index"
+)
+
+// BlindIndexSuffix names the companion column of a blind-indexed field
+const BlindIndexSuffix = "_bidx"
+
+// encryptionLeaks documents what each algorithm reveals to anyone who can read the stored data
+var encryptionLeaks = map[string]string{
+	AlgorithmRandomized:    "length of the value only",
+	AlgorithmDeterministic: "length, and which records share a value (equality and frequency) across every AES-SIV field under the same master key",
+	AlgorithmBlindIndex:    "length, and which records share a value (equality and frequency) through the companion index; indexes are keyed per field and not comparable across fields",
 }
 
 // RedactionInfo defines how fields should be redacted for unauthorized users
 type RedactionInfo struct {
-	Strategy string `json:"strategy,omitempty"` // "mask", "zero", "custom"
+	Strategy string `json:"strategy,omitempty"` // "mask", "zero", "hash", "tokenize", "partial", "custom"
 	Value    string `json:"value,omitempty"`    // Custom redaction value
+	Keep     int    `json:"keep,omitempty"`     // Trailing characters left visible by "partial"
 }
 
 // FunctionInfo captures methods attached to the model
 type FunctionInfo struct {
 	Name       string            `json:"name"`
 	Type       string            `json:"type"`       // "method", "receiver"
+	Receiver   string            `json:"receiver,omitempty"`   // "value" or "pointer" - pointer conventions need an addressable value
 	Signature  string            `json:"signature"`
 	Convention string            `json:"convention,omitempty"` // "ScopeProvider", etc.
 	Tags       map[string]string `json:"tags,omitempty"`
@@ -76,25 +167,66 @@ type FunctionInfo struct {
 // Global metadata cache - reflect once, use everywhere
 var (
 	metadataCache = make(map[string]ModelMetadata)
+	typeRegistry  = make(map[string]reflect.Type) // Cached types, dereferenced, for re-scanning on convention registration
 	cacheMutex    sync.RWMutex
 )
 
-// GetModelMetadata retrieves cached metadata by type name
-func GetModelMetadata(typeName string) (ModelMetadata, bool) {
+// Lookup errors for name-based access
+var (
+	ErrTypeNotFound  = errors.New("type not found in catalog")
+	ErrAmbiguousType = errors.New("ambiguous type name")
+)
+
+// Lookup retrieves cached metadata by qualified name, or by short name when
+// exactly one catalogued type carries it
+func Lookup(name string) (ModelMetadata, error) {
 	cacheMutex.RLock()
 	defer cacheMutex.RUnlock()
-	metadata, exists := metadataCache[typeName]
-	return metadata, exists
+	
+	if metadata, exists := metadataCache[name]; exists {
+		return metadata, nil
+	}
+	
+	var matches []string
+	for identity, metadata := range metadataCache {
+		if metadata.TypeName == name {
+			matches = append(matches, identity)
+		}
+	}
+	
+	switch len(matches) {
+	case 0:
+		return ModelMetadata{}, fmt.Errorf("%w: %s", ErrTypeNotFound, name)
+	case 1:
+		return metadataCache[matches[0]], nil
+	default:
+		sort.Strings(matches)
+		return ModelMetadata{}, fmt.Errorf("%w: %s matches %s", ErrAmbiguousType, name, strings.Join(matches, ", "))
+	}
+}
+
+// GetModelMetadata retrieves cached metadata by qualified or short type name.
+// Ambiguous short names report false; use Lookup for the reason.
+func GetModelMetadata(typeName string) (ModelMetadata, bool) {
+	metadata, err := Lookup(typeName)
+	return metadata, err == nil
 }
 
 // ExtractAndCacheMetadata performs comprehensive reflection on a type and caches the result
 func ExtractAndCacheMetadata[T any](example T) ModelMetadata {
-	t := reflect.TypeOf(example)
-	typeName := getTypeName(t)
+	return loadMetadata(reflect.TypeOf(example), example)
+}
+
+// loadMetadata returns cached metadata for t, extracting it on first use
+func loadMetadata(t reflect.Type, example any) ModelMetadata {
+	identity := typeIdentity(t)
+	base := derefType(t)
 	
-	// Check cache first
+	// Check cache first - entries without a type were imported from a
+	// snapshot and are replaced by live extraction, as are entries for a
+	// different type sharing the identity (function-local types)
 	cacheMutex.RLock()
-	if cached, exists := metadataCache[typeName]; exists {
+	if cached, exists := metadataCache[identity]; exists && typeRegistry[identity] == base {
 		cacheMutex.RUnlock()
 		return cached
 	}
@@ -105,7 +237,8 @@ func ExtractAndCacheMetadata[T any](example T) ModelMetadata {
 	
 	// Cache the result
 	cacheMutex.Lock()
-	metadataCache[typeName] = metadata
+	metadataCache[identity] = metadata
+	typeRegistry[identity] = base
 	cacheMutex.Unlock()
 	
 	return metadata
@@ -115,81 +248,218 @@ func ExtractAndCacheMetadata[T any](example T) ModelMetadata {
 func extractMetadata(t reflect.Type, example any) ModelMetadata {
 	metadata := ModelMetadata{
 		TypeName:    getTypeName(t),
+		QualifiedName: typeIdentity(t),
 		PackageName: t.PkgPath(),
 		Fields:      extractFieldMetadata(t),
-		Functions:   extractFunctionMetadata(t, example),
+		Functions:   extractFunctionMetadata(t),
 		Examples:    make(map[string]any),
 	}
+	metadata.JSONSchema = GenerateJSONSchema(metadata)
 	
 	return metadata
 }
 
-// extractFieldMetadata extracts comprehensive field information
+// extractFieldMetadata extracts field information for the whole type graph.
+// Embedded structs are flattened, nested structs and containers are described
+// recursively and self-referencing types are cut at the first repeat.
 func extractFieldMetadata(t reflect.Type) []FieldMetadata {
-	var fields []FieldMetadata
-	
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+	t = derefType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
 	}
+	return walkStruct(t, "", map[reflect.Type]bool{t: true})
+}
+
+// walkStruct extracts the fields of a struct; visiting holds the struct types
+// on the current path so cycles are detected without blocking siblings
+func walkStruct(t reflect.Type, prefix string, visiting map[reflect.Type]bool) []FieldMetadata {
+	return flattenStruct(t, prefix, visiting, map[reflect.Type]bool{t: true})
+}
+
+// flattenStruct extracts the fields of a struct and of the structs it embeds.
+// Embedded types only guard against embedding cycles; they are not added to
+// visiting, so a nested field of an embedded type is still expanded once.
+func flattenStruct(t reflect.Type, prefix string, visiting, embedding map[reflect.Type]bool) []FieldMetadata {
+	var fields []FieldMetadata
 	
-	if t.Kind() != reflect.Struct {
-		return fields
+	// Direct fields shadow promoted ones, matching Go's selector rules
+	seen := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); !isFlattenedEmbed(field) && field.IsExported() {
+			seen[field.Name] = true
+		}
 	}
 	
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		
-		if !field.IsExported() {
+		if isFlattenedEmbed(field) {
+			embedded := derefType(field.Type)
+			if embedding[embedded] {
+				continue
+			}
+			embedding[embedded] = true
+			for _, promoted := range flattenStruct(embedded, prefix, visiting, embedding) {
+				if seen[promoted.Name] {
+					continue
+				}
+				seen[promoted.Name] = true
+				promoted.Index = append([]int{i}, promoted.Index...)
+				if promoted.EmbeddedIn == "" {
+					promoted.EmbeddedIn = getTypeName(embedded)
+				}
+				fields = append(fields, promoted)
+			}
+			delete(embedding, embedded)
 			continue
 		}
 		
-		fieldMeta := FieldMetadata{
-			Name:     field.Name,
-			Type:     field.Type.String(),
-			JSONName: extractJSONName(field),
-			DBColumn: field.Tag.Get("db"),
-			Description: field.Tag.Get("desc"),
-			Scopes:   extractScopes(field),
-			Validation: extractValidationInfo(field),
-			Encryption: extractEncryptionInfo(field),
-			Redaction:  extractRedactionInfo(field),
-			Tags:       extractAllTags(field),
-		}
-		
-		// Extract example if provided
-		if example := field.Tag.Get("example"); example != "" {
-			fieldMeta.Example = example
+		if !field.IsExported() {
+			continue
 		}
 		
-		fields = append(fields, fieldMeta)
+		fields = append(fields, buildFieldMetadata(field, prefix, visiting))
 	}
 	
 	return fields
 }
 
-// extractFunctionMetadata discovers methods and conventions implemented by the type
-func extractFunctionMetadata(t reflect.Type, example any) []FunctionInfo {
-	var functions []FunctionInfo
+// buildFieldMetadata extracts tag metadata and the type graph for one field
+func buildFieldMetadata(field reflect.StructField, prefix string, visiting map[reflect.Type]bool) FieldMetadata {
+	fieldMeta := FieldMetadata{
+		Name:     field.Name,
+		Type:     field.Type.String(),
+		JSONName: extractJSONName(field),
+		DBColumn: field.Tag.Get("db"),
+		Description: field.Tag.Get("desc"),
+		Scopes:   extractScopes(field),
+		Validation: extractValidationInfo(field),
+		Encryption: extractEncryptionInfo(field),
+		Redaction:  extractRedactionInfo(field),
+		Tags:       extractAllTags(field),
+		Index:      append([]int(nil), field.Index...),
+	}
 	
-	// Check for convention interfaces
-	if scopeProvider, ok := example.(interface{ GetRequiredScopes() []string }); ok {
-		_ = scopeProvider // Use the interface
-		functions = append(functions, FunctionInfo{
-			Name:       "GetRequiredScopes",
-			Type:       "method",
-			Convention: "ScopeProvider",
-			Signature:  "() []string",
-		})
+	// Extract example if provided
+	if example := field.Tag.Get("example"); example != "" {
+		fieldMeta.Example = example
 	}
 	
-	// TODO: Add detection for other conventions as they're implemented
-	// - ValidationProvider, AuditProvider, etc.
+	fieldMeta.Extensions, fieldMeta.ExtensionErrors = extractExtensions(fieldMeta.Tags)
 	
-	return functions
+	segment := fieldMeta.JSONName
+	if segment == "" {
+		segment = field.Name
+	}
+	fieldMeta.Path = joinPath(prefix, segment)
+	
+	descriptor := describeType(field.Type)
+	fieldMeta.Kind = descriptor.Kind
+	fieldMeta.Nullable = descriptor.Nullable
+	fieldMeta.Elem = descriptor.Elem
+	fieldMeta.Key = descriptor.Key
+	
+	// Descend through containers to the struct that holds nested fields
+	childPrefix := fieldMeta.Path
+	nested := derefType(field.Type)
+	for {
+		switch nested.Kind() {
+		case reflect.Slice, reflect.Array:
+			childPrefix += "[]"
+			nested = derefType(nested.Elem())
+			continue
+		case reflect.Map:
+			childPrefix += "{}"
+			nested = derefType(nested.Elem())
+			continue
+		}
+		break
+	}
+	
+	if nested.Kind() == reflect.Struct {
+		if nested.Name() != "" {
+			fieldMeta.Ref = typeIdentity(nested)
+		}
+		if visiting[nested] {
+			fieldMeta.Recursive = true
+		} else {
+			visiting[nested] = true
+			fieldMeta.Fields = walkStruct(nested, childPrefix, visiting)
+			delete(visiting, nested)
+		}
+	}
+	
+	return fieldMeta
+}
+
+// describeType builds a descriptor, recursing through container element types
+func describeType(t reflect.Type) *TypeDescriptor {
+	descriptor := &TypeDescriptor{}
+	if t.Kind() == reflect.Ptr {
+		descriptor.Nullable = true
+		t = derefType(t)
+	}
+	descriptor.Type = t.String()
+	descriptor.Kind = t.Kind().String()
+	
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		descriptor.Elem = describeType(t.Elem())
+	case reflect.Map:
+		descriptor.Key = describeType(t.Key())
+		descriptor.Elem = describeType(t.Elem())
+	}
+	
+	return descriptor
+}
+
+// AllFields returns every field in the type graph, depth first, with nested
+// fields following their parent
+func (m ModelMetadata) AllFields() []FieldMetadata {
+	var all []FieldMetadata
+	var walk func([]FieldMetadata)
+	walk = func(fields []FieldMetadata) {
+		for _, field := range fields {
+			all = append(all, field)
+			walk(field.Fields)
+		}
+	}
+	walk(m.Fields)
+	return all
 }
 
 // Helper functions for tag extraction
 
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isFlattenedEmbed reports whether an anonymous field's fields are promoted.
+// Like encoding/json, a json name on the embedded field keeps it nested.
+func isFlattenedEmbed(field reflect.StructField) bool {
+	return field.Anonymous && derefType(field.Type).Kind() == reflect.Struct && extractJSONName(field) == ""
+}
+
+func joinPath(prefix, segment string) string {
+	if prefix == "" {
+		return segment
+	}
+	return prefix + "." + segment
+}
+
+// typeIdentity returns a stable fully-qualified identity: package path, name
+// and type arguments for named types, the type literal for anonymous ones
+func typeIdentity(t reflect.Type) string {
+	t = derefType(t)
+	if t.Name() == "" || t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
 func getTypeName(t reflect.Type) string {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -231,6 +501,7 @@ func extractValidationInfo(field reflect.StructField) ValidationInfo {
 		} else if strings.Contains(part, "=") {
 			kv := strings.SplitN(part, "=", 2)
 			info.Constraints[kv[0]] = kv[1]
+			info.Conditions = append(info.Conditions, parseConditions(kv[0], kv[1])...)
 		} else {
 			// Custom validation rule
 			info.CustomRules = append(info.CustomRules, part)
@@ -240,6 +511,26 @@ func extractValidationInfo(field reflect.StructField) ValidationInfo {
 	return info
 }
 
+// parseConditions splits the parameter of a cross-field rule into clauses
+func parseConditions(rule, param string) []ValidationCondition {
+	paired, conditional := conditionalRules[rule]
+	if !conditional {
+		return nil
+	}
+	
+	var conditions []ValidationCondition
+	params := strings.Fields(param)
+	for i := 0; i < len(params); i++ {
+		condition := ValidationCondition{Rule: rule, Field: params[i]}
+		if paired && i+1 < len(params) {
+			i++
+			condition.Value = params[i]
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions
+}
+
 func extractEncryptionInfo(field reflect.StructField) EncryptionInfo {
 	encryptTag := field.Tag.Get("encrypt")
 	if encryptTag == "" {
@@ -255,36 +546,38 @@ func extractEncryptionInfo(field reflect.StructField) EncryptionInfo {
 		info.Algorithm = algo
 	}
 	
+	info.Leaks = encryptionLeaks[info.Algorithm]
+	switch info.Algorithm {
+	case "", "AES-256":
+		info.Leaks = encryptionLeaks[AlgorithmRandomized]
+	case AlgorithmDeterministic:
+		info.Deterministic = true
+	case AlgorithmBlindIndex:
+		column := field.Tag.Get("db")
+		if column == "" || column == "-" {
+			column, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		}
+		if column == "" || column == "-" {
+			column = field.Name
+		}
+		info.BlindIndex = column + BlindIndexSuffix
+	}
+	
 	if residency := field.Tag.Get("data_residency"); residency != "" {
 		info.DataResidency = strings.Split(residency, ",")
 	}
 	
+	// Encrypted fields follow master key rotation unless pinned with key_rotation:"false"
+	info.KeyRotation = true
+	if rotation := field.Tag.Get("key_rotation"); rotation != "" {
+		if enabled, err := strconv.ParseBool(rotation); err == nil {
+			info.KeyRotation = enabled
+		}
+	}
+	
 	return info
 }
 
 func extractRedactionInfo(field reflect.StructField) RedactionInfo {
-	redactTag := field.Tag.Get("redact")
-	if redactTag == "" {
-		return RedactionInfo{}
-	}
-	
-	return RedactionInfo{
-		Strategy: "custom",
-		Value:    redactTag,
-	}
+	return parseRedaction(field.Tag.Get("redact"))
 }
-
-func extractAllTags(field reflect.StructField) map[string]string {
-	tags := make(map[string]string)
-	
-	// Known tags we want to preserve
-	knownTags := []string{"json", "db", "validate", "scope", "encrypt", "redact", "desc", "example"}
-	
-	for _, tag := range knownTags {
-		if value := field.Tag.Get(tag); value != "" {
-			tags[tag] = value
-		}
-	}
-	
-	return tags
-}
\ No newline at end of file
@@ -0,0 +1,221 @@
+package catalog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testAddress struct {
+	Street string `json:"street" scope:"admin"`
+	City   string `json:"city"`
+}
+
+type testItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type testNode struct {
+	Value    string      `json:"value"`
+	Children []*testNode `json:"children"`
+	Parent   *testNode   `json:"parent"`
+}
+
+type testCustomer struct {
+	testBase
+	ID        string              `json:"customer_id"` // Shadows testBase.ID
+	Address   testAddress         `json:"address"`
+	Addresses []testAddress       `json:"addresses"`
+	Items     map[string]testItem `json:"items"`
+	Manager   *testCustomer       `json:"manager"`
+	Tree      testNode            `json:"tree"`
+}
+
+func findField(fields []FieldMetadata, path string) (FieldMetadata, bool) {
+	for _, field := range fields {
+		if field.Path == path {
+			return field, true
+		}
+	}
+	return FieldMetadata{}, false
+}
+
+func TestNestedMetadata_EmbeddedFieldsFlattened(t *testing.T) {
+	metadata := Select[testCustomer]()
+
+	created, ok := findField(metadata.Fields, "created_at")
+	if !ok {
+		t.Fatal("Expected embedded created_at to be promoted to top level")
+	}
+	if created.EmbeddedIn != "testBase" {
+		t.Errorf("Expected EmbeddedIn 'testBase', got '%s'", created.EmbeddedIn)
+	}
+	if len(created.Index) != 2 || created.Index[0] != 0 {
+		t.Errorf("Expected index path through embedded field, got %v", created.Index)
+	}
+
+	count := 0
+	for _, field := range metadata.Fields {
+		if field.Name == "ID" {
+			count++
+			if field.JSONName != "customer_id" {
+				t.Errorf("Expected outer ID to shadow embedded ID, got json name '%s'", field.JSONName)
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected exactly one ID field, got %d", count)
+	}
+}
+
+func TestNestedMetadata_NestedStructPaths(t *testing.T) {
+	metadata := Select[testCustomer]()
+	all := metadata.AllFields()
+
+	street, ok := findField(all, "address.street")
+	if !ok {
+		t.Fatal("Expected nested field 'address.street'")
+	}
+	if len(street.Scopes) != 1 || street.Scopes[0] != "admin" {
+		t.Errorf("Expected nested scopes to be extracted, got %v", street.Scopes)
+	}
+
+	if _, ok := findField(all, "addresses[].city"); !ok {
+		t.Error("Expected slice element field 'addresses[].city'")
+	}
+	if _, ok := findField(all, "items{}.price"); !ok {
+		t.Error("Expected map element field 'items{}.price'")
+	}
+}
+
+func TestNestedMetadata_ElementTypes(t *testing.T) {
+	metadata := Select[testCustomer]()
+
+	items, _ := findField(metadata.Fields, "items")
+	if items.Kind != "map" {
+		t.Errorf("Expected kind 'map', got '%s'", items.Kind)
+	}
+	if items.Key == nil || items.Key.Kind != "string" {
+		t.Errorf("Expected string map key, got %+v", items.Key)
+	}
+	if items.Elem == nil || items.Elem.Kind != "struct" {
+		t.Errorf("Expected struct map element, got %+v", items.Elem)
+	}
+
+	manager, _ := findField(metadata.Fields, "manager")
+	if !manager.Nullable || manager.Kind != "struct" {
+		t.Errorf("Expected nullable struct for pointer field, got kind '%s' nullable %v", manager.Kind, manager.Nullable)
+	}
+}
+
+func TestNestedMetadata_CyclesDetected(t *testing.T) {
+	metadata := Select[testCustomer]()
+	all := metadata.AllFields()
+
+	manager, _ := findField(all, "manager")
+	if !manager.Recursive || manager.Ref != "zbz/catalog.testCustomer" {
+		t.Errorf("Expected manager to be marked recursive to testCustomer, got %+v", manager)
+	}
+	if len(manager.Fields) != 0 {
+		t.Error("Recursive fields should not be expanded")
+	}
+
+	children, ok := findField(all, "tree.children")
+	if !ok || !children.Recursive {
+		t.Error("Expected tree.children to be marked recursive")
+	}
+}
+
+type testVault struct {
+	Token   string `json:"token" encrypt:"pii"`
+	Archive string `json:"archive" encrypt:"pii" key_rotation:"false"`
+	Public  string `json:"public"`
+}
+
+func TestEncryptionInfo_KeyRotation(t *testing.T) {
+	metadata := Select[testVault]()
+
+	token, _ := findField(metadata.Fields, "token")
+	if !token.Encryption.KeyRotation {
+		t.Error("Expected encrypted field to follow key rotation by default")
+	}
+	archive, _ := findField(metadata.Fields, "archive")
+	if archive.Encryption.KeyRotation {
+		t.Error("Expected key_rotation:\"false\" to pin the field's key")
+	}
+	public, _ := findField(metadata.Fields, "public")
+	if public.Encryption.KeyRotation {
+		t.Error("Expected unencrypted field to have no key rotation")
+	}
+}
+
+type testLookup struct {
+	Email string `json:"email,omitempty" encrypt:"pii" encrypt_algo:"blind-index"`
+	SSN   string `json:"ssn" db:"tax_id" encrypt:"pii" encrypt_algo:"AES-SIV"`
+	Notes string `json:"notes" encrypt:"pii"`
+}
+
+func TestEncryptionInfo_SearchableModes(t *testing.T) {
+	metadata := Select[testLookup]()
+
+	email, _ := findField(metadata.Fields, "email")
+	if email.Encryption.BlindIndex != "email_bidx" || email.Encryption.Deterministic {
+		t.Errorf("Expected blind index column email_bidx, got %+v", email.Encryption)
+	}
+
+	ssn, _ := findField(metadata.Fields, "ssn")
+	if !ssn.Encryption.Deterministic || ssn.Encryption.BlindIndex != "" {
+		t.Errorf("Expected deterministic encryption, got %+v", ssn.Encryption)
+	}
+
+	notes, _ := findField(metadata.Fields, "notes")
+	for _, field := range []FieldMetadata{email, ssn, notes} {
+		if field.Encryption.Leaks == "" {
+			t.Errorf("Expected %s to document its leakage", field.Path)
+		}
+	}
+	if notes.Encryption.Leaks == ssn.Encryption.Leaks {
+		t.Error("Expected deterministic leakage to differ from randomized")
+	}
+}
+
+type testShipment struct {
+	Status   string `json:"status" validate:"oneof=draft shipped"`
+	Carrier  string `json:"carrier" validate:"required_if=Status shipped"`
+	Tracking string `json:"tracking" validate:"excluded_unless=Status shipped Carrier ups"`
+	Phone    string `json:"phone" validate:"required_without=Email Fax"`
+}
+
+func TestValidationInfo_Conditions(t *testing.T) {
+	metadata := Select[testShipment]()
+
+	carrier, _ := findField(metadata.Fields, "carrier")
+	expected := []ValidationCondition{{Rule: "required_if", Field: "Status", Value: "shipped"}}
+	if !reflect.DeepEqual(carrier.Validation.Conditions, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, carrier.Validation.Conditions)
+	}
+	if carrier.Validation.Required {
+		t.Error("Expected conditional requirement not to mark the field required")
+	}
+
+	tracking, _ := findField(metadata.Fields, "tracking")
+	if len(tracking.Validation.Conditions) != 2 || tracking.Validation.Conditions[1] != (ValidationCondition{Rule: "excluded_unless", Field: "Carrier", Value: "ups"}) {
+		t.Errorf("Expected two excluded_unless clauses, got %+v", tracking.Validation.Conditions)
+	}
+
+	phone, _ := findField(metadata.Fields, "phone")
+	if len(phone.Validation.Conditions) != 2 || phone.Validation.Conditions[1].Field != "Fax" || phone.Validation.Conditions[1].Value != "" {
+		t.Errorf("Expected field-only required_without clauses, got %+v", phone.Validation.Conditions)
+	}
+
+	status, _ := findField(metadata.Fields, "status")
+	if len(status.Validation.Conditions) != 0 {
+		t.Errorf("Expected oneof not to be a condition, got %+v", status.Validation.Conditions)
+	}
+}
@@ -0,0 +1,72 @@
+package catalog
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RegisterOptions carries documentation that struct tags cannot express
+type RegisterOptions struct {
+	Description       string            // Model description
+	Examples          map[string]any    // Named example instances, e.g. {"admin": User{...}}
+	FieldDescriptions map[string]string // Field path -> description, overrides desc tags
+	FieldExamples     map[string]any    // Field path -> example, overrides example tags
+}
+
+// Register eagerly catalogs T at boot so Browse() does not depend on which
+// code happened to call Select[T]() first. Registering again replaces the
+// options; unknown field paths are reported as an error.
+func Register[T any](opts RegisterOptions) (ModelMetadata, error) {
+	var zero T
+	t := reflect.TypeOf(zero)
+	identity := typeIdentity(t)
+
+	metadata := extractMetadata(t, zero)
+	if opts.Description != "" {
+		metadata.Description = opts.Description
+	}
+	for name, example := range opts.Examples {
+		metadata.Examples[name] = example
+	}
+	for path, description := range opts.FieldDescriptions {
+		if !updateField(metadata.Fields, path, func(field *FieldMetadata) { field.Description = description }) {
+			return ModelMetadata{}, fmt.Errorf("register %s: unknown field path %q", identity, path)
+		}
+	}
+	for path, example := range opts.FieldExamples {
+		if !updateField(metadata.Fields, path, func(field *FieldMetadata) { field.Example = example }) {
+			return ModelMetadata{}, fmt.Errorf("register %s: unknown field path %q", identity, path)
+		}
+	}
+	metadata.JSONSchema = GenerateJSONSchema(metadata)
+
+	cacheMutex.Lock()
+	metadataCache[identity] = metadata
+	typeRegistry[identity] = derefType(t)
+	cacheMutex.Unlock()
+
+	return metadata, nil
+}
+
+// MustRegister is Register for package-level var initialization; it panics on error
+func MustRegister[T any](opts RegisterOptions) ModelMetadata {
+	metadata, err := Register[T](opts)
+	if err != nil {
+		panic(err)
+	}
+	return metadata
+}
+
+// updateField applies fn to the field at path anywhere in the type graph
+func updateField(fields []FieldMetadata, path string, fn func(*FieldMetadata)) bool {
+	for i := range fields {
+		if fields[i].Path == path {
+			fn(&fields[i])
+			return true
+		}
+		if updateField(fields[i].Fields, path, fn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+package catalog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDraft is the dialect emitted by GenerateJSONSchema
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateJSONSchema builds a draft 2020-12 JSON Schema from model metadata.
+// Named nested structs are emitted once under $defs, keyed by qualified name,
+// and referenced with $ref; recursive references back to the model itself
+// point at the document root.
+func GenerateJSONSchema(metadata ModelMetadata) map[string]any {
+	builder := &schemaBuilder{
+		root: metadata.QualifiedName,
+		defs: make(map[string]any),
+	}
+
+	schema := builder.objectSchema(metadata.Fields)
+	schema["$schema"] = JSONSchemaDraft
+	if metadata.TypeName != "" {
+		schema["title"] = metadata.TypeName
+	}
+	if metadata.Description != "" {
+		schema["description"] = metadata.Description
+	}
+	if len(metadata.Examples) > 0 {
+		names := make([]string, 0, len(metadata.Examples))
+		for name := range metadata.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		examples := make([]any, 0, len(names))
+		for _, name := range names {
+			examples = append(examples, metadata.Examples[name])
+		}
+		schema["examples"] = examples
+	}
+	if len(builder.defs) > 0 {
+		schema["$defs"] = builder.defs
+	}
+
+	return schema
+}
+
+// schemaBuilder accumulates $defs while walking the field graph
+type schemaBuilder struct {
+	root string
+	defs map[string]any
+}
+
+// objectSchema builds an object schema with properties and required fields
+func (b *schemaBuilder) objectSchema(fields []FieldMetadata) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+
+	for _, field := range fields {
+		if field.Tags["json"] == "-" {
+			continue
+		}
+		name := field.JSONName
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.fieldSchema(field)
+		if field.Validation.Required {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldSchema builds the schema for one field including constraints and docs
+func (b *schemaBuilder) fieldSchema(field FieldMetadata) map[string]any {
+	descriptor := &TypeDescriptor{
+		Type: strings.TrimPrefix(field.Type, "*"),
+		Kind: field.Kind,
+		Elem: field.Elem,
+		Key:  field.Key,
+	}
+	schema := b.typeSchema(descriptor, field)
+
+	applyConstraints(schema, field)
+	if field.Description != "" {
+		schema["description"] = field.Description
+	}
+	if field.Example != nil {
+		schema["examples"] = []any{convertExample(field.Example, field.Kind)}
+	}
+
+	if field.Nullable {
+		schema = nullable(schema)
+	}
+	return schema
+}
+
+// typeSchema maps a type descriptor to a schema; the struct at the bottom of a
+// container chain is resolved through the owning field's Ref and Fields
+func (b *schemaBuilder) typeSchema(descriptor *TypeDescriptor, field FieldMetadata) map[string]any {
+	switch descriptor.Kind {
+	case "string":
+		return map[string]any{"type": "string"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+		return map[string]any{"type": "integer"}
+	case "float32", "float64":
+		return map[string]any{"type": "number"}
+	case "slice", "array":
+		if descriptor.Elem != nil && descriptor.Elem.Kind == "uint8" && descriptor.Kind == "slice" {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		schema := map[string]any{"type": "array"}
+		if descriptor.Elem != nil {
+			schema["items"] = b.elemSchema(descriptor.Elem, field)
+		}
+		return schema
+	case "map":
+		schema := map[string]any{"type": "object"}
+		if descriptor.Elem != nil {
+			schema["additionalProperties"] = b.elemSchema(descriptor.Elem, field)
+		}
+		return schema
+	case "struct":
+		if descriptor.Type == "time.Time" {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		return b.structSchema(field)
+	default:
+		// interface{} and anything else accepts any value
+		return map[string]any{}
+	}
+}
+
+// elemSchema builds the schema for a container element
+func (b *schemaBuilder) elemSchema(descriptor *TypeDescriptor, field FieldMetadata) map[string]any {
+	schema := b.typeSchema(descriptor, field)
+	if descriptor.Nullable {
+		schema = nullable(schema)
+	}
+	return schema
+}
+
+// structSchema registers a named struct under $defs and returns a $ref to it;
+// anonymous structs are inlined
+func (b *schemaBuilder) structSchema(field FieldMetadata) map[string]any {
+	if field.Ref == "" {
+		return b.objectSchema(field.Fields)
+	}
+	if field.Ref == b.root {
+		return map[string]any{"$ref": "#"}
+	}
+
+	name := defName(field.Ref)
+	if _, exists := b.defs[name]; !exists && !field.Recursive {
+		// Reserve the name first so self-references inside resolve to it
+		b.defs[name] = map[string]any{}
+		b.defs[name] = b.objectSchema(field.Fields)
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+// defName turns a qualified type name into a $defs key that needs no JSON
+// pointer escaping: "example.com/billing.User" -> "example.com.billing.User"
+func defName(qualifiedName string) string {
+	return strings.ReplaceAll(qualifiedName, "/", ".")
+}
+
+// applyConstraints maps validate tag rules onto schema keywords
+func applyConstraints(schema map[string]any, field FieldMetadata) {
+	var minKey, maxKey string
+	switch schema["type"] {
+	case "string":
+		minKey, maxKey = "minLength", "maxLength"
+	case "integer", "number":
+		minKey, maxKey = "minimum", "maximum"
+	case "array":
+		minKey, maxKey = "minItems", "maxItems"
+	case "object":
+		minKey, maxKey = "minProperties", "maxProperties"
+	default:
+		return
+	}
+	numeric := minKey == "minimum"
+
+	for rule, value := range field.Validation.Constraints {
+		switch rule {
+		case "min", "gte":
+			setNumber(schema, minKey, value)
+		case "max", "lte":
+			setNumber(schema, maxKey, value)
+		case "gt":
+			if numeric {
+				setNumber(schema, "exclusiveMinimum", value)
+			}
+		case "lt":
+			if numeric {
+				setNumber(schema, "exclusiveMaximum", value)
+			}
+		case "len":
+			setNumber(schema, minKey, value)
+			setNumber(schema, maxKey, value)
+		case "oneof":
+			var enum []any
+			for _, option := range strings.Fields(value) {
+				enum = append(enum, convertExample(option, field.Kind))
+			}
+			schema["enum"] = enum
+		}
+	}
+
+	for _, rule := range field.Validation.CustomRules {
+		switch rule {
+		case "email":
+			schema["format"] = "email"
+		case "url", "uri":
+			schema["format"] = "uri"
+		case "uuid", "uuid4":
+			schema["format"] = "uuid"
+		case "datetime":
+			schema["format"] = "date-time"
+		case "ipv4":
+			schema["format"] = "ipv4"
+		case "ipv6":
+			schema["format"] = "ipv6"
+		}
+	}
+}
+
+// setNumber stores a numeric keyword, ignoring values that do not parse
+func setNumber(schema map[string]any, key, value string) {
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		if n == float64(int64(n)) {
+			schema[key] = int64(n)
+		} else {
+			schema[key] = n
+		}
+	}
+}
+
+// convertExample turns a tag string into a JSON value matching the field kind
+func convertExample(example any, kind string) any {
+	s, ok := example.(string)
+	if !ok {
+		return example
+	}
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "float32", "float64":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+// nullable widens a schema to also accept null
+func nullable(schema map[string]any) map[string]any {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+		return schema
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
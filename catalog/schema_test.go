@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testSignup struct {
+	Email    string        `json:"email" validate:"required,email" desc:"Login email" example:"ada@example.com"`
+	Age      int           `json:"age" validate:"min=18,max=130" example:"42"`
+	Code     string        `json:"code" validate:"len=6"`
+	Plan     string        `json:"plan" validate:"oneof=free pro"`
+	Secret   string        `json:"-"`
+	Home     testAddress   `json:"home"`
+	Previous []testAddress `json:"previous"`
+	Referrer *testSignup   `json:"referrer"`
+}
+
+func TestJSONSchema_PopulatedBySelect(t *testing.T) {
+	schema := Select[testSignup]().JSONSchema
+	if schema == nil {
+		t.Fatal("Expected JSONSchema to be populated")
+	}
+	if schema["$schema"] != JSONSchemaDraft {
+		t.Errorf("Expected draft 2020-12 dialect, got %v", schema["$schema"])
+	}
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("Schema should be JSON serializable: %v", err)
+	}
+
+	properties := schema["properties"].(map[string]any)
+	if _, exists := properties["Secret"]; exists {
+		t.Error("Fields tagged json:\"-\" should be omitted")
+	}
+	required := schema["required"].([]string)
+	if len(required) != 1 || required[0] != "email" {
+		t.Errorf("Expected required [email], got %v", required)
+	}
+}
+
+func TestJSONSchema_ValidationKeywords(t *testing.T) {
+	properties := Select[testSignup]().JSONSchema["properties"].(map[string]any)
+
+	email := properties["email"].(map[string]any)
+	if email["format"] != "email" || email["description"] != "Login email" {
+		t.Errorf("Unexpected email schema: %v", email)
+	}
+	if examples := email["examples"].([]any); examples[0] != "ada@example.com" {
+		t.Errorf("Unexpected examples: %v", examples)
+	}
+
+	age := properties["age"].(map[string]any)
+	if age["type"] != "integer" || age["minimum"] != int64(18) || age["maximum"] != int64(130) {
+		t.Errorf("Unexpected age schema: %v", age)
+	}
+	if examples := age["examples"].([]any); examples[0] != int64(42) {
+		t.Errorf("Expected numeric example, got %v", examples[0])
+	}
+
+	code := properties["code"].(map[string]any)
+	if code["minLength"] != int64(6) || code["maxLength"] != int64(6) {
+		t.Errorf("Unexpected code schema: %v", code)
+	}
+
+	plan := properties["plan"].(map[string]any)
+	if enum := plan["enum"].([]any); len(enum) != 2 || enum[1] != "pro" {
+		t.Errorf("Unexpected plan enum: %v", plan["enum"])
+	}
+}
+
+func TestJSONSchema_DefsAndRefs(t *testing.T) {
+	schema := Select[testSignup]().JSONSchema
+	properties := schema["properties"].(map[string]any)
+	defs := schema["$defs"].(map[string]any)
+
+	if _, exists := defs["zbz.catalog.testAddress"]; !exists {
+		t.Fatalf("Expected testAddress in $defs, got %v", defs)
+	}
+	if home := properties["home"].(map[string]any); home["$ref"] != "#/$defs/zbz.catalog.testAddress" {
+		t.Errorf("Expected home to reference testAddress, got %v", home)
+	}
+	items := properties["previous"].(map[string]any)["items"].(map[string]any)
+	if items["$ref"] != "#/$defs/zbz.catalog.testAddress" {
+		t.Errorf("Expected slice items to reference testAddress, got %v", items)
+	}
+
+	referrer := properties["referrer"].(map[string]any)
+	anyOf := referrer["anyOf"].([]any)
+	if anyOf[0].(map[string]any)["$ref"] != "#" {
+		t.Errorf("Expected recursive root reference, got %v", referrer)
+	}
+}
+
+type testTreeBase struct {
+	ID     string        `json:"id"`
+	Parent *testTreeBase `json:"parent"`
+}
+
+type testTreeDoc struct {
+	testTreeBase
+	Title string `json:"title"`
+}
+
+func TestJSONSchema_RecursionThroughFlattenedEmbed(t *testing.T) {
+	schema := Select[testTreeDoc]().JSONSchema
+	properties := schema["properties"].(map[string]any)
+	if _, exists := properties["id"]; !exists {
+		t.Fatalf("Expected embedded fields to be flattened, got %v", properties)
+	}
+
+	parent := properties["parent"].(map[string]any)["anyOf"].([]any)[0].(map[string]any)
+	if parent["$ref"] != "#/$defs/zbz.catalog.testTreeBase" {
+		t.Fatalf("Expected parent to reference testTreeBase, got %v", parent)
+	}
+	defs, _ := schema["$defs"].(map[string]any)
+	def, exists := defs["zbz.catalog.testTreeBase"].(map[string]any)
+	if !exists {
+		t.Fatalf("Expected testTreeBase in $defs, got %v", defs)
+	}
+	inner := def["properties"].(map[string]any)["parent"].(map[string]any)["anyOf"].([]any)[0].(map[string]any)
+	if inner["$ref"] != "#/$defs/zbz.catalog.testTreeBase" {
+		t.Errorf("Expected the def to reference itself, got %v", inner)
+	}
+}
@@ -0,0 +1,258 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SnapshotVersion is the document version written by Export.
+// Bump it when the snapshot layout changes incompatibly.
+const SnapshotVersion = 1
+
+// SnapshotFormat selects the encoding of a snapshot document
+type SnapshotFormat string
+
+const (
+	SnapshotJSON SnapshotFormat = "json"
+	SnapshotYAML SnapshotFormat = "yaml"
+)
+
+// Snapshot is a serializable copy of the whole catalog, so docs generation,
+// SDK generation and Diff can run against a build artifact instead of a live process
+type Snapshot struct {
+	Version     int             `json:"version"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Models      []ModelMetadata `json:"models"`
+	Conventions []Convention    `json:"conventions,omitempty"`
+}
+
+// Export captures every catalogued model and registered convention
+func Export() Snapshot {
+	cacheMutex.RLock()
+	models := make([]ModelMetadata, 0, len(metadataCache))
+	for _, metadata := range metadataCache {
+		models = append(models, metadata)
+	}
+	cacheMutex.RUnlock()
+
+	sort.Slice(models, func(i, j int) bool { return models[i].QualifiedName < models[j].QualifiedName })
+
+	return Snapshot{
+		Version:     SnapshotVersion,
+		GeneratedAt: time.Now().UTC(),
+		Models:      models,
+		Conventions: Conventions(),
+	}
+}
+
+// Import loads snapshot models into the catalog for offline lookups.
+// Types already extracted from live code take precedence, and a live
+// Select[T]() replaces an imported entry.
+func Import(snapshot Snapshot) error {
+	if err := checkSnapshotVersion(snapshot.Version); err != nil {
+		return err
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	for _, metadata := range snapshot.Models {
+		if metadata.QualifiedName == "" {
+			return fmt.Errorf("snapshot model %q has no qualified name", metadata.TypeName)
+		}
+		if _, exists := metadataCache[metadata.QualifiedName]; !exists {
+			metadataCache[metadata.QualifiedName] = metadata
+		}
+	}
+	return nil
+}
+
+// Model finds a snapshot model by qualified name, or by short name when unambiguous
+func (s Snapshot) Model(name string) (ModelMetadata, error) {
+	var matches []ModelMetadata
+	for _, metadata := range s.Models {
+		if metadata.QualifiedName == name {
+			return metadata, nil
+		}
+		if metadata.TypeName == name {
+			matches = append(matches, metadata)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return ModelMetadata{}, fmt.Errorf("%w: %s", ErrTypeNotFound, name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, match := range matches {
+			names[i] = match.QualifiedName
+		}
+		return ModelMetadata{}, fmt.Errorf("%w: %s matches %s", ErrAmbiguousType, name, strings.Join(names, ", "))
+	}
+}
+
+// DiffSnapshots compares every model present in either snapshot. Models that
+// disappeared are reported as a removal of each of their fields; new models
+// are a single compatible ModelAdded change.
+func DiffSnapshots(old, new Snapshot) []SchemaDiff {
+	oldModels := make(map[string]ModelMetadata)
+	for _, metadata := range old.Models {
+		oldModels[metadata.QualifiedName] = metadata
+	}
+
+	var diffs []SchemaDiff
+	seen := make(map[string]bool)
+	for _, metadata := range new.Models {
+		seen[metadata.QualifiedName] = true
+		previous, existed := oldModels[metadata.QualifiedName]
+		if !existed {
+			// No client or stored record uses a new model yet
+			diffs = append(diffs, SchemaDiff{
+				TypeName: metadata.QualifiedName,
+				Changes:  []Change{{Kind: ModelAdded, New: metadata.TypeName}},
+			})
+			continue
+		}
+		if diff := Diff(previous, metadata); len(diff.Changes) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, metadata := range old.Models {
+		if !seen[metadata.QualifiedName] {
+			if diff := Diff(metadata, ModelMetadata{}); len(diff.Changes) > 0 {
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+	return diffs
+}
+
+// WriteSnapshot encodes a snapshot as JSON or YAML
+func WriteSnapshot(w io.Writer, snapshot Snapshot, format SnapshotFormat) error {
+	switch format {
+	case SnapshotJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshot)
+	case SnapshotYAML:
+		// Route through JSON so YAML keys match the json tags exactly
+		generic, err := toGeneric(snapshot)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(generic); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unsupported snapshot format: %s", format)
+	}
+}
+
+// ReadSnapshot decodes a JSON or YAML snapshot and checks its version
+func ReadSnapshot(r io.Reader, format SnapshotFormat) (Snapshot, error) {
+	var snapshot Snapshot
+
+	switch format {
+	case SnapshotJSON:
+		if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+			return Snapshot{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+	case SnapshotYAML:
+		var generic any
+		if err := yaml.NewDecoder(r).Decode(&generic); err != nil {
+			return Snapshot{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+		data, err := json.Marshal(generic)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return Snapshot{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+	default:
+		return Snapshot{}, fmt.Errorf("unsupported snapshot format: %s", format)
+	}
+
+	if err := checkSnapshotVersion(snapshot.Version); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// SaveSnapshot exports the catalog to path, choosing the format by extension
+func SaveSnapshot(path string) error {
+	format, err := snapshotFormatFor(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := WriteSnapshot(file, Export(), format); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// LoadSnapshot reads a snapshot from path, choosing the format by extension
+func LoadSnapshot(path string) (Snapshot, error) {
+	format, err := snapshotFormatFor(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer file.Close()
+
+	return ReadSnapshot(file, format)
+}
+
+func snapshotFormatFor(path string) (SnapshotFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return SnapshotJSON, nil
+	case ".yaml", ".yml":
+		return SnapshotYAML, nil
+	default:
+		return "", fmt.Errorf("cannot infer snapshot format from %q", path)
+	}
+}
+
+func checkSnapshotVersion(version int) error {
+	if version < 1 || version > SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d (supported: 1-%d)", version, SnapshotVersion)
+	}
+	return nil
+}
+
+// toGeneric converts a value to maps and slices via its JSON encoding
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot_RoundTripFormats(t *testing.T) {
+	Select[testCustomer]()
+	Select[testSignup]()
+	exported := Export()
+
+	for _, format := range []SnapshotFormat{SnapshotJSON, SnapshotYAML} {
+		var buf bytes.Buffer
+		if err := WriteSnapshot(&buf, exported, format); err != nil {
+			t.Fatalf("%s: write failed: %v", format, err)
+		}
+
+		loaded, err := ReadSnapshot(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", format, err)
+		}
+		if loaded.Version != SnapshotVersion || len(loaded.Models) != len(exported.Models) {
+			t.Fatalf("%s: expected %d models at version %d, got %d at %d", format, len(exported.Models), SnapshotVersion, len(loaded.Models), loaded.Version)
+		}
+
+		model, err := loaded.Model(GetQualifiedTypeName[testCustomer]())
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if diff := Diff(model, Select[testCustomer]()); len(diff.Changes) != 0 {
+			t.Errorf("%s: loaded model differs from live metadata: %v", format, diff.Changes)
+		}
+		if model.JSONSchema["$schema"] != JSONSchemaDraft {
+			t.Errorf("%s: schema not preserved", format)
+		}
+	}
+}
+
+func TestSnapshot_SaveAndLoadByExtension(t *testing.T) {
+	Select[testSignup]()
+	path := filepath.Join(t.TempDir(), "catalog.yaml")
+
+	if err := SaveSnapshot(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, err := loaded.Model("testSignup"); err != nil {
+		t.Errorf("Expected short-name lookup in snapshot: %v", err)
+	}
+
+	if err := SaveSnapshot(filepath.Join(t.TempDir(), "catalog.txt")); err == nil {
+		t.Error("Expected error for unknown extension")
+	}
+}
+
+func TestSnapshot_RejectsUnknownVersion(t *testing.T) {
+	_, err := ReadSnapshot(strings.NewReader(`{"version": 99, "models": []}`), SnapshotJSON)
+	if err == nil {
+		t.Error("Expected error for unsupported version")
+	}
+}
+
+func TestSnapshot_ImportForOfflineLookup(t *testing.T) {
+	offline := ModelMetadata{TypeName: "OfflineOnly", QualifiedName: "example.com/offline.OfflineOnly"}
+	if err := Import(Snapshot{Version: SnapshotVersion, Models: []ModelMetadata{offline}}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	metadata, err := Lookup("example.com/offline.OfflineOnly")
+	if err != nil || metadata.TypeName != "OfflineOnly" {
+		t.Errorf("Expected imported model to be found: %v", err)
+	}
+}
+
+func TestSnapshot_DiffSnapshots(t *testing.T) {
+	old := Snapshot{Version: SnapshotVersion, Models: []ModelMetadata{Select[testAccountV1]()}}
+	renamed := Select[testAccountV2]()
+	renamed.QualifiedName = old.Models[0].QualifiedName
+	current := Snapshot{Version: SnapshotVersion, Models: []ModelMetadata{renamed}}
+
+	diffs := DiffSnapshots(old, current)
+	if len(diffs) != 1 || !diffs[0].IsBreaking() {
+		t.Errorf("Expected one breaking diff, got %v", diffs)
+	}
+
+	added := DiffSnapshots(Snapshot{Version: SnapshotVersion}, current)
+	if len(added) != 1 || added[0].IsBreaking() || len(added[0].Changes) != 1 || added[0].Changes[0].Kind != ModelAdded {
+		t.Errorf("Adding a model should be one compatible change, got %v", added)
+	}
+
+	removed := DiffSnapshots(old, Snapshot{Version: SnapshotVersion})
+	if len(removed) != 1 || !removed[0].IsBreaking() {
+		t.Errorf("Removing a model should be breaking, got %v", removed)
+	}
+}
@@ -0,0 +1,213 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagParser turns a raw struct tag value into a typed extension value
+type TagParser func(value string) (any, error)
+
+// Tag extractor registry
+var (
+	tagExtractors = make(map[string]TagParser)
+	tagMutex      sync.RWMutex
+)
+
+// builtinTags are parsed into dedicated FieldMetadata fields and cannot be overridden
+var builtinTags = []string{"json", "db", "validate", "scope", "encrypt", "encrypt_algo", "key_rotation", "data_residency", "redact", "desc", "example"}
+
+// RegisterTag registers a typed parser for a struct tag owned by another
+// package, e.g. astql:"index:btree" or merge:"union". Parsed values are
+// stored in FieldMetadata.Extensions and read back with Extension[V].
+// Types already in the catalog are re-parsed.
+func RegisterTag[V any](tag string, parse func(value string) (V, error)) error {
+	for _, builtin := range builtinTags {
+		if tag == builtin {
+			return fmt.Errorf("tag %s is built in and cannot be overridden", tag)
+		}
+	}
+
+	tagMutex.Lock()
+	if _, exists := tagExtractors[tag]; exists {
+		tagMutex.Unlock()
+		return fmt.Errorf("tag %s already has a registered extractor", tag)
+	}
+	tagExtractors[tag] = func(value string) (any, error) {
+		return parse(value)
+	}
+	tagMutex.Unlock()
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	for identity, metadata := range metadataCache {
+		metadata.Fields = reparseExtensions(metadata.Fields, tag)
+		metadataCache[identity] = metadata
+	}
+	return nil
+}
+
+// Extension returns the parsed value of a registered tag. Values loaded from
+// a snapshot arrive as generic JSON and are decoded into V on demand.
+func Extension[V any](field FieldMetadata, tag string) (V, bool) {
+	var zero V
+	value, exists := field.Extensions[tag]
+	if !exists {
+		return zero, false
+	}
+	if typed, ok := value.(V); ok {
+		return typed, true
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return zero, false
+	}
+	var decoded V
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return zero, false
+	}
+	return decoded, true
+}
+
+// extractExtensions runs every registered parser whose tag is present
+func extractExtensions(tags map[string]string) (map[string]any, map[string]string) {
+	tagMutex.RLock()
+	defer tagMutex.RUnlock()
+
+	var extensions map[string]any
+	var errs map[string]string
+	for tag, parse := range tagExtractors {
+		value, exists := tags[tag]
+		if !exists {
+			continue
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			if errs == nil {
+				errs = make(map[string]string)
+			}
+			errs[tag] = err.Error()
+			continue
+		}
+		if extensions == nil {
+			extensions = make(map[string]any)
+		}
+		extensions[tag] = parsed
+	}
+	return extensions, errs
+}
+
+// reparseExtensions applies a newly registered tag to cached fields. Slices
+// and maps handed out by Select are shared with callers, so it returns new
+// ones and leaves the originals untouched.
+func reparseExtensions(fields []FieldMetadata, tag string) []FieldMetadata {
+	if fields == nil {
+		return nil
+	}
+	reparsed := make([]FieldMetadata, len(fields))
+	for i, field := range fields {
+		if value, exists := field.Tags[tag]; exists {
+			extensions, errs := extractExtensions(map[string]string{tag: value})
+			field.Extensions = mergeInto(field.Extensions, extensions)
+			field.ExtensionErrors = mergeInto(field.ExtensionErrors, errs)
+		}
+		field.Fields = reparseExtensions(field.Fields, tag)
+		reparsed[i] = field
+	}
+	return reparsed
+}
+
+// mergeInto returns a copy of base with additions applied, or base itself
+// when there is nothing to add
+func mergeInto[V any](base, additions map[string]V) map[string]V {
+	if len(additions) == 0 {
+		return base
+	}
+	merged := make(map[string]V, len(base)+len(additions))
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range additions {
+		merged[name] = value
+	}
+	return merged
+}
+
+// extractAllTags returns every key:"value" pair in the struct tag, following
+// the same syntax rules as reflect.StructTag.Lookup
+func extractAllTags(field reflect.StructField) map[string]string {
+	tags := make(map[string]string)
+	tag := string(field.Tag)
+
+	for tag != "" {
+		// Skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon; a key is a non-empty run of non-control, non-space, non-quote characters
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			break
+		}
+		if value != "" {
+			tags[name] = value
+		}
+	}
+
+	return tags
+}
+
+// parseRedaction parses the built-in redact tag:
+// "mask", "zero", "hash", "tokenize", "partial:N" or any other literal
+// replacement value
+func parseRedaction(value string) RedactionInfo {
+	switch value {
+	case "":
+		return RedactionInfo{}
+	case "mask", "zero", "hash", "tokenize":
+		return RedactionInfo{Strategy: value}
+	}
+
+	if keep, ok := strings.CutPrefix(value, "partial:"); ok {
+		if n, err := strconv.Atoi(keep); err == nil && n >= 0 {
+			return RedactionInfo{Strategy: "partial", Keep: n}
+		}
+	}
+
+	return RedactionInfo{Strategy: "custom", Value: value}
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testIndexHint struct {
+	Kind   string
+	Unique bool
+}
+
+type testTagged struct {
+	Email  string `json:"email" astqlx:"index:btree,unique" astqly:"index:hash" custom:"kept"`
+	Name   string `json:"name" astqlx:"bogus"`
+	SSN    string `json:"ssn" redact:"partial:4"`
+	Card   string `json:"card" redact:"mask"`
+	Secret string `json:"secret" redact:"[HIDDEN]"`
+	Handle string `json:"handle" redact:"tokenize"`
+}
+
+func parseIndexHint(value string) (testIndexHint, error) {
+	hint := testIndexHint{}
+	for _, part := range strings.Split(value, ",") {
+		switch {
+		case strings.HasPrefix(part, "index:"):
+			hint.Kind = strings.TrimPrefix(part, "index:")
+		case part == "unique":
+			hint.Unique = true
+		default:
+			return hint, fmt.Errorf("unknown hint %q", part)
+		}
+	}
+	return hint, nil
+}
+
+func TestTags_AllTagsPreserved(t *testing.T) {
+	email := Select[testTagged]().Fields[0]
+	if email.Tags["custom"] != "kept" || email.Tags["astqlx"] != "index:btree,unique" {
+		t.Errorf("Expected unknown tags to be preserved, got %v", email.Tags)
+	}
+}
+
+func TestTags_RegisteredParser(t *testing.T) {
+	Select[testTagged]() // Cache before registering to exercise re-parsing
+
+	if err := RegisterTag("astqlx", parseIndexHint); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fields := Select[testTagged]().Fields
+	hint, ok := Extension[testIndexHint](fields[0], "astqlx")
+	if !ok || hint.Kind != "btree" || !hint.Unique {
+		t.Errorf("Expected parsed index hint, got %+v (ok=%v)", hint, ok)
+	}
+	if _, ok := Extension[testIndexHint](fields[1], "astqlx"); ok {
+		t.Error("Invalid tag values should not produce an extension")
+	}
+	if fields[1].ExtensionErrors["astqlx"] == "" {
+		t.Error("Expected parse error to be recorded")
+	}
+
+	if err := RegisterTag("astqlx", parseIndexHint); err == nil {
+		t.Error("Expected error on duplicate registration")
+	}
+	if err := RegisterTag("json", func(v string) (string, error) { return v, nil }); err == nil {
+		t.Error("Expected error when overriding a built-in tag")
+	}
+}
+
+func TestTags_RegisterDoesNotMutateSelected(t *testing.T) {
+	held := Select[testTagged]()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			for _, field := range held.Fields {
+				_ = field.Extensions["astqly"]
+			}
+		}
+	}()
+	if err := RegisterTag("astqly", parseIndexHint); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	<-done
+
+	if _, ok := Extension[testIndexHint](held.Fields[0], "astqly"); ok {
+		t.Error("Expected metadata selected before registration to be left untouched")
+	}
+	if _, ok := Extension[testIndexHint](Select[testTagged]().Fields[0], "astqly"); !ok {
+		t.Error("Expected newly selected metadata to carry the extension")
+	}
+}
+
+func TestTags_RedactionStrategies(t *testing.T) {
+	fields := Select[testTagged]().Fields
+
+	if ssn := fields[2].Redaction; ssn.Strategy != "partial" || ssn.Keep != 4 {
+		t.Errorf("Expected partial:4, got %+v", ssn)
+	}
+	if card := fields[3].Redaction; card.Strategy != "mask" || card.Value != "" {
+		t.Errorf("Expected mask strategy, got %+v", card)
+	}
+	if secret := fields[4].Redaction; secret.Strategy != "custom" || secret.Value != "[HIDDEN]" {
+		t.Errorf("Expected custom literal, got %+v", secret)
+	}
+	if handle := fields[5].Redaction; handle.Strategy != "tokenize" {
+		t.Errorf("Expected tokenize strategy, got %+v", handle)
+	}
+}
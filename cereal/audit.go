@@ -0,0 +1,282 @@
+package cereal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"zbz/catalog"
+)
+
+// ErrAuditFailed is returned when an access audit record cannot be written.
+// The operation fails with it: data that can't be audited isn't served.
+var ErrAuditFailed = errors.New("access audit failed")
+
+// AccessDecision is what happened to one field in an audited operation
+type AccessDecision string
+
+const (
+	DecisionReturned AccessDecision = "returned" // Marshaled as is
+	DecisionRedacted AccessDecision = "redacted" // Marshaled with its value hidden
+	DecisionAccepted AccessDecision = "accepted" // Unmarshaled and kept
+	DecisionRejected AccessDecision = "rejected" // Unmarshaled and discarded for lack of scope
+)
+
+// FieldAccess records the decision made for one field
+type FieldAccess struct {
+	Path           string         `json:"path"`                     // Field path in the operation's format
+	Type           string         `json:"type"`                     // Go type of the field
+	Classification string         `json:"classification,omitempty"` // Encryption type, e.g. "pii"
+	Decision       AccessDecision `json:"decision"`
+}
+
+// AuditRecord describes one audited marshal or unmarshal: who asked, why,
+// and what each field's fate was
+type AuditRecord struct {
+	Time        time.Time     `json:"time"`
+	Action      string        `json:"action"` // "marshal" or "unmarshal"
+	Model       string        `json:"model"`
+	Format      string        `json:"format"`
+	Identity    string        `json:"identity,omitempty"`
+	Purpose     string        `json:"purpose,omitempty"`
+	Tenant      string        `json:"tenant,omitempty"`
+	Permissions []string      `json:"permissions"`
+	Fields      []FieldAccess `json:"fields"`
+	Success     bool          `json:"success"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Field returns the access recorded for a field path
+func (r AuditRecord) Field(path string) (FieldAccess, bool) {
+	for _, access := range r.Fields {
+		if access.Path == path {
+			return access, true
+		}
+	}
+	return FieldAccess{}, false
+}
+
+// AuditSink receives access audit records. Records are written synchronously;
+// an error fails the audited operation with ErrAuditFailed.
+type AuditSink interface {
+	WriteAudit(record AuditRecord) error
+}
+
+// AuditFunc adapts a function to an AuditSink, e.g. to forward records to capitan
+type AuditFunc func(record AuditRecord) error
+
+// WriteAudit implements AuditSink
+func (f AuditFunc) WriteAudit(record AuditRecord) error {
+	return f(record)
+}
+
+var (
+	auditSink AuditSink
+	auditMu   sync.RWMutex
+)
+
+// SetAuditSink turns on access auditing: every marshal and unmarshal writes
+// an AuditRecord to sink. nil turns it off.
+func SetAuditSink(sink AuditSink) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditSink = sink
+}
+
+// GetAuditSink returns the current audit sink, or nil when auditing is off
+func GetAuditSink() AuditSink {
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	return auditSink
+}
+
+// MemoryAuditSink keeps audit records in memory, for tests and short-lived tools
+type MemoryAuditSink struct {
+	mu      sync.RWMutex
+	records []AuditRecord
+}
+
+// NewMemoryAuditSink creates an empty in-memory audit sink
+func NewMemoryAuditSink() *MemoryAuditSink {
+	return &MemoryAuditSink{}
+}
+
+// WriteAudit implements AuditSink
+func (s *MemoryAuditSink) WriteAudit(record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Records returns every record written so far, oldest first
+func (s *MemoryAuditSink) Records() []AuditRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]AuditRecord(nil), s.records...)
+}
+
+// Accesses returns the records in which a model's field had the given
+// decision, e.g. who was returned User's ssn
+func (s *MemoryAuditSink) Accesses(model, path string, decision AccessDecision) []AuditRecord {
+	matches := []AuditRecord{}
+	for _, record := range s.Records() {
+		if access, found := record.Field(path); found && record.Model == model && access.Decision == decision {
+			matches = append(matches, record)
+		}
+	}
+	return matches
+}
+
+// Reset discards all records
+func (s *MemoryAuditSink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = nil
+}
+
+// FileAuditSink appends audit records to a file as JSON lines, with
+// owner-only permissions
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditSink opens path for appending, creating it if it does not exist
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &FileAuditSink{file: file}, nil
+}
+
+// WriteAudit implements AuditSink; each record is one line, synced to disk
+func (s *FileAuditSink) WriteAudit(record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the underlying file
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ReadAuditLog reads the records written by a FileAuditSink
+func ReadAuditLog(path string) ([]AuditRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer file.Close()
+
+	records := []AuditRecord{}
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var record AuditRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("parse audit log %s: %w", path, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// accessAudit collects the field decisions of one operation. A nil
+// *accessAudit still emits field events, without an identity.
+type accessAudit struct {
+	ctx    context.Context
+	sink   AuditSink
+	record AuditRecord
+}
+
+// beginAudit starts collecting decisions for an operation on v
+func beginAudit(ctx context.Context, action, format string, v any, permissions []string) *accessAudit {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	audit := &accessAudit{ctx: ctx, sink: GetAuditSink()}
+	if audit.sink == nil {
+		return audit
+	}
+
+	model := "unknown"
+	if v != nil {
+		if metadata := catalog.ExtractAndCacheMetadata(v); metadata.TypeName != "" {
+			model = metadata.TypeName
+		}
+	}
+	audit.record = AuditRecord{
+		Time:        time.Now().UTC(),
+		Action:      action,
+		Model:       model,
+		Format:      format,
+		Identity:    IdentityFrom(ctx),
+		Purpose:     PurposeFrom(ctx),
+		Tenant:      TenantFrom(ctx),
+		Permissions: permissions,
+		Fields:      []FieldAccess{},
+	}
+	return audit
+}
+
+// context returns the context the operation runs in
+func (a *accessAudit) context() context.Context {
+	if a == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+// field records a decision and emits it as a scope_check event
+func (a *accessAudit) field(model, path string, meta catalog.FieldMetadata, decision AccessDecision, permissions []string) {
+	access := FieldAccess{
+		Path:           path,
+		Type:           meta.Type,
+		Classification: meta.Encryption.Type,
+		Decision:       decision,
+	}
+	emitFieldScopeEvent(a.context(), model, access, permissions)
+
+	if a != nil && a.sink != nil {
+		a.record.Fields = append(a.record.Fields, access)
+	}
+}
+
+// finish writes the record and returns the operation's error, or
+// ErrAuditFailed when the record could not be written
+func (a *accessAudit) finish(err error) error {
+	if a == nil || a.sink == nil {
+		return err
+	}
+
+	a.record.Success = err == nil
+	if err != nil {
+		a.record.Error = err.Error()
+	}
+	if writeErr := a.sink.WriteAudit(a.record); writeErr != nil && err == nil {
+		return fmt.Errorf("%w: %v", ErrAuditFailed, writeErr)
+	}
+	return err
+}
+
+// redactedBy reports whether a field processor hid or replaced a value
+func redactedBy(processed []Field, original reflect.Value) bool {
+	return len(processed) == 0 || !reflect.DeepEqual(processed[0].Value, original.Interface())
+}
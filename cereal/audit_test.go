@@ -0,0 +1,148 @@
+package cereal
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type auditPatient struct {
+	Name   string `json:"name"`
+	SSN    string `json:"ssn" encrypt:"pii"`
+	Salary int    `json:"salary" scope:"finance"`
+}
+
+func useAuditSink(t *testing.T, sink AuditSink) {
+	SetAuditSink(sink)
+	t.Cleanup(func() { SetAuditSink(nil) })
+}
+
+func TestAudit_Marshal(t *testing.T) {
+	sink := NewMemoryAuditSink()
+	useAuditSink(t, sink)
+
+	patient := auditPatient{Name: "Ada", SSN: "123-45-6789", Salary: 100}
+	ctx := WithPurpose(WithIdentity(context.Background(), "alice"), "support-ticket")
+	if _, err := JSON.MarshalContext(ctx, patient, "user"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := YAML.MarshalContext(WithIdentity(context.Background(), "bob"), patient, "pii", "finance"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	records := sink.Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	alice := records[0]
+	if alice.Identity != "alice" || alice.Purpose != "support-ticket" || alice.Action != "marshal" || alice.Format != "json" || !alice.Success {
+		t.Errorf("Expected alice's marshal to be recorded, got %+v", alice)
+	}
+	expected := map[string]AccessDecision{"name": DecisionReturned, "ssn": DecisionRedacted, "salary": DecisionRedacted}
+	for path, decision := range expected {
+		if access, _ := alice.Field(path); access.Decision != decision {
+			t.Errorf("Expected %s to be %s, got %+v", path, decision, access)
+		}
+	}
+	if access, _ := alice.Field("ssn"); access.Classification != "pii" {
+		t.Errorf("Expected ssn to be classified pii, got %q", access.Classification)
+	}
+
+	// Who saw the SSN?
+	seen := sink.Accesses(alice.Model, "ssn", DecisionReturned)
+	if len(seen) != 1 || seen[0].Identity != "bob" {
+		t.Errorf("Expected only bob to have seen the ssn, got %+v", seen)
+	}
+}
+
+func TestAudit_Unmarshal(t *testing.T) {
+	sink := NewMemoryAuditSink()
+	useAuditSink(t, sink)
+
+	var patient auditPatient
+	ctx := WithIdentity(context.Background(), "mallory")
+	if err := JSON.UnmarshalContext(ctx, []byte(`{"name":"Ada","salary":1000000}`), &patient, "user"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	record := sink.Records()[0]
+	if access, _ := record.Field("salary"); access.Decision != DecisionRejected {
+		t.Errorf("Expected salary to be rejected, got %+v", access)
+	}
+	if access, _ := record.Field("name"); access.Decision != DecisionAccepted {
+		t.Errorf("Expected name to be accepted, got %+v", access)
+	}
+	if record.Identity != "mallory" || record.Action != "unmarshal" {
+		t.Errorf("Expected mallory's unmarshal to be recorded, got %+v", record)
+	}
+}
+
+func TestAudit_SinkFailureFailsClosed(t *testing.T) {
+	useAuditSink(t, AuditFunc(func(AuditRecord) error { return errors.New("disk full") }))
+
+	data, err := JSON.Marshal(auditPatient{Name: "Ada"})
+	if !errors.Is(err, ErrAuditFailed) || data != nil {
+		t.Errorf("Expected ErrAuditFailed and no data, got %s (%v)", data, err)
+	}
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	useAuditSink(t, sink)
+
+	request := httptest.NewRequest("GET", "/patients/1", nil)
+	request = request.WithContext(WithIdentity(request.Context(), "carol"))
+	if err := Encode(httptest.NewRecorder(), request, 200, auditPatient{Name: "Ada", SSN: "123-45-6789"}, "pii"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	sink.Close()
+
+	records, err := ReadAuditLog(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(records) != 1 || records[0].Identity != "carol" {
+		t.Fatalf("Expected carol's request to be logged, got %+v", records)
+	}
+	if access, _ := records[0].Field("ssn"); access.Decision != DecisionReturned {
+		t.Errorf("Expected ssn to be returned, got %+v", access)
+	}
+}
+
+type channelEventSink chan CerealEvent
+
+func (c channelEventSink) EmitCerealEvent(event CerealEvent) { c <- event }
+
+func TestFieldScopeEvents(t *testing.T) {
+	events := make(channelEventSink, 16)
+	SetEventSink(events)
+	t.Cleanup(func() { SetEventSink(nil) })
+
+	ctx := WithIdentity(context.Background(), "alice")
+	if _, err := JSON.MarshalContext(ctx, auditPatient{Name: "Ada", Salary: 100}, "user"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Action != "scope_check" || event.FieldName != "salary" {
+				continue
+			}
+			if event.Identity != "alice" || event.Success || event.Metadata["decision"] != "redacted" {
+				t.Errorf("Expected a redacted salary decision for alice, got %+v", event)
+			}
+			return
+		case <-timeout:
+			t.Fatal("Expected a scope_check event for salary")
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package cereal
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
@@ -19,57 +21,75 @@ func NewCatalogScoper() *CatalogScoper {
 	return &CatalogScoper{}
 }
 
-// FilterForMarshal applies scoping for marshal operations using catalog metadata
-func (cs *CatalogScoper) FilterForMarshal(data any, userPermissions []string) any {
-	// Use catalog to get type metadata instead of reflection
+// Scope returns a copy of data in which every field the caller may not read
+// is redacted, at any depth. Field paths in errors use the names the given
+// format ("json", "yaml", "toml") encodes them under. A model whose
+// ScopeProvider requirements are not met yields a *ScopeError.
+func (cs *CatalogScoper) Scope(data any, format string, userPermissions []string) (any, error) {
+	scoped, _, err := cs.scope(data, format, userPermissions, nil)
+	return scoped, err
+}
+
+// scope is Scope, recording each field's decision in audit. It also returns
+// the paths of the values it replaced.
+func (cs *CatalogScoper) scope(data any, format string, userPermissions []string, audit *accessAudit) (any, map[string]bool, error) {
+	if data == nil {
+		return nil, nil, nil
+	}
+	
 	metadata := catalog.ExtractAndCacheMetadata(data)
+	walker := &scopeWalker{
+		format:      format,
+		permissions: userPermissions,
+		visited:     make(map[visitKey]reflect.Value),
+		model:       metadata.TypeName,
+		audit:       audit,
+	}
 	
-	// Check for convention-based scope requirements first
-	if err := cs.checkConventionScopes(data, userPermissions, metadata); err != nil {
-		// Return nil or error marker for failed scope check
+	scoped, err := walker.walk(reflect.ValueOf(data), metadata.Fields, "", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scoped.Interface(), walker.redacted, nil
+}
+
+// FilterForMarshal applies scoping for marshal operations using catalog metadata.
+// It returns nil when the scope check fails; use Scope to get the error.
+func (cs *CatalogScoper) FilterForMarshal(data any, userPermissions []string) any {
+	scoped, err := cs.Scope(data, "json", userPermissions)
+	if err != nil {
+		return nil
+	}
+	return scoped
+}
+
+// Enforce zeroes, in place, every decoded field the caller may not write, at
+// any depth. data must be a pointer. A model whose ScopeProvider requirements
+// are not met yields a *ScopeError.
+func (cs *CatalogScoper) Enforce(data any, format string, userPermissions []string) error {
+	return cs.enforce(data, format, userPermissions, nil)
+}
+
+// enforce is Enforce, recording each field's decision in audit
+func (cs *CatalogScoper) enforce(data any, format string, userPermissions []string, audit *accessAudit) error {
+	if data == nil {
 		return nil
 	}
 	
-	// Create fields for processing based on catalog metadata
-	var processedFields []Field
-	
-	// Process each field using catalog metadata
-	for _, fieldMeta := range metadata.Fields {
-		// Create field from catalog metadata
-		field := Field{
-			Key:         fieldMeta.Name,
-			Type:        cs.mapCatalogFieldType(fieldMeta),
-			Value:       nil, // Will be set by field processor
-			Permissions: userPermissions,
-			Metadata:    fieldMeta,
-		}
-		
-		// Apply field processor for scoping decision
-		processed := ProcessField(field)
-		processedFields = append(processedFields, processed...)
+	metadata := catalog.ExtractAndCacheMetadata(data)
+	walker := &scopeWalker{
+		format:      format,
+		permissions: userPermissions,
+		model:       metadata.TypeName,
+		audit:       audit,
 	}
 	
-	// Convert processed fields back to struct format
-	return cs.fieldsToStruct(processedFields, metadata)
+	return walker.enforceWrites(reflect.ValueOf(data), metadata.Fields, metadata.TypeName, "", true)
 }
 
 // ValidateUnmarshalPermissions filters unmarshaled data using catalog metadata
 func (cs *CatalogScoper) ValidateUnmarshalPermissions(data any, userPermissions []string) error {
-	metadata := catalog.ExtractAndCacheMetadata(data)
-	
-	// Create fields for processing based on catalog metadata
-	for _, fieldMeta := range metadata.Fields {
-		// Check if user has permission for this field
-		if !cs.hasFieldPermission(fieldMeta.Scopes, userPermissions) {
-			// User doesn't have permission - emit event for audit trail
-			emitFieldScopeEvent(metadata.TypeName, fieldMeta.Name, fieldMeta.Type, userPermissions, false)
-		} else {
-			// User has permission - emit success event
-			emitFieldScopeEvent(metadata.TypeName, fieldMeta.Name, fieldMeta.Type, userPermissions, true)
-		}
-	}
-	
-	return nil
+	return cs.Enforce(data, "json", userPermissions)
 }
 
 // hasFieldPermission checks if user has permission for field scopes
@@ -110,48 +130,10 @@ func (cs *CatalogScoper) satisfiesScopeGroup(scopeGroup string, userPermissions
 	return true // User has all required permissions
 }
 
-// checkConventionScopes checks convention-based scoping (ScopeProvider interface)
-func (cs *CatalogScoper) checkConventionScopes(data any, userPermissions []string, metadata catalog.ModelMetadata) error {
-	// Check if type implements ScopeProvider convention
-	for _, function := range metadata.Functions {
-		if function.Convention == "ScopeProvider" {
-			// Type implements ScopeProvider - check if it's satisfied
-			if scopeProvider, ok := data.(interface{ GetRequiredScopes() []string }); ok {
-				requiredScopes := scopeProvider.GetRequiredScopes()
-				for _, required := range requiredScopes {
-					if !slices.Contains(userPermissions, required) {
-						return fmt.Errorf("insufficient scope: requires %s", required)
-					}
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// getRedactedFieldValue creates redacted value using field processors
-func (cs *CatalogScoper) getRedactedFieldValue(fieldMeta catalog.FieldMetadata, originalValue any) any {
-	// Create field for processing
-	field := Field{
-		Key:      fieldMeta.Name,
-		Type:     cs.mapCatalogFieldType(fieldMeta),
-		Value:    originalValue,
-		Metadata: fieldMeta,
-	}
-	
-	// Apply field processor for redaction
-	processedFields := ProcessField(field)
-	if len(processedFields) > 0 {
-		return processedFields[0].Value
-	}
-	
-	// No processor - return default redaction
-	return cs.getDefaultRedaction(fieldMeta)
-}
-
 // mapCatalogFieldType maps catalog field metadata to FieldType, considering security annotations
 func (cs *CatalogScoper) mapCatalogFieldType(fieldMeta catalog.FieldMetadata) FieldType {
-	// Check for security-specific field types first
+	// Security types come from the encrypt tag; validation rules only describe
+	// format and must not hide values from callers whose scopes allow them
 	if fieldMeta.Encryption.Type != "" {
 		switch fieldMeta.Encryption.Type {
 		case "pii":
@@ -163,116 +145,47 @@ func (cs *CatalogScoper) mapCatalogFieldType(fieldMeta catalog.FieldMetadata) Fi
 		}
 	}
 	
-	// Check validation rules for security field types
-	for _, rule := range fieldMeta.Validation.CustomRules {
-		switch rule {
-		case "ssn", "phone", "address":
-			return PIIType
-		case "creditcard", "bank_account":
-			return FinancialType
-		case "password", "api_key", "secret":
-			return SecretType
-		}
-	}
-	
-	// Fall back to basic type mapping
-	switch {
-	case strings.Contains(fieldMeta.Type, "string"):
+	// Fall back to the field's kind; containers are checked before their element types
+	switch fieldMeta.Kind {
+	case "string":
 		return StringType
-	case strings.Contains(fieldMeta.Type, "int"):
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
 		return IntType
-	case strings.Contains(fieldMeta.Type, "float"):
+	case "float32", "float64":
 		return FloatType
-	case strings.Contains(fieldMeta.Type, "bool"):
+	case "bool":
 		return BoolType
-	case strings.Contains(fieldMeta.Type, "[]"):
+	case "slice", "array":
 		return SliceType
-	case strings.Contains(fieldMeta.Type, "map"):
+	case "map":
 		return MapType
 	default:
 		return StructType
 	}
 }
 
-// fieldsToStruct converts processed fields back to map for serialization
-func (cs *CatalogScoper) fieldsToStruct(fields []Field, metadata catalog.ModelMetadata) map[string]any {
-	result := make(map[string]any)
-	
-	for _, field := range fields {
-		// Find the corresponding field metadata for JSON name
-		jsonName := field.Key
-		for _, fieldMeta := range metadata.Fields {
-			if fieldMeta.Name == field.Key {
-				if fieldMeta.JSONName != "" {
-					jsonName = fieldMeta.JSONName
-				}
-				break
-			}
-		}
-		
-		result[jsonName] = field.Value
-	}
-	
-	return result
-}
-
-// getDefaultRedaction provides fallback redaction values
-func (cs *CatalogScoper) getDefaultRedaction(fieldMeta catalog.FieldMetadata) any {
-	// Use catalog redaction info if available
-	if fieldMeta.Redaction.Value != "" {
-		return fieldMeta.Redaction.Value
-	}
-	
-	// Check validation constraints for appropriate redaction
-	if fieldMeta.Validation.Required {
-		// Need validation-satisfying redaction
-		for _, rule := range fieldMeta.Validation.CustomRules {
-			switch rule {
-			case "email":
-				return "redacted@example.com"
-			case "uuid":
-				return "00000000-0000-0000-0000-000000000000"
-			case "phone":
-				return "XXX-XXX-XXXX"
-			case "ssn":
-				return "XXX-XX-XXXX"
-			case "creditcard":
-				return "0000-0000-0000-0000"
-			}
-		}
-	}
-	
-	// Default redaction based on type
-	switch cs.mapCatalogFieldType(fieldMeta) {
-	case StringType:
-		return "[REDACTED]"
-	case IntType:
-		return 0
-	case FloatType:
-		return 0.0
-	case BoolType:
-		return false
-	default:
-		return nil
-	}
-}
-
-
 // Global catalog scoper instance
 var catalogScoper = NewCatalogScoper()
 
 // Public API functions that use catalog instead of reflection
 
-// FilterByPermissions applies permission-based filtering using catalog metadata
+// FilterByPermissions applies permission-based filtering using catalog metadata,
+// returning the scoped value as a map keyed by JSON field names
 func FilterByPermissions(data any, permissions []string) (map[string]any, error) {
-	result := catalogScoper.FilterForMarshal(data, permissions)
-	if result == nil {
-		return nil, fmt.Errorf("permission denied or invalid data")
+	scoped, err := catalogScoper.Scope(data, "json", permissions)
+	if err != nil {
+		return nil, err
+	}
+	
+	encoded, err := json.Marshal(scoped)
+	if err != nil {
+		return nil, err
 	}
-	if resultMap, ok := result.(map[string]any); ok {
-		return resultMap, nil
+	var result map[string]any
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, fmt.Errorf("scoped value is not an object: %w", err)
 	}
-	return nil, fmt.Errorf("unexpected result type from filter")
+	return result, nil
 }
 
 // ValidatePermissions validates that input data doesn't violate permission constraints
@@ -0,0 +1,38 @@
+package cereal
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborEncMode writes times as RFC 3339 strings so they round-trip without
+// losing precision or zone, and sorts map keys for deterministic output
+var cborEncMode, _ = cbor.EncOptions{
+	Sort: cbor.SortCanonical,
+	Time: cbor.TimeRFC3339Nano,
+}.EncMode()
+
+// cborDecMode decodes untyped maps with string keys, like the text formats
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]any(nil)),
+}.DecMode()
+
+// cborFormat encodes CBOR (RFC 8949). Fields are keyed by their cbor tag,
+// falling back to the json tag.
+var cborFormat = &funcFormat{
+	name:   "cbor",
+	encode: cborEncMode.Marshal,
+	decode: cborDecMode.Unmarshal,
+}
+
+// Register CBOR so it can be selected by extension or content type
+func init() {
+	mustRegisterFormat(FormatInfo{
+		Name:         "cbor",
+		ContentTypes: []string{"application/cbor"},
+		Extensions:   []string{".cbor"},
+		Binary:       true,
+		Serializer:   CBOR,
+	})
+}
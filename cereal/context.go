@@ -0,0 +1,60 @@
+package cereal
+
+import "context"
+
+// contextKey namespaces the request values cereal reads from a context
+type contextKey int
+
+const (
+	identityKey contextKey = iota
+	tenantKey
+	localeKey
+	purposeKey
+)
+
+// WithIdentity returns a context carrying the caller's identity, for
+// validators that depend on who is making the request
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// IdentityFrom returns the identity stored by WithIdentity, or ""
+func IdentityFrom(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey).(string)
+	return identity
+}
+
+// WithTenant returns a context carrying the tenant a request belongs to
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFrom returns the tenant stored by WithTenant, or ""
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey).(string)
+	return tenant
+}
+
+// WithLocale returns a context carrying the locale messages are written in,
+// e.g. "fr" or "pt-BR"
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFrom returns the locale stored by WithLocale, or ""
+func LocaleFrom(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey).(string)
+	return locale
+}
+
+// WithPurpose returns a context carrying why data is being accessed, e.g.
+// "support-ticket" or "billing-export", which access audits record
+func WithPurpose(ctx context.Context, purpose string) context.Context {
+	return context.WithValue(ctx, purposeKey, purpose)
+}
+
+// PurposeFrom returns the purpose stored by WithPurpose, or ""
+func PurposeFrom(ctx context.Context) string {
+	purpose, _ := ctx.Value(purposeKey).(string)
+	return purpose
+}
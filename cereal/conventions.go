@@ -1,5 +1,7 @@
 package cereal
 
+import "zbz/catalog"
+
 // ScopeProvider is the ONLY convention cereal cares about
 // Other services handle their own conventions (ValidationProvider, AuditProvider, etc.)
 // This keeps cereal focused on serialization + scoping
@@ -7,6 +9,11 @@ type ScopeProvider interface {
 	GetRequiredScopes() []string
 }
 
+// Register cereal's convention so catalog reports it for every model
+func init() {
+	catalog.RegisterConvention[ScopeProvider]("ScopeProvider", "cereal")
+}
+
 // checkScopeProvider checks if model implements ScopeProvider and validates permissions
 func checkScopeProvider(model interface{}, permissions []string) bool {
 	if scopeProvider, ok := model.(ScopeProvider); ok {
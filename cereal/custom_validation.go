@@ -57,8 +57,7 @@ func RegisterValidator(tag string, fn CustomValidationFunc, redactionValue strin
 	
 	// Register with go-playground/validator
 	if dv, ok := globalValidator.(*DefaultValidator); ok {
-		return dv.validate.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
-			ctx := context.Background()
+		return dv.validate.RegisterValidationCtx(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
 			err := fn(ctx, fl.Field(), fl.Param())
 			
 			// Simple event emission
@@ -85,6 +84,47 @@ func RegisterValidator(tag string, fn CustomValidationFunc, redactionValue strin
 	return nil
 }
 
+// StructValidationFunc checks rules that span several fields of T. ctx is the
+// one given to ValidateContext. Failures are reported by Go field name with a
+// tag, which also selects the message, and an optional param.
+type StructValidationFunc[T any] func(ctx context.Context, value T, report func(field, tag, param string))
+
+// RegisterStructValidator registers a validator that runs whenever a T is
+// validated, whether on its own or nested in another value
+//
+//	cereal.RegisterStructValidator(func(ctx context.Context, o Order, report func(field, tag, param string)) {
+//	    if o.Discount > 0 && cereal.TenantFrom(ctx) == "" {
+//	        report("Discount", "tenant_discount", "")
+//	    }
+//	})
+func RegisterStructValidator[T any](fn StructValidationFunc[T]) error {
+	if fn == nil {
+		return fmt.Errorf("validation function cannot be nil")
+	}
+	var zero T
+	if t := reflect.TypeOf(zero); t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("struct validators are registered for struct types, got %T", zero)
+	}
+	
+	if dv, ok := globalValidator.(*DefaultValidator); ok {
+		dv.validate.RegisterStructValidationCtx(func(ctx context.Context, sl validator.StructLevel) {
+			value, ok := sl.Current().Interface().(T)
+			if !ok {
+				return
+			}
+			fn(ctx, value, func(field, tag, param string) {
+				var fieldValue any
+				if f := sl.Current().FieldByName(field); f.IsValid() && f.CanInterface() {
+					fieldValue = f.Interface()
+				}
+				sl.ReportError(fieldValue, field, field, tag, param)
+			})
+		}, zero)
+	}
+	
+	return nil
+}
+
 // OnEvent sets a simple callback for cereal events - adapters can bridge to other systems
 func OnEvent(handler CerealEventHandler) {
 	customValidationRegistry.eventHandler = handler
@@ -0,0 +1,363 @@
+package cereal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zbz/catalog"
+)
+
+// Change is one difference between two values of the same type
+type Change struct {
+	Path     string `json:"path"`               // JSON pointer to the changed field
+	Op       string `json:"op"`                 // "add", "remove" or "replace", as in JSON Patch
+	Old      any    `json:"old,omitempty"`      // Value before; nil when added
+	New      any    `json:"new,omitempty"`      // Value after; nil when removed
+	Redacted bool   `json:"redacted,omitempty"` // Old and New were redacted for the caller
+}
+
+// ChangeSet lists the changes between two values in field order
+type ChangeSet []Change
+
+// Paths returns the JSON pointers of the changed fields
+func (cs ChangeSet) Paths() []string {
+	paths := make([]string, len(cs))
+	for i, change := range cs {
+		paths[i] = change.Path
+	}
+	return paths
+}
+
+// JSONPatch encodes the change set as an RFC 6902 JSON Patch that turns the
+// old value into the new one. Redacted changes are left out, since their
+// values are not known to the caller.
+func (cs ChangeSet) JSONPatch() ([]byte, error) {
+	operations := []PatchOperation{}
+	for _, change := range cs {
+		if change.Redacted {
+			continue
+		}
+		operation := PatchOperation{Op: change.Op, Path: change.Path}
+		if change.Op != "remove" {
+			value, err := json.Marshal(change.New)
+			if err != nil {
+				return nil, fmt.Errorf("encode %s: %w", change.Path, err)
+			}
+			operation.Value = value
+		}
+		operations = append(operations, operation)
+	}
+	return json.Marshal(operations)
+}
+
+// Diff compares two values field by field and returns what changed. Paths
+// are JSON pointers and values are scoped: a field the caller may not read
+// is still reported as changed, with its old and new values redacted.
+//
+//	changes, err := cereal.Diff(old, updated, "user")
+//	patch, err := changes.JSONPatch()
+func Diff[T any](before, after T, permissions ...string) (ChangeSet, error) {
+	shownBefore, err := catalogScoper.Scope(before, "json", permissions)
+	if err != nil {
+		return nil, err
+	}
+	shownAfter, err := catalogScoper.Scope(after, "json", permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := catalog.ExtractAndCacheMetadata(before)
+	walker := &diffWalker{model: metadata.TypeName, permissions: permissions}
+	return walker.diff(reflect.ValueOf(before), reflect.ValueOf(after), reflect.ValueOf(shownBefore), reflect.ValueOf(shownAfter), metadata.Fields, "", false, true)
+}
+
+// diffWalker compares two values along the paths the merge engine walks:
+// structs field by field, pointers and interfaces through to their values,
+// string-keyed maps key by key, and lists of structs element by element;
+// anything else is compared whole. Field
+// values in the changes come from the shown (scoped) copies. Diff reports
+// fields the caller may not read as redacted changes; patches (op is set)
+// reject changes to fields the caller may not write.
+type diffWalker struct {
+	op          string
+	model       string
+	permissions []string
+}
+
+func (w *diffWalker) diff(before, after, shownBefore, shownAfter reflect.Value, fields []catalog.FieldMetadata, pointer string, omitEmpty, root bool) (ChangeSet, error) {
+	if !before.IsValid() || !after.IsValid() {
+		if before.IsValid() == after.IsValid() {
+			return nil, nil
+		}
+		return ChangeSet{w.leaf(before, after, shownBefore, shownAfter, pointer, omitEmpty)}, nil
+	}
+	// Equal values have no changes, but a root struct is still walked so its
+	// ScopeProvider requirements are checked
+	equal := equalValues(before, after)
+	if equal && (!root || derefKind(before.Type()) != reflect.Struct) {
+		return nil, nil
+	}
+	if before.Type() != after.Type() {
+		return ChangeSet{w.leaf(before, after, shownBefore, shownAfter, pointer, omitEmpty)}, nil
+	}
+
+	switch before.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if before.IsNil() || after.IsNil() || !needsWalk(before.Type()) {
+			break
+		}
+		if before.Kind() == reflect.Interface {
+			// The dynamic type is only known now, so its fields come from the catalog
+			fields = nil
+		}
+		return w.diff(before.Elem(), after.Elem(), shownElem(shownBefore), shownElem(shownAfter), fields, pointer, omitEmpty, root)
+
+	case reflect.Map:
+		if before.IsNil() || after.IsNil() || before.Type().Key().Kind() != reflect.String {
+			break
+		}
+		return w.diffMaps(before, after, shownBefore, shownAfter, fields, pointer)
+
+	case reflect.Slice, reflect.Array:
+		if (before.Kind() == reflect.Slice && (before.IsNil() || after.IsNil())) || !needsWalk(before.Type().Elem()) {
+			break
+		}
+		return w.diffLists(before, after, shownBefore, shownAfter, fields, pointer)
+
+	case reflect.Struct:
+		if hasExportedFields(before.Type()) {
+			return w.diffStructs(before, after, shownBefore, shownAfter, fields, pointer, root)
+		}
+	}
+
+	if equal {
+		return nil, nil
+	}
+	return ChangeSet{w.leaf(before, after, shownBefore, shownAfter, pointer, omitEmpty)}, nil
+}
+
+// diffStructs compares two structs field by field
+func (w *diffWalker) diffStructs(before, after, shownBefore, shownAfter reflect.Value, fields []catalog.FieldMetadata, pointer string, root bool) (ChangeSet, error) {
+	t := before.Type()
+	if required, ok := requiredScopes(before); ok && !hasAllScopes(w.permissions, required) {
+		if root || w.op != "" {
+			return nil, &ScopeError{Model: qualifiedName(t), Path: pointer, Required: required, Permissions: w.permissions}
+		}
+		// The whole value is hidden from the caller, so only the fact that it changed is shown
+		return ChangeSet{{Path: pointer, Op: "replace", Redacted: true}}, nil
+	}
+	if fields == nil {
+		fields = structFields(t)
+	}
+
+	changes := ChangeSet{}
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name, skip := formatFieldName(structField, "json")
+		if skip {
+			continue
+		}
+
+		if isPromoted(structField) {
+			if !structField.IsExported() {
+				continue
+			}
+			promoted, err := w.diff(before.Field(i), after.Field(i), shownField(shownBefore, i), shownField(shownAfter, i), promotedFields(fields, i), pointer, false, false)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, promoted...)
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+
+		fieldPointer := pointer + "/" + escapePointerToken(name)
+		omitEmpty := strings.Contains(formatTag(structField, "json"), ",omitempty")
+		meta, known := directField(fields, i)
+		if known && !equalValues(before.Field(i), after.Field(i)) {
+			granted := hasPermissionForField(meta.Scopes, w.permissions)
+			if w.op != "" {
+				decision := DecisionAccepted
+				if !granted {
+					decision = DecisionRejected
+				}
+				emitFieldScopeEvent(context.Background(), w.model, FieldAccess{Path: fieldPointer, Type: meta.Type, Classification: meta.Encryption.Type, Decision: decision}, w.permissions)
+				if !granted {
+					return nil, &PatchError{Op: w.op, Path: fieldPointer, Err: ErrFieldNotWritable}
+				}
+			} else if !granted {
+				change := w.leaf(before.Field(i), after.Field(i), shownField(shownBefore, i), shownField(shownAfter, i), fieldPointer, omitEmpty)
+				change.Redacted = true
+				changes = append(changes, change)
+				continue
+			}
+		}
+
+		nested, err := w.diff(before.Field(i), after.Field(i), shownField(shownBefore, i), shownField(shownAfter, i), meta.Fields, fieldPointer, omitEmpty, false)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, nested...)
+	}
+	return changes, nil
+}
+
+// diffMaps compares two string-keyed maps key by key, in key order
+func (w *diffWalker) diffMaps(before, after, shownBefore, shownAfter reflect.Value, fields []catalog.FieldMetadata, pointer string) (ChangeSet, error) {
+	keys := map[string]reflect.Value{}
+	for _, key := range before.MapKeys() {
+		keys[key.String()] = key
+	}
+	for _, key := range after.MapKeys() {
+		keys[key.String()] = key
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := ChangeSet{}
+	for _, name := range names {
+		key := keys[name]
+		nested, err := w.diff(before.MapIndex(key), after.MapIndex(key), shownIndex(shownBefore, key), shownIndex(shownAfter, key), fields, pointer+"/"+escapePointerToken(name), false, false)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, nested...)
+	}
+	return changes, nil
+}
+
+// diffLists compares two lists index by index, so element fields keep their
+// scopes. Elements past the end of the shorter list are added or removed as a
+// whole; removals are listed last to first so the changes apply in order as
+// a JSON Patch. A patch may only add or remove an element whose fields it may
+// write.
+func (w *diffWalker) diffLists(before, after, shownBefore, shownAfter reflect.Value, fields []catalog.FieldMetadata, pointer string) (ChangeSet, error) {
+	common := min(before.Len(), after.Len())
+	changes := ChangeSet{}
+	for i := 0; i < common; i++ {
+		nested, err := w.diff(before.Index(i), after.Index(i), shownAt(shownBefore, i), shownAt(shownAfter, i), fields, pointer+"/"+strconv.Itoa(i), false, false)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, nested...)
+	}
+
+	zero := reflect.Zero(before.Type().Elem())
+	for i := common; i < after.Len(); i++ {
+		elemPointer := pointer + "/" + strconv.Itoa(i)
+		if err := w.checkWrites(zero, after.Index(i), fields, elemPointer); err != nil {
+			return nil, err
+		}
+		changes = append(changes, w.leaf(reflect.Value{}, after.Index(i), reflect.Value{}, shownAt(shownAfter, i), elemPointer, false))
+	}
+	for i := before.Len() - 1; i >= common; i-- {
+		elemPointer := pointer + "/" + strconv.Itoa(i)
+		if err := w.checkWrites(before.Index(i), zero, fields, elemPointer); err != nil {
+			return nil, err
+		}
+		changes = append(changes, w.leaf(before.Index(i), reflect.Value{}, shownAt(shownBefore, i), reflect.Value{}, elemPointer, false))
+	}
+	return changes, nil
+}
+
+// checkWrites rejects a patch that sets or clears fields it may not write
+// when it adds or removes a whole element
+func (w *diffWalker) checkWrites(before, after reflect.Value, fields []catalog.FieldMetadata, pointer string) error {
+	if w.op == "" {
+		return nil
+	}
+	_, err := w.diff(before, after, reflect.Value{}, reflect.Value{}, fields, pointer, false, false)
+	return err
+}
+
+// leaf reports a whole value as changed. A value is added or removed when it
+// is missing from the JSON form on one side: an absent map key, or an empty
+// omitempty field. The change is redacted when either shown value differs
+// from the real one, e.g. PII masked by the security processors.
+func (w *diffWalker) leaf(before, after, shownBefore, shownAfter reflect.Value, pointer string, omitEmpty bool) Change {
+	change := Change{Path: pointer, Op: "replace", Old: interfaceOf(shownBefore), New: interfaceOf(shownAfter)}
+	change.Redacted = redacted(before, shownBefore) || redacted(after, shownAfter)
+	switch {
+	case !present(before, omitEmpty):
+		change.Op, change.Old = "add", nil
+	case !present(after, omitEmpty):
+		change.Op, change.New = "remove", nil
+	}
+	return change
+}
+
+// equalValues reports whether two values are the same as JSON sees them
+func equalValues(a, b reflect.Value) bool {
+	return reflect.DeepEqual(a.Interface(), b.Interface()) || sameJSON(a, b)
+}
+
+// redacted reports whether scoping replaced a value in its shown copy
+func redacted(value, shown reflect.Value) bool {
+	return value.IsValid() && shown.IsValid() && !equalValues(value, shown)
+}
+
+// present reports whether a value appears in its JSON form
+func present(v reflect.Value, omitEmpty bool) bool {
+	if !v.IsValid() {
+		return false
+	}
+	if !omitEmpty {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() > 0
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil()
+	case reflect.Struct:
+		return true
+	}
+	return !v.IsZero()
+}
+
+// shownElem, shownField and shownIndex step into a shown copy, tolerating
+// values the scoper zeroed
+func shownElem(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+func shownField(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return v.Field(i)
+}
+
+func shownAt(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || i >= v.Len() {
+		return reflect.Value{}
+	}
+	return v.Index(i)
+}
+
+func shownIndex(v reflect.Value, key reflect.Value) reflect.Value {
+	if !v.IsValid() || v.Kind() != reflect.Map || v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.MapIndex(key)
+}
+
+func interfaceOf(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,190 @@
+package cereal
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type diffProfile struct {
+	Name     string            `json:"name"`
+	SSN      string            `json:"ssn" encrypt:"pii"`
+	Salary   int               `json:"salary" scope:"finance"`
+	Nickname string            `json:"nickname,omitempty"`
+	Address  *patchAddress     `json:"address"`
+	Labels   map[string]string `json:"labels"`
+	Tags     []string          `json:"tags"`
+	Updated  time.Time         `json:"updated"`
+}
+
+func newDiffProfile() diffProfile {
+	return diffProfile{
+		Name:    "Ada",
+		SSN:     "123-45-6789",
+		Salary:  100,
+		Address: &patchAddress{City: "London", Zip: "N1"},
+		Labels:  map[string]string{"team": "core", "site": "ldn"},
+		Tags:    []string{"a"},
+		Updated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := newDiffProfile()
+	after := newDiffProfile()
+	after.Name = "Grace"
+	after.Nickname = "G"
+	after.Address = &patchAddress{City: "Paris", Zip: "N1"}
+	after.Labels = map[string]string{"team": "core", "role": "lead"}
+	after.Tags = append(after.Tags, "b")
+	after.Updated = before.Updated.In(time.FixedZone("CET", 3600)).In(time.UTC)
+
+	changes, err := Diff(before, after, "finance", "pii")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := ChangeSet{
+		{Path: "/name", Op: "replace", Old: "Ada", New: "Grace"},
+		{Path: "/nickname", Op: "add", New: "G"},
+		{Path: "/address/city", Op: "replace", Old: "London", New: "Paris"},
+		{Path: "/labels/role", Op: "add", New: "lead"},
+		{Path: "/labels/site", Op: "remove", Old: "ldn"},
+		{Path: "/tags", Op: "replace", Old: []string{"a"}, New: []string{"a", "b"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes:\n%+v\ngot:\n%+v", expected, changes)
+	}
+
+	if same, _ := Diff(before, newDiffProfile()); len(same) != 0 {
+		t.Errorf("Expected no changes for equal values, got %+v", same)
+	}
+}
+
+func TestDiff_Redacted(t *testing.T) {
+	before := newDiffProfile()
+	after := newDiffProfile()
+	after.SSN = "987-65-4321"
+	after.Salary = 200
+
+	changes, err := Diff(before, after, "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+
+	for _, change := range changes {
+		if !change.Redacted {
+			t.Errorf("Expected %s to be redacted, got %+v", change.Path, change)
+		}
+		if change.Old == before.SSN || change.New == after.SSN || change.Old == 100 || change.New == 200 {
+			t.Errorf("Expected %s values to be hidden, got %+v", change.Path, change)
+		}
+	}
+
+	patch, err := changes.JSONPatch()
+	if err != nil || string(patch) != "[]" {
+		t.Errorf("Expected redacted changes to be left out of the patch, got %s (%v)", patch, err)
+	}
+}
+
+func TestChangeSet_JSONPatch(t *testing.T) {
+	before := newDiffProfile()
+	after := newDiffProfile()
+	after.Name = "Grace"
+	after.Address.Zip = ""
+	after.Labels = map[string]string{"team": "core"}
+	after.Nickname = "G"
+
+	changes, err := Diff(before, after, "finance", "pii")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	patch, err := changes.JSONPatch()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var operations []PatchOperation
+	json.Unmarshal(patch, &operations)
+	if len(operations) != 4 || operations[3].Op != "remove" || operations[3].Value != nil {
+		t.Errorf("Expected four operations ending in a remove, got %s", patch)
+	}
+
+	// Applying the encoded patch to the old value yields the new one
+	patched, _, err := ApplyJSONPatch(before, patch, "finance", "pii")
+	if err != nil {
+		t.Fatalf("Expected patch to apply, got: %v", err)
+	}
+	if !reflect.DeepEqual(patched, after) {
+		t.Errorf("Expected %+v, got %+v", after, patched)
+	}
+}
+
+type diffItem struct {
+	Name  string `json:"name"`
+	Price int    `json:"price" scope:"admin"`
+}
+
+type diffOrder struct {
+	Items []diffItem `json:"items"`
+}
+
+func TestDiff_ListElements(t *testing.T) {
+	before := diffOrder{Items: []diffItem{{"pen", 10}, {"ink", 20}, {"pad", 30}}}
+	after := diffOrder{Items: []diffItem{{"quill", 10}}}
+
+	changes, err := Diff(before, after, "admin")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	paths := []string{"/items/0/name", "/items/2", "/items/1"}
+	if !reflect.DeepEqual(changes.Paths(), paths) {
+		t.Errorf("Expected %v, got %v", paths, changes.Paths())
+	}
+
+	patch, err := changes.JSONPatch()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	patched, _, err := ApplyJSONPatch(before, patch, "admin")
+	if err != nil || !reflect.DeepEqual(patched, after) {
+		t.Errorf("Expected the change set to apply as a patch, got %+v (%v)", patched, err)
+	}
+}
+
+func TestPatch_ListElementScopes(t *testing.T) {
+	order := diffOrder{Items: []diffItem{{"pen", 10}}}
+
+	rejected := []func() error{
+		func() error {
+			_, _, err := ApplyJSONPatch(order, []byte(`[{"op":"replace","path":"/items/0/price","value":1}]`), "user")
+			return err
+		},
+		func() error {
+			_, _, err := ApplyJSONPatch(order, []byte(`[{"op":"add","path":"/items/-","value":{"name":"ink","price":5}}]`), "user")
+			return err
+		},
+		func() error {
+			_, _, err := ApplyMergePatch(order, []byte(`{"items":[{"name":"pen","price":1}]}`), "user")
+			return err
+		},
+	}
+	for i, apply := range rejected {
+		if err := apply(); !errors.Is(err, ErrFieldNotWritable) {
+			t.Errorf("Expected patch %d to be rejected as not writable, got %v", i, err)
+		}
+	}
+
+	patched, changed, err := ApplyJSONPatch(order, []byte(`[{"op":"replace","path":"/items/0/name","value":"quill"}]`), "user")
+	if err != nil {
+		t.Fatalf("Expected visible element field to be patched, got: %v", err)
+	}
+	if patched.Items[0] != (diffItem{"quill", 10}) || !reflect.DeepEqual(changed, []string{"/items/0/name"}) {
+		t.Errorf("Expected only the name to change, got %+v %v", patched.Items, changed)
+	}
+}
@@ -0,0 +1,555 @@
+package cereal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"zbz/catalog"
+)
+
+// AlgorithmAES256GCM is the default envelope algorithm: a random 256-bit
+// data key encrypts the value with AES-GCM and is itself wrapped with AES-GCM
+// under the master key
+const AlgorithmAES256GCM = catalog.AlgorithmRandomized
+
+// AlgorithmAESSIV is deterministic: the value is encrypted with AES-SIV under
+// a key derived from the master key, so equal values give equal ciphertext
+const AlgorithmAESSIV = catalog.AlgorithmDeterministic
+
+// Ciphertext layout: zbz:v<version>:<key ID>:<algorithm>:<wrapped data key>:<data>
+const (
+	envelopeMagic     = "zbz"
+	envelopeSeparator = ":"
+	envelopeVersion   = 1
+)
+
+// envelopeEncoding keeps ciphertext free of the separator and padding
+var envelopeEncoding = base64.RawURLEncoding
+
+// Ciphertext is a parsed self-describing encrypted value. The header
+// (version, key ID and algorithm) is authenticated along with the data.
+type Ciphertext struct {
+	Version    int
+	KeyID      string // Master key that wraps the data key
+	Algorithm  string
+	WrappedKey []byte // Data key encrypted under the master key, nonce first
+	Data       []byte // Value encrypted under the data key, nonce first
+}
+
+// String encodes the ciphertext in its stored form
+func (c Ciphertext) String() string {
+	return strings.Join([]string{
+		c.header(),
+		envelopeEncoding.EncodeToString(c.WrappedKey),
+		envelopeEncoding.EncodeToString(c.Data),
+	}, envelopeSeparator)
+}
+
+// header is the authenticated prefix of the stored form
+func (c Ciphertext) header() string {
+	return strings.Join([]string{
+		envelopeMagic, "v" + strconv.Itoa(c.Version), c.KeyID, c.Algorithm,
+	}, envelopeSeparator)
+}
+
+// ParseCiphertext decodes a stored value written by the envelope encryption
+func ParseCiphertext(s string) (Ciphertext, error) {
+	parts := strings.Split(s, envelopeSeparator)
+	if len(parts) != 6 || parts[0] != envelopeMagic || !strings.HasPrefix(parts[1], "v") {
+		return Ciphertext{}, fmt.Errorf("not a cereal ciphertext")
+	}
+
+	version, err := strconv.Atoi(parts[1][1:])
+	if err != nil {
+		return Ciphertext{}, fmt.Errorf("invalid ciphertext version %q", parts[1])
+	}
+	if version != envelopeVersion {
+		return Ciphertext{}, fmt.Errorf("unsupported ciphertext version %d", version)
+	}
+
+	wrapped, err := envelopeEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Ciphertext{}, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	data, err := envelopeEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Ciphertext{}, fmt.Errorf("invalid ciphertext data: %w", err)
+	}
+
+	return Ciphertext{
+		Version:    version,
+		KeyID:      parts[2],
+		Algorithm:  parts[3],
+		WrappedKey: wrapped,
+		Data:       data,
+	}, nil
+}
+
+// IsCiphertext reports whether s is in the self-describing ciphertext format
+func IsCiphertext(s string) bool {
+	return strings.HasPrefix(s, envelopeMagic+envelopeSeparator+"v") && strings.Count(s, envelopeSeparator) == 5
+}
+
+// sealEnvelope encrypts plaintext with the current master key. AES-256-GCM
+// uses a fresh data key wrapped by the master key; AES-SIV has no data key.
+func sealEnvelope(plaintext []byte, keys KeyProvider, algorithm string) (Ciphertext, error) {
+	keyID, masterKey, err := keys.CurrentKey()
+	if err != nil {
+		return Ciphertext{}, err
+	}
+
+	if algorithm == AlgorithmAESSIV {
+		c := Ciphertext{Version: envelopeVersion, KeyID: keyID, Algorithm: AlgorithmAESSIV}
+		c.Data, err = sivSeal(deriveSubkey(masterKey, "aes-siv", sha512.New), plaintext, []byte(c.header()))
+		return c, err
+	}
+	if algorithm != AlgorithmAES256GCM {
+		return Ciphertext{}, fmt.Errorf("unsupported encryption algorithm %s", algorithm)
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return Ciphertext{}, err
+	}
+
+	c := Ciphertext{Version: envelopeVersion, KeyID: keyID, Algorithm: AlgorithmAES256GCM}
+	aad := []byte(c.header())
+
+	if c.WrappedKey, err = sealGCM(deriveMasterKey(masterKey), dataKey, aad); err != nil {
+		return Ciphertext{}, err
+	}
+	if c.Data, err = sealGCM(dataKey, plaintext, aad); err != nil {
+		return Ciphertext{}, err
+	}
+	return c, nil
+}
+
+// openEnvelope unwraps the data key with the master key named in the header and decrypts the value
+func openEnvelope(c Ciphertext, keys KeyProvider) ([]byte, error) {
+	if c.Algorithm != AlgorithmAES256GCM && c.Algorithm != AlgorithmAESSIV {
+		return nil, fmt.Errorf("unsupported ciphertext algorithm %s", c.Algorithm)
+	}
+
+	masterKey, err := keys.Key(c.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	aad := []byte(c.header())
+	if c.Algorithm == AlgorithmAESSIV {
+		return sivOpen(deriveSubkey(masterKey, "aes-siv", sha512.New), c.Data, aad)
+	}
+
+	dataKey, err := openGCM(deriveMasterKey(masterKey), c.WrappedKey, aad)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key %s: %w", c.KeyID, err)
+	}
+	return openGCM(dataKey, c.Data, aad)
+}
+
+// deriveMasterKey stretches a master key of any length to an AES-256 key,
+// matching the derivation used for legacy ciphertext
+func deriveMasterKey(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:]
+}
+
+// deriveSubkey derives a purpose-bound key from a master key with HMAC, so
+// the deterministic and blind-index keys never equal the wrapping key
+func deriveSubkey(masterKey []byte, purpose string, h func() hash.Hash) []byte {
+	mac := hmac.New(h, deriveMasterKey(masterKey))
+	mac.Write([]byte("zbz/" + purpose))
+	return mac.Sum(nil)
+}
+
+// sealGCM encrypts with AES-GCM and prepends the nonce
+func sealGCM(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// openGCM decrypts a nonce-prefixed AES-GCM message
+func openGCM(key, ciphertext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyChain tries each provider in order, so a context key can override the
+// service's keyring without hiding the keyring's older keys
+type keyChain []KeyProvider
+
+// CurrentKey implements KeyProvider with the first provider that has one,
+// reporting the first provider's error when none does
+func (c keyChain) CurrentKey() (string, []byte, error) {
+	var firstErr error
+	for _, provider := range c {
+		id, key, err := provider.CurrentKey()
+		if err == nil {
+			return id, key, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no organization master key provided")
+	}
+	return "", nil, firstErr
+}
+
+// Key implements KeyProvider with the first provider that holds id
+func (c keyChain) Key(id string) ([]byte, error) {
+	for _, provider := range c {
+		key, err := provider.Key(id)
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+}
+
+// decryptOnly exposes a provider's keys for decryption but never offers
+// one as the current key
+type decryptOnly struct {
+	KeyProvider
+}
+
+// CurrentKey implements KeyProvider; a decrypt-only key never seals values
+func (d decryptOnly) CurrentKey() (string, []byte, error) {
+	return "", nil, fmt.Errorf("legacy master key is decrypt-only")
+}
+
+// Reencrypt migrates one stored "owner" value to the current master key.
+// Values already under the current key are returned unchanged with
+// changed=false; legacy base64 ciphertext is upgraded to the envelope format.
+func (e *EncryptionService) Reencrypt(stored string, ctx SecurityContext) (string, bool, error) {
+	keys := e.keyProvider(ctx)
+	currentID, _, err := keys.CurrentKey()
+	if err != nil {
+		return "", false, err
+	}
+
+	var plaintext []byte
+	algorithm := AlgorithmAES256GCM
+	if IsCiphertext(stored) {
+		parsed, err := ParseCiphertext(stored)
+		if err != nil {
+			return "", false, err
+		}
+		algorithm = parsed.Algorithm
+		if parsed.KeyID == currentID {
+			return stored, false, nil
+		}
+		if plaintext, err = openEnvelope(parsed, keys); err != nil {
+			return "", false, err
+		}
+	} else {
+		legacy, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil {
+			return "", false, fmt.Errorf("value is neither envelope nor legacy ciphertext")
+		}
+		if plaintext, err = e.DecryptOwner(legacy, ctx); err != nil {
+			return "", false, err
+		}
+	}
+
+	// The algorithm is kept, so deterministic values stay searchable
+	sealed, err := sealEnvelope(plaintext, keys, algorithm)
+	if err != nil {
+		return "", false, err
+	}
+	return sealed.String(), true, nil
+}
+
+// ReencryptFields migrates the "owner"-encrypted fields of the struct v
+// points to onto the current master key, in place, at any depth: nested
+// structs, embeds, pointers, slices, arrays and maps are walked the way
+// encryption walks them. Fields pinned with key_rotation:"false" keep their
+// key. It returns the number of fields that were rewritten.
+//
+// Only string fields can hold ciphertext in a Go value; use ReencryptEncoded
+// to migrate stored documents with encrypted fields of other types.
+func (e *EncryptionService) ReencryptFields(v any, ctx SecurityContext) (int, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return 0, fmt.Errorf("ReencryptFields requires a non-nil struct pointer, got %T", v)
+	}
+	return e.reencryptValue(value.Elem(), ctx)
+}
+
+// reencryptValue rotates the ciphertext strings held anywhere in value
+func (e *EncryptionService) reencryptValue(value reflect.Value, ctx SecurityContext) (int, error) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return 0, nil
+		}
+		return e.reencryptValue(value.Elem(), ctx)
+
+	case reflect.Interface:
+		if value.IsNil() || !value.CanSet() {
+			return 0, nil
+		}
+		// Interface contents are not addressable: rotate a copy and store it back
+		inner := reflect.New(value.Elem().Type()).Elem()
+		inner.Set(value.Elem())
+		migrated, err := e.reencryptValue(inner, ctx)
+		if migrated > 0 {
+			value.Set(inner)
+		}
+		return migrated, err
+
+	case reflect.Slice, reflect.Array:
+		if !hasEncryptedFields(value.Type().Elem()) {
+			return 0, nil
+		}
+		migrated := 0
+		for i := 0; i < value.Len(); i++ {
+			n, err := e.reencryptValue(value.Index(i), ctx)
+			migrated += n
+			if err != nil {
+				return migrated, err
+			}
+		}
+		return migrated, nil
+
+	case reflect.Map:
+		if !hasEncryptedFields(value.Type().Elem()) {
+			return 0, nil
+		}
+		migrated := 0
+		iter := value.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			n, err := e.reencryptValue(elem, ctx)
+			if n > 0 {
+				value.SetMapIndex(iter.Key(), elem)
+			}
+			migrated += n
+			if err != nil {
+				return migrated, err
+			}
+		}
+		return migrated, nil
+
+	case reflect.Struct:
+		t := value.Type()
+		fields := structFields(t)
+		migrated := 0
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+
+			fieldValue := value.Field(i)
+			if !isEncryptMode(field.Tag.Get("encrypt")) {
+				if field.Anonymous || hasEncryptedFields(field.Type) {
+					n, err := e.reencryptValue(fieldValue, ctx)
+					migrated += n
+					if err != nil {
+						return migrated, err
+					}
+				}
+				continue
+			}
+
+			meta, _ := directField(fields, i)
+			if meta.Encryption.Type != "owner" || !meta.Encryption.KeyRotation {
+				continue
+			}
+			changed, err := e.reencryptString(fieldValue, ctx)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to re-encrypt field %s: %w", field.Name, err)
+			}
+			if changed {
+				migrated++
+			}
+		}
+		return migrated, nil
+	}
+	return 0, nil
+}
+
+// reencryptString rotates a stored ciphertext held in a string field, or in
+// an interface field holding a string
+func (e *EncryptionService) reencryptString(value reflect.Value, ctx SecurityContext) (bool, error) {
+	if !value.CanSet() {
+		return false, nil
+	}
+	stored := value
+	if value.Kind() == reflect.Interface && !value.IsNil() {
+		stored = value.Elem()
+	}
+	if stored.Kind() != reflect.String || stored.String() == "" {
+		return false, nil
+	}
+
+	rewritten, changed, err := e.Reencrypt(stored.String(), ctx)
+	if err != nil || !changed {
+		return false, err
+	}
+	if value.Kind() == reflect.Interface {
+		value.Set(reflect.ValueOf(rewritten).Convert(stored.Type()))
+	} else {
+		value.SetString(rewritten)
+	}
+	return true, nil
+}
+
+// ReencryptEncoded migrates the "owner"-encrypted fields of a stored
+// document onto the current master key. model gives the document's Go type
+// (a value or pointer); unlike ReencryptFields this also rotates encrypted
+// fields that are not strings, whose ciphertext only exists in the encoded
+// form. It returns the re-encoded document and the number of fields that
+// were rewritten; data is returned unchanged when nothing was.
+func (e *EncryptionService) ReencryptEncoded(data []byte, model any, format Format, ctx SecurityContext) ([]byte, int, error) {
+	t := reflect.TypeOf(model)
+	if t == nil || !hasEncryptedFields(t) {
+		return data, 0, nil
+	}
+
+	tree, err := decodeTree(format, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	migrated, err := e.reencryptTree(t, tree, format.Name(), ctx)
+	if err != nil || migrated == 0 {
+		return data, migrated, err
+	}
+
+	encoded, err := format.Encode(tree)
+	if err != nil {
+		return nil, 0, err
+	}
+	return encoded, migrated, nil
+}
+
+// reencryptTree rotates the ciphertext in a decoded document laid out as t
+func (e *EncryptionService) reencryptTree(t reflect.Type, tree any, format string, ctx SecurityContext) (int, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return e.reencryptTree(t.Elem(), tree, format, ctx)
+
+	case reflect.Slice, reflect.Array:
+		nodes, ok := tree.([]any)
+		if !ok || !hasEncryptedFields(t.Elem()) {
+			return 0, nil
+		}
+		migrated := 0
+		for _, node := range nodes {
+			n, err := e.reencryptTree(t.Elem(), node, format, ctx)
+			migrated += n
+			if err != nil {
+				return migrated, err
+			}
+		}
+		return migrated, nil
+
+	case reflect.Map:
+		nodes, ok := tree.(map[string]any)
+		if !ok || !hasEncryptedFields(t.Elem()) {
+			return 0, nil
+		}
+		migrated := 0
+		for _, node := range nodes {
+			n, err := e.reencryptTree(t.Elem(), node, format, ctx)
+			migrated += n
+			if err != nil {
+				return migrated, err
+			}
+		}
+		return migrated, nil
+
+	case reflect.Struct:
+		nodes, ok := tree.(map[string]any)
+		if !ok {
+			return 0, nil
+		}
+		fields := structFields(t)
+		migrated := 0
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			name, skip := formatFieldName(field, format)
+			if skip {
+				continue
+			}
+
+			var node any = nodes
+			if name != "" {
+				present := false
+				if node, present = nodes[name]; !present {
+					continue
+				}
+			}
+
+			if name == "" || !isEncryptMode(field.Tag.Get("encrypt")) {
+				if name == "" || hasEncryptedFields(field.Type) {
+					n, err := e.reencryptTree(field.Type, node, format, ctx)
+					migrated += n
+					if err != nil {
+						return migrated, err
+					}
+				}
+				continue
+			}
+
+			meta, _ := directField(fields, i)
+			stored, isString := node.(string)
+			if meta.Encryption.Type != "owner" || !meta.Encryption.KeyRotation || !isString || stored == "" {
+				continue
+			}
+			rewritten, changed, err := e.Reencrypt(stored, ctx)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to re-encrypt field %s: %w", field.Name, err)
+			}
+			if changed {
+				nodes[name] = rewritten
+				migrated++
+			}
+		}
+		return migrated, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,532 @@
+package cereal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"zbz/catalog"
+)
+
+// typedEnvelope is the plaintext sealed for an encrypted field. Type records
+// the value's type family so decryption can restore it, including into
+// interface fields, and reject ciphertext moved onto a field of another type.
+type typedEnvelope struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeFamily names the envelope type of t: string, bool, int, uint, float,
+// time, bytes, or json for structs, slices and maps
+func typeFamily(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "time"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+	}
+	return "json"
+}
+
+// sealTyped encodes a field value as a typed envelope
+func sealTyped(value reflect.Value) ([]byte, error) {
+	raw, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(typedEnvelope{Type: typeFamily(value.Type()), Value: raw})
+}
+
+// openTyped restores a typed envelope into dest, which must be addressable.
+// Plaintext sealed before typed envelopes is a bare string.
+func openTyped(plaintext []byte, dest reflect.Value) error {
+	var envelope typedEnvelope
+	if err := json.Unmarshal(plaintext, &envelope); err != nil || envelope.Type == "" {
+		if dest.Kind() == reflect.String {
+			dest.SetString(string(plaintext))
+			return nil
+		}
+		return fmt.Errorf("ciphertext does not hold a typed value")
+	}
+
+	if dest.Kind() == reflect.Interface {
+		restored, err := restoreTyped(envelope)
+		if err != nil {
+			return err
+		}
+		if restored == nil {
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		value := reflect.ValueOf(restored)
+		if !value.Type().AssignableTo(dest.Type()) {
+			return fmt.Errorf("ciphertext holds %s, which does not implement %s", envelope.Type, dest.Type())
+		}
+		dest.Set(value)
+		return nil
+	}
+
+	if family := typeFamily(dest.Type()); family != envelope.Type {
+		return fmt.Errorf("ciphertext holds %s, field is %s", envelope.Type, dest.Type())
+	}
+	return json.Unmarshal(envelope.Value, dest.Addr().Interface())
+}
+
+// restoreTyped decodes an envelope to the Go type its family was sealed from
+func restoreTyped(envelope typedEnvelope) (any, error) {
+	var target any
+	switch envelope.Type {
+	case "string":
+		target = new(string)
+	case "bool":
+		target = new(bool)
+	case "int":
+		target = new(int64)
+	case "uint":
+		target = new(uint64)
+	case "float":
+		target = new(float64)
+	case "time":
+		target = new(time.Time)
+	case "bytes":
+		target = new([]byte)
+	default:
+		var value any
+		err := json.Unmarshal(envelope.Value, &value)
+		return value, err
+	}
+
+	if err := json.Unmarshal(envelope.Value, target); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(target).Elem().Interface(), nil
+}
+
+// isEncryptMode reports whether an encrypt tag names a supported mode
+func isEncryptMode(mode string) bool {
+	return mode == "owner" || mode == "subscriber"
+}
+
+// Types are checked for encrypted fields once
+var encryptedTypes sync.Map // reflect.Type -> bool
+
+// hasEncryptedFields reports whether values of t can hold an encrypted field
+func hasEncryptedFields(t reflect.Type) bool {
+	if cached, exists := encryptedTypes.Load(t); exists {
+		return cached.(bool)
+	}
+	found := typeHasEncryption(t, make(map[reflect.Type]bool))
+	encryptedTypes.Store(t, found)
+	return found
+}
+
+func typeHasEncryption(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasEncryption(t.Elem(), visiting)
+	case reflect.Struct:
+		if visiting[t] {
+			return false
+		}
+		visiting[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			if isEncryptMode(field.Tag.Get("encrypt")) || typeHasEncryption(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyFieldEncryption replaces the encrypted fields of tree, the generic
+// decoding of value in format, with ciphertext. It descends into nested and
+// embedded structs, slice and array elements, and map values.
+func applyFieldEncryption(value reflect.Value, tree any, format string, ctx SecurityContext, encService *EncryptionService) error {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return applyFieldEncryption(value.Elem(), tree, format, ctx, encService)
+
+	case reflect.Slice, reflect.Array:
+		nodes, ok := tree.([]any)
+		if !ok || !hasEncryptedFields(value.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < value.Len() && i < len(nodes); i++ {
+			if err := applyFieldEncryption(value.Index(i), nodes[i], format, ctx, encService); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		nodes, ok := tree.(map[string]any)
+		if !ok || !hasEncryptedFields(value.Type().Elem()) {
+			return nil
+		}
+		iter := value.MapRange()
+		for iter.Next() {
+			node, exists := nodes[fmt.Sprint(iter.Key().Interface())]
+			if !exists {
+				continue
+			}
+			if err := applyFieldEncryption(iter.Value(), node, format, ctx, encService); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		nodes, ok := tree.(map[string]any)
+		if !ok {
+			return nil
+		}
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			name, skip := formatFieldName(field, format)
+			if skip {
+				continue
+			}
+
+			fieldValue := value.Field(i)
+			if name == "" {
+				// Flattened embed: its fields live in this node
+				if err := applyFieldEncryption(fieldValue, nodes, format, ctx, encService); err != nil {
+					return err
+				}
+				continue
+			}
+
+			node, present := nodes[name]
+			if !present {
+				continue
+			}
+
+			mode := field.Tag.Get("encrypt")
+			if !isEncryptMode(mode) {
+				if hasEncryptedFields(field.Type) {
+					if err := applyFieldEncryption(fieldValue, node, format, ctx, encService); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			sealed, index, err := sealField(t, field, fieldValue, mode, ctx, encService)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt field %s: %w", field.Name, err)
+			}
+			if sealed != "" {
+				nodes[name] = sealed
+			}
+			if index != "" {
+				nodes[blindIndexColumn(t, field)] = index
+			}
+		}
+	}
+	return nil
+}
+
+// sealField encrypts one field of owner; nil values are left as they are.
+// Blind-indexed fields also return their companion index.
+func sealField(owner reflect.Type, field reflect.StructField, value reflect.Value, mode string, ctx SecurityContext, encService *EncryptionService) (string, string, error) {
+	algorithm, err := encryptAlgorithm(field, mode)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !value.CanInterface() {
+		return "", "", nil
+	}
+	if value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "", "", nil
+		}
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+	}
+
+	plaintext, err := sealTyped(value)
+	if err != nil {
+		return "", "", err
+	}
+
+	if mode == "subscriber" {
+		encrypted, err := encService.EncryptSubscriber(plaintext, ctx)
+		if err != nil {
+			return "", "", err
+		}
+		return base64.StdEncoding.EncodeToString(encrypted), "", nil
+	}
+
+	switch algorithm {
+	case AlgorithmAESSIV:
+		encrypted, err := encService.EncryptDeterministic(plaintext, ctx)
+		return string(encrypted), "", err
+	case catalog.AlgorithmBlindIndex:
+		index, err := encService.blindIndex(fieldContext(owner, field), plaintext, ctx)
+		if err != nil {
+			return "", "", err
+		}
+		encrypted, err := encService.EncryptOwner(plaintext, ctx)
+		return string(encrypted), index, err
+	}
+	encrypted, err := encService.EncryptOwner(plaintext, ctx)
+	return string(encrypted), "", err
+}
+
+// applyFieldDecryption restores the encrypted fields of value from tree, the
+// generic decoding of the input value was unmarshaled from. value must be a
+// pointer or addressable.
+func applyFieldDecryption(value reflect.Value, tree any, format string, ctx SecurityContext, encService *EncryptionService) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+		return applyFieldDecryption(value.Elem(), tree, format, ctx, encService)
+
+	case reflect.Slice, reflect.Array:
+		nodes, ok := tree.([]any)
+		if !ok || !hasEncryptedFields(value.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < value.Len() && i < len(nodes); i++ {
+			if err := applyFieldDecryption(value.Index(i), nodes[i], format, ctx, encService); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		nodes, ok := tree.(map[string]any)
+		if !ok || !hasEncryptedFields(value.Type().Elem()) {
+			return nil
+		}
+		// Map values are not addressable, so decrypt a copy and store it back
+		for _, key := range value.MapKeys() {
+			node, exists := nodes[fmt.Sprint(key.Interface())]
+			if !exists {
+				continue
+			}
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(value.MapIndex(key))
+			if err := applyFieldDecryption(elem, node, format, ctx, encService); err != nil {
+				return err
+			}
+			value.SetMapIndex(key, elem)
+		}
+
+	case reflect.Struct:
+		nodes, ok := tree.(map[string]any)
+		if !ok {
+			return nil
+		}
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			name, skip := formatFieldName(field, format)
+			if skip {
+				continue
+			}
+
+			fieldValue := value.Field(i)
+			if name == "" {
+				if err := applyFieldDecryption(fieldValue, nodes, format, ctx, encService); err != nil {
+					return err
+				}
+				continue
+			}
+
+			node, present := nodes[name]
+			if !present {
+				continue
+			}
+
+			mode := field.Tag.Get("encrypt")
+			if !isEncryptMode(mode) {
+				if hasEncryptedFields(field.Type) {
+					if err := applyFieldDecryption(fieldValue, node, format, ctx, encService); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			if !fieldValue.CanSet() {
+				continue
+			}
+			if err := openField(fieldValue, node, mode, ctx, encService); err != nil {
+				return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// openField decrypts one node into a field. Values that are not ciphertext
+// were sent in plaintext and are decoded as they are.
+func openField(dest reflect.Value, node any, mode string, ctx SecurityContext, encService *EncryptionService) error {
+	stored, ok := node.(string)
+	if !ok {
+		return setPlaintext(dest, node)
+	}
+
+	ciphertext := []byte(stored)
+	if mode == "subscriber" || !IsCiphertext(stored) {
+		decoded, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil {
+			// If it's not base64, assume it's already plaintext
+			return setPlaintext(dest, node)
+		}
+		ciphertext = decoded
+	}
+
+	var plaintext []byte
+	var err error
+	switch mode {
+	case "owner":
+		plaintext, err = encService.DecryptOwner(ciphertext, ctx)
+	case "subscriber":
+		plaintext, err = encService.DecryptSubscriber(ciphertext, ctx)
+	}
+	if err != nil {
+		return err
+	}
+	return openTyped(plaintext, dest)
+}
+
+// setPlaintext decodes a generic node into a field
+func setPlaintext(dest reflect.Value, node any) error {
+	raw, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dest.Addr().Interface())
+}
+
+// pruneEncryptedFields removes the encrypted fields of t from tree, so the
+// rest of the input can be decoded before the ciphertext is opened
+func pruneEncryptedFields(t reflect.Type, tree any, format string) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		pruneEncryptedFields(t.Elem(), tree, format)
+
+	case reflect.Slice, reflect.Array:
+		if nodes, ok := tree.([]any); ok && hasEncryptedFields(t.Elem()) {
+			for _, node := range nodes {
+				pruneEncryptedFields(t.Elem(), node, format)
+			}
+		}
+
+	case reflect.Map:
+		if nodes, ok := tree.(map[string]any); ok && hasEncryptedFields(t.Elem()) {
+			for _, node := range nodes {
+				pruneEncryptedFields(t.Elem(), node, format)
+			}
+		}
+
+	case reflect.Struct:
+		nodes, ok := tree.(map[string]any)
+		if !ok {
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			name, skip := formatFieldName(field, format)
+			switch {
+			case skip:
+			case name == "":
+				pruneEncryptedFields(field.Type, nodes, format)
+			case isEncryptMode(field.Tag.Get("encrypt")):
+				delete(nodes, name)
+			case hasEncryptedFields(field.Type):
+				pruneEncryptedFields(field.Type, nodes[name], format)
+			}
+		}
+	}
+}
+
+// sealFields encodes value, replacing its encrypted fields with ciphertext
+func sealFields(value reflect.Value, format Format, ctx SecurityContext, encService *EncryptionService) ([]byte, error) {
+	data, err := format.Encode(value.Interface())
+	if err != nil || !hasEncryptedFields(value.Type()) {
+		return data, err
+	}
+
+	tree, err := decodeTree(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyFieldEncryption(value, tree, format.Name(), ctx, encService); err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
+	return format.Encode(tree)
+}
+
+// openFields decodes data into v, restoring encrypted fields to their Go types
+func openFields(data []byte, v any, format Format, ctx SecurityContext, encService *EncryptionService) error {
+	t := reflect.TypeOf(v)
+	if t == nil || !hasEncryptedFields(t) {
+		return format.Decode(data, v)
+	}
+
+	tree, err := decodeTree(format, data)
+	if err != nil {
+		return err
+	}
+	pruned, err := decodeTree(format, data)
+	if err != nil {
+		return err
+	}
+	pruneEncryptedFields(t, pruned, format.Name())
+
+	plain, err := format.Encode(pruned)
+	if err != nil {
+		return err
+	}
+	if err := format.Decode(plain, v); err != nil {
+		return err
+	}
+
+	if err := applyFieldDecryption(reflect.ValueOf(v), tree, format.Name(), ctx, encService); err != nil {
+		return fmt.Errorf("decryption failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SealedAudit struct {
+	Reviewer string `json:"reviewer" yaml:"reviewer" toml:"reviewer" encrypt:"owner"`
+}
+
+type sealedCard struct {
+	Last4  string `json:"last4" yaml:"last4" toml:"last4"`
+	Number string `json:"number" yaml:"number" toml:"number" encrypt:"owner"`
+}
+
+type sealedProfile struct {
+	SealedAudit `yaml:",inline"`
+
+	Name     string       `json:"name" yaml:"name" toml:"name"`
+	Age      int          `json:"age" yaml:"age" toml:"age" encrypt:"owner"`
+	Balance  float64      `json:"balance" yaml:"balance" toml:"balance" encrypt:"owner"`
+	Born     time.Time    `json:"born" yaml:"born" toml:"born" encrypt:"owner"`
+	Secret   []byte       `json:"secret" yaml:"secret" toml:"secret" encrypt:"owner"`
+	Limit    *int64       `json:"limit" yaml:"limit" toml:"limit" encrypt:"owner"`
+	Address  sealedCard   `json:"address" yaml:"address" toml:"address" encrypt:"owner"`
+	Cards    []sealedCard `json:"cards" yaml:"cards" toml:"cards"`
+	Hint     any          `json:"hint" yaml:"hint" toml:"hint" encrypt:"owner"`
+	Verified bool         `json:"verified" yaml:"verified" toml:"verified" encrypt:"owner"`
+}
+
+func newSealedProfile() sealedProfile {
+	limit := int64(1) << 60
+	return sealedProfile{
+		SealedAudit: SealedAudit{Reviewer: "ops"},
+		Name:        "Ada",
+		Age:         36,
+		Balance:     1024.5,
+		Born:        time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC),
+		Secret:      []byte{0, 1, 2, 255},
+		Limit:       &limit,
+		Address:     sealedCard{Last4: "0000", Number: "nested"},
+		Cards:       []sealedCard{{Last4: "4242", Number: "4242424242424242"}, {Last4: "1111", Number: "4111111111111111"}},
+		Hint:        time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Verified:    true,
+	}
+}
+
+func TestFieldEncryption_TypedRoundTrip(t *testing.T) {
+	ctx := SecurityContext{OrgMasterKey: []byte("typed-envelope-key")}
+
+	formats := map[string]struct {
+		marshal   func(any, SecurityContext) ([]byte, error)
+		unmarshal func([]byte, any, SecurityContext) error
+	}{
+		"json": {MarshalSecure, UnmarshalSecure},
+		"yaml": {MarshalSecureYAML, UnmarshalSecureYAML},
+		"toml": {MarshalSecureTOML, UnmarshalSecureTOML},
+	}
+
+	for name, format := range formats {
+		t.Run(name, func(t *testing.T) {
+			original := newSealedProfile()
+			data, err := format.marshal(original, ctx)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			for _, plaintext := range []string{"4242424242424242", "nested", "ops", "1815"} {
+				if bytes.Contains(data, []byte(plaintext)) {
+					t.Errorf("Expected %q to be encrypted, got %s", plaintext, data)
+				}
+			}
+			if !bytes.Contains(data, []byte("4242")) || !bytes.Contains(data, []byte("Ada")) {
+				t.Errorf("Expected unencrypted fields in plaintext, got %s", data)
+			}
+
+			var decoded sealedProfile
+			if err := format.unmarshal(data, &decoded, ctx); err != nil {
+				t.Fatalf("Expected no error on unmarshal, got: %v", err)
+			}
+
+			if decoded.Age != 36 || decoded.Balance != 1024.5 || !decoded.Verified {
+				t.Errorf("Expected scalar fields restored, got %+v", decoded)
+			}
+			if !decoded.Born.Equal(original.Born) {
+				t.Errorf("Expected time %v, got %v", original.Born, decoded.Born)
+			}
+			if !bytes.Equal(decoded.Secret, original.Secret) {
+				t.Errorf("Expected bytes %v, got %v", original.Secret, decoded.Secret)
+			}
+			if decoded.Limit == nil || *decoded.Limit != *original.Limit {
+				t.Errorf("Expected exact int64 limit, got %v", decoded.Limit)
+			}
+			if decoded.Address != original.Address {
+				t.Errorf("Expected nested struct restored, got %+v", decoded.Address)
+			}
+			if len(decoded.Cards) != 2 || decoded.Cards[1].Number != "4111111111111111" {
+				t.Errorf("Expected slice elements decrypted, got %+v", decoded.Cards)
+			}
+			if decoded.Reviewer != "ops" {
+				t.Errorf("Expected embedded field decrypted, got %q", decoded.Reviewer)
+			}
+			if hint, ok := decoded.Hint.(time.Time); !ok || !hint.Equal(original.Hint.(time.Time)) {
+				t.Errorf("Expected interface field restored as time.Time, got %T %v", decoded.Hint, decoded.Hint)
+			}
+		})
+	}
+}
+
+func TestFieldEncryption_TypeMismatch(t *testing.T) {
+	ctx := SecurityContext{OrgMasterKey: []byte("typed-envelope-key")}
+
+	data, err := MarshalSecure(sealedProfile{Name: "a", Age: 1}, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Move the age ciphertext onto the balance field
+	var tree map[string]any
+	json.Unmarshal(data, &tree)
+	tree["balance"] = tree["age"]
+	tampered, _ := json.Marshal(tree)
+
+	var decoded sealedProfile
+	err = UnmarshalSecure(tampered, &decoded, ctx)
+	if err == nil || !strings.Contains(err.Error(), "ciphertext holds int") {
+		t.Errorf("Expected type mismatch error, got %v", err)
+	}
+}
+
+func TestFieldEncryption_PlaintextInput(t *testing.T) {
+	ctx := SecurityContext{OrgMasterKey: []byte("typed-envelope-key")}
+	input := []byte(`{"name":"a","age":41,"cards":[{"last4":"1","number":"not-sealed"}]}`)
+
+	var decoded sealedProfile
+	if err := UnmarshalSecure(input, &decoded, ctx); err != nil {
+		t.Fatalf("Expected plaintext input to be accepted, got: %v", err)
+	}
+	if decoded.Age != 41 || decoded.Cards[0].Number != "not-sealed" {
+		t.Errorf("Expected plaintext values kept, got %+v", decoded)
+	}
+}
@@ -0,0 +1,232 @@
+package cereal
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Serializer is the surface every cereal format exposes
+type Serializer interface {
+	Marshal(v any, permissions ...string) ([]byte, error)
+	Unmarshal(data []byte, v any, permissions ...string) error
+}
+
+// Format encodes and decodes one wire format. It knows nothing about
+// scoping, validation or encryption: wrap it in a Pipeline to get those.
+type Format interface {
+	Name() string // Also the struct tag the format reads, e.g. "json"
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, v any) error
+}
+
+// TreeDecoder is implemented by formats whose generic decoding needs more
+// than Decode into an any, e.g. to keep numbers exact or to produce string
+// keyed maps. Field encryption, patches and schema upcasting work on trees.
+type TreeDecoder interface {
+	DecodeTree(data []byte) (any, error)
+}
+
+// decodeTree decodes data into the format's generic tree
+func decodeTree(format Format, data []byte) (any, error) {
+	if decoder, ok := format.(TreeDecoder); ok {
+		return decoder.DecodeTree(data)
+	}
+	var tree any
+	err := format.Decode(data, &tree)
+	return tree, err
+}
+
+// NewFormat builds a Format from an encoder and a decoder
+//
+//	bsonFormat := cereal.NewFormat("bson", bson.Marshal, bson.Unmarshal)
+func NewFormat(name string, encode func(v any) ([]byte, error), decode func(data []byte, v any) error) Format {
+	return &funcFormat{name: name, encode: encode, decode: decode}
+}
+
+// funcFormat is a Format made of functions; decodeTree is optional
+type funcFormat struct {
+	name       string
+	encode     func(v any) ([]byte, error)
+	decode     func(data []byte, v any) error
+	decodeTree func(data []byte) (any, error)
+}
+
+// Name implements Format
+func (f *funcFormat) Name() string { return f.name }
+
+// Encode implements Format
+func (f *funcFormat) Encode(v any) ([]byte, error) { return f.encode(v) }
+
+// Decode implements Format
+func (f *funcFormat) Decode(data []byte, v any) error { return f.decode(data, v) }
+
+// DecodeTree implements TreeDecoder
+func (f *funcFormat) DecodeTree(data []byte) (any, error) {
+	if f.decodeTree != nil {
+		return f.decodeTree(data)
+	}
+	var tree any
+	err := f.decode(data, &tree)
+	return tree, err
+}
+
+// ContextSerializer is a Serializer that acts on behalf of the identity and
+// purpose carried by a context (see WithIdentity and WithPurpose). All the
+// built-in formats implement it.
+type ContextSerializer interface {
+	Serializer
+	MarshalContext(ctx context.Context, v any, permissions ...string) ([]byte, error)
+	UnmarshalContext(ctx context.Context, data []byte, v any, permissions ...string) error
+}
+
+// FormatInfo describes a registered format so callers can pick one by name,
+// file extension or media type
+type FormatInfo struct {
+	Name         string     // Short name, also the struct tag the format reads, e.g. "json"
+	ContentTypes []string   // Media types; the first is canonical
+	Extensions   []string   // File extensions with the leading dot
+	Binary       bool       // Output is not text
+	Format       Format     `json:"-"` // Wire encoding; RegisterFormat wraps it in a Pipeline
+	Serializer   Serializer `json:"-"` // Set by RegisterFormat when Format is given
+}
+
+// ContentType returns the canonical media type
+func (f FormatInfo) ContentType() string {
+	if len(f.ContentTypes) == 0 {
+		return ""
+	}
+	return f.ContentTypes[0]
+}
+
+// Format registry
+var (
+	formats      = make(map[string]FormatInfo)
+	formatsMutex sync.RWMutex
+)
+
+// RegisterFormat makes a format selectable by name, extension and content type.
+// Names, extensions and content types must be unique across formats. A
+// format registered with a Format gets a Pipeline as its Serializer, so it is
+// scoped, validated, audited and can be secured like the built-in ones.
+func RegisterFormat(info FormatInfo) error {
+	if info.Serializer == nil && info.Format != nil {
+		info.Serializer = NewPipeline(info.Format)
+	}
+	if pipeline, ok := info.Serializer.(*Pipeline); ok && info.Format == nil {
+		info.Format = pipeline.format
+	}
+	if info.Name == "" || info.Serializer == nil {
+		return fmt.Errorf("format must have a name and a format or serializer")
+	}
+
+	formatsMutex.Lock()
+	defer formatsMutex.Unlock()
+
+	if _, exists := formats[info.Name]; exists {
+		return fmt.Errorf("format %s is already registered", info.Name)
+	}
+	for _, existing := range formats {
+		for _, ext := range info.Extensions {
+			for _, other := range existing.Extensions {
+				if strings.EqualFold(ext, other) {
+					return fmt.Errorf("extension %s is already registered by %s", ext, existing.Name)
+				}
+			}
+		}
+		for _, contentType := range info.ContentTypes {
+			for _, other := range existing.ContentTypes {
+				if strings.EqualFold(contentType, other) {
+					return fmt.Errorf("content type %s is already registered by %s", contentType, existing.Name)
+				}
+			}
+		}
+	}
+
+	formats[info.Name] = info
+	return nil
+}
+
+// mustRegisterFormat registers a built-in format at init
+func mustRegisterFormat(info FormatInfo) {
+	if err := RegisterFormat(info); err != nil {
+		panic(err)
+	}
+}
+
+// Formats returns every registered format sorted by name
+func Formats() []FormatInfo {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+
+	list := make([]FormatInfo, 0, len(formats))
+	for _, info := range formats {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// FormatByName finds a format by its short name
+func FormatByName(name string) (FormatInfo, bool) {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+	info, exists := formats[strings.ToLower(name)]
+	return info, exists
+}
+
+// FormatByExtension finds a format by file extension or by a path ending in one
+func FormatByExtension(pathOrExt string) (FormatInfo, bool) {
+	ext := pathOrExt
+	if !strings.HasPrefix(ext, ".") {
+		ext = filepath.Ext(pathOrExt)
+	}
+
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+	for _, info := range formats {
+		for _, candidate := range info.Extensions {
+			if strings.EqualFold(candidate, ext) {
+				return info, true
+			}
+		}
+	}
+	return FormatInfo{}, false
+}
+
+// FormatByContentType finds a format by media type; parameters such as
+// charset are ignored
+func FormatByContentType(contentType string) (FormatInfo, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.ToLower(contentType))
+	}
+
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+	for _, info := range formats {
+		for _, candidate := range info.ContentTypes {
+			if strings.EqualFold(candidate, mediaType) {
+				return info, true
+			}
+		}
+	}
+	return FormatInfo{}, false
+}
+
+// SecureFormat returns a pipeline for a registered format that also
+// encrypts and decrypts encrypt-tagged fields
+func SecureFormat(name string, encryption *EncryptionService) (*Pipeline, error) {
+	info, exists := FormatByName(name)
+	if !exists {
+		return nil, fmt.Errorf("format %s is not registered", name)
+	}
+	if info.Format == nil {
+		return nil, fmt.Errorf("format %s has no Format to secure", name)
+	}
+	return NewSecurePipeline(info.Format, encryption), nil
+}
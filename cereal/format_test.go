@@ -0,0 +1,210 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type binaryContact struct {
+	Email string `json:"email" validate:"required,email"`
+	Phone string `json:"phone" msgpack:"tel" cbor:"tel" scope:"support"`
+}
+
+type binaryAccount struct {
+	ID       int             `json:"id" validate:"min=1"`
+	Name     string          `json:"name"`
+	Tier     string          `json:"tier" scope:"admin"`
+	Created  time.Time       `json:"created"`
+	Contacts []binaryContact `json:"contacts" validate:"dive"`
+}
+
+func newBinaryAccount() binaryAccount {
+	return binaryAccount{
+		ID:       3,
+		Name:     "acme",
+		Tier:     "gold",
+		Created:  time.Date(2024, 5, 1, 12, 30, 0, 123, time.UTC),
+		Contacts: []binaryContact{{Email: "ops@acme.test", Phone: "555-0100"}},
+	}
+}
+
+func TestBinaryFormats_RoundTrip(t *testing.T) {
+	for _, format := range []Serializer{MSGPACK, CBOR} {
+		original := newBinaryAccount()
+
+		data, err := format.Marshal(original, "admin", "support")
+		if err != nil {
+			t.Fatalf("%T: expected no error, got: %v", format, err)
+		}
+
+		var decoded binaryAccount
+		if err := format.Unmarshal(data, &decoded, "admin", "support"); err != nil {
+			t.Fatalf("%T: expected no error on unmarshal, got: %v", format, err)
+		}
+
+		if decoded.Tier != "gold" || decoded.Contacts[0].Phone != "555-0100" {
+			t.Errorf("%T: expected full round trip, got %+v", format, decoded)
+		}
+		if !decoded.Created.Equal(original.Created) {
+			t.Errorf("%T: expected time %v, got %v", format, original.Created, decoded.Created)
+		}
+	}
+}
+
+func TestBinaryFormats_Scoping(t *testing.T) {
+	for _, format := range []Serializer{MSGPACK, CBOR} {
+		data, err := format.Marshal(newBinaryAccount())
+		if err != nil {
+			t.Fatalf("%T: expected no error, got: %v", format, err)
+		}
+
+		var decoded binaryAccount
+		if err := format.Unmarshal(data, &decoded, "admin", "support"); err != nil {
+			t.Fatalf("%T: expected no error on unmarshal, got: %v", format, err)
+		}
+		if decoded.Tier != "[REDACTED]" || decoded.Contacts[0].Phone != "[REDACTED]" {
+			t.Errorf("%T: expected scoped fields to be redacted, got %+v", format, decoded)
+		}
+		if decoded.Name != "acme" {
+			t.Errorf("%T: expected unscoped field to be kept, got %q", format, decoded.Name)
+		}
+	}
+}
+
+func TestBinaryFormats_ValidationAndScopeErrors(t *testing.T) {
+	for _, format := range []Serializer{MSGPACK, CBOR} {
+		invalid := newBinaryAccount()
+		invalid.Contacts[0].Email = "not-an-email"
+		if _, err := format.Marshal(invalid, "admin"); err == nil {
+			t.Errorf("%T: expected validation error for nested email", format)
+		}
+
+		_, err := format.Marshal(ScopedUser{Name: "a", Email: "a@b.test", Role: "admin"})
+		var scopeErr *ScopeError
+		if !errors.As(err, &scopeErr) {
+			t.Errorf("%T: expected *ScopeError, got %v", format, err)
+		}
+	}
+}
+
+func TestFormatRegistry(t *testing.T) {
+	tests := []struct {
+		lookup string
+		byExt  bool
+		want   string
+	}{
+		{"config.yml", true, "yaml"},
+		{".JSON", true, "json"},
+		{"events.msgpack", true, "msgpack"},
+		{"cache.cbor", true, "cbor"},
+		{"application/json; charset=utf-8", false, "json"},
+		{"application/x-msgpack", false, "msgpack"},
+		{"application/cbor", false, "cbor"},
+		{"application/toml", false, "toml"},
+	}
+
+	for _, tt := range tests {
+		var info FormatInfo
+		var found bool
+		if tt.byExt {
+			info, found = FormatByExtension(tt.lookup)
+		} else {
+			info, found = FormatByContentType(tt.lookup)
+		}
+		if !found || info.Name != tt.want {
+			t.Errorf("Expected %s for %q, got %q (found=%v)", tt.want, tt.lookup, info.Name, found)
+		}
+	}
+
+	if _, found := FormatByExtension(".exe"); found {
+		t.Error("Expected no format for .exe")
+	}
+	if info, _ := FormatByName("cbor"); !info.Binary || info.Serializer != CBOR {
+		t.Errorf("Expected binary CBOR serializer, got %+v", info)
+	}
+	if err := RegisterFormat(FormatInfo{Name: "jsonish", Extensions: []string{".json"}, Serializer: JSON}); err == nil {
+		t.Error("Expected duplicate extension to be rejected")
+	}
+	if len(Formats()) < 5 {
+		t.Errorf("Expected at least 5 formats, got %d", len(Formats()))
+	}
+}
+
+// base64JSON is a format the package knows nothing about
+var base64JSON = NewFormat("b64json",
+	func(v any) ([]byte, error) {
+		data, err := json.Marshal(v)
+		return []byte(base64.StdEncoding.EncodeToString(data)), err
+	},
+	func(data []byte, v any) error {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(decoded, v)
+	},
+)
+
+func TestRegisterFormat_Pipeline(t *testing.T) {
+	if err := RegisterFormat(FormatInfo{Name: "b64json", Extensions: []string{".b64"}, Format: base64JSON}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	sink := NewMemoryAuditSink()
+	useAuditSink(t, sink)
+
+	info, _ := FormatByExtension("data.b64")
+	data, err := info.Serializer.Marshal(newBinaryAccount())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var decoded binaryAccount
+	if err := base64JSON.Decode(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if decoded.Tier != "[REDACTED]" || decoded.Contacts[0].Phone != "[REDACTED]" {
+		t.Errorf("Expected scoped fields to be redacted, got %+v", decoded)
+	}
+	if records := sink.Records(); len(records) != 1 || records[0].Format != "b64json" {
+		t.Errorf("Expected the marshal to be audited as b64json, got %+v", records)
+	}
+
+	invalid := newBinaryAccount()
+	invalid.ID = 0
+	if _, err := info.Serializer.Marshal(invalid, "admin"); err == nil {
+		t.Error("Expected validation error")
+	}
+}
+
+func TestSecureFormat(t *testing.T) {
+	if _, err := SecureFormat("nope", NewEncryptionService([]byte("k"))); err == nil {
+		t.Error("Expected an unregistered format to be rejected")
+	}
+
+	for _, name := range []string{"json", "msgpack", "cbor"} {
+		secure, err := SecureFormat(name, NewEncryptionService([]byte("test-org-master-key")))
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", name, err)
+		}
+
+		card := sealedCard{Last4: "4242", Number: "4242424242424242"}
+		data, err := secure.Marshal(card)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", name, err)
+		}
+		if bytes.Contains(data, []byte(card.Number)) {
+			t.Errorf("%s: expected number to be encrypted, got %q", name, data)
+		}
+
+		var decoded sealedCard
+		if err := secure.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: expected no error on unmarshal, got: %v", name, err)
+		}
+		if decoded != card {
+			t.Errorf("%s: expected %+v, got %+v", name, card, decoded)
+		}
+	}
+}
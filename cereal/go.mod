@@ -5,14 +5,17 @@ go 1.22
 toolchain go1.23.1
 
 require (
+	github.com/fxamacker/cbor/v2 v2.9.2
 	github.com/go-playground/validator/v10 v10.17.0
 	github.com/pelletier/go-toml/v2 v2.2.4
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 	gopkg.in/yaml.v3 v3.0.1
 	zbz/catalog v0.0.0-00010101000000-000000000000
 	zbz/pipz v0.0.0-00010101000000-000000000000
 )
 
 replace zbz/catalog => ../catalog
+
 replace zbz/pipz => ../pipz
 
 require (
@@ -20,6 +23,8 @@ require (
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
 	github.com/leodido/go-urn v1.2.4 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/crypto v0.7.0 // indirect
 	golang.org/x/net v0.8.0 // indirect
 	golang.org/x/sys v0.6.0 // indirect
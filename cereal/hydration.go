@@ -1,6 +1,7 @@
 package cereal
 
 import (
+	"context"
 	"sync"
 )
 
@@ -16,6 +17,8 @@ type CerealEvent struct {
 	ModelType   string                 `json:"model_type"`   
 	FieldName   string                 `json:"field_name,omitempty"`
 	FieldType   string                 `json:"field_type,omitempty"`
+	Identity    string                 `json:"identity,omitempty"` // From WithIdentity
+	Purpose     string                 `json:"purpose,omitempty"`  // From WithPurpose
 	Permissions []string               `json:"permissions"`
 	Success     bool                   `json:"success"`
 	Error       string                 `json:"error,omitempty"`
@@ -59,10 +62,12 @@ func emitEvent(event CerealEvent) {
 
 // Convenience functions for emitting specific event types
 
-func emitMarshalEvent(modelType string, permissions []string, success bool, err error) {
+func emitMarshalEvent(ctx context.Context, modelType string, permissions []string, success bool, err error) {
 	event := CerealEvent{
 		Action:      "marshal",
 		ModelType:   modelType,
+		Identity:    IdentityFrom(ctx),
+		Purpose:     PurposeFrom(ctx),
 		Permissions: permissions,
 		Success:     success,
 	}
@@ -72,10 +77,12 @@ func emitMarshalEvent(modelType string, permissions []string, success bool, err
 	emitEvent(event)
 }
 
-func emitUnmarshalEvent(modelType string, permissions []string, success bool, err error) {
+func emitUnmarshalEvent(ctx context.Context, modelType string, permissions []string, success bool, err error) {
 	event := CerealEvent{
 		Action:      "unmarshal",
 		ModelType:   modelType,
+		Identity:    IdentityFrom(ctx),
+		Purpose:     PurposeFrom(ctx),
 		Permissions: permissions,
 		Success:     success,
 	}
@@ -85,16 +92,20 @@ func emitUnmarshalEvent(modelType string, permissions []string, success bool, er
 	emitEvent(event)
 }
 
-func emitFieldScopeEvent(modelType, fieldName, fieldType string, permissions []string, granted bool) {
+func emitFieldScopeEvent(ctx context.Context, modelType string, access FieldAccess, permissions []string) {
+	granted := access.Decision == DecisionReturned || access.Decision == DecisionAccepted
 	event := CerealEvent{
 		Action:      "scope_check",
 		ModelType:   modelType,
-		FieldName:   fieldName,
-		FieldType:   fieldType,
+		FieldName:   access.Path,
+		FieldType:   access.Type,
+		Identity:    IdentityFrom(ctx),
+		Purpose:     PurposeFrom(ctx),
 		Permissions: permissions,
 		Success:     granted,
 		Metadata: map[string]interface{}{
 			"access_granted": granted,
+			"decision":       string(access.Decision),
 		},
 	}
 	emitEvent(event)
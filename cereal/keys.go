@@ -0,0 +1,285 @@
+package cereal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrKeyNotFound is returned when a ciphertext names a key the provider does not hold
+var ErrKeyNotFound = errors.New("encryption key not found")
+
+// KeyProvider supplies master keys by ID. New values are always encrypted
+// with the current key; older keys stay available so existing ciphertext
+// can be decrypted and re-encrypted.
+type KeyProvider interface {
+	CurrentKey() (id string, key []byte, err error)
+	Key(id string) ([]byte, error)
+}
+
+// MemoryKeyring is an in-memory KeyProvider, useful for tests and for keys
+// loaded from a secret manager at startup
+type MemoryKeyring struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+// NewMemoryKeyring creates an empty keyring
+func NewMemoryKeyring() *MemoryKeyring {
+	return &MemoryKeyring{keys: make(map[string][]byte)}
+}
+
+// Add stores a master key and makes it current
+func (k *MemoryKeyring) Add(id string, key []byte) error {
+	if err := validateKeyID(id); err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("key %s is empty", id)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, exists := k.keys[id]; exists {
+		return fmt.Errorf("key %s already exists", id)
+	}
+	k.keys[id] = append([]byte(nil), key...)
+	k.current = id
+	return nil
+}
+
+// SetCurrent selects an existing key for new encryptions
+func (k *MemoryKeyring) SetCurrent(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, exists := k.keys[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	k.current = id
+	return nil
+}
+
+// Rotate generates a random 256-bit key, makes it current and returns its ID
+func (k *MemoryKeyring) Rotate() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	id := keyFingerprint(key)
+	return id, k.Add(id, key)
+}
+
+// IDs returns the IDs of every key in the ring, sorted
+func (k *MemoryKeyring) IDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CurrentKey implements KeyProvider
+func (k *MemoryKeyring) CurrentKey() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == "" {
+		return "", nil, fmt.Errorf("keyring has no current key")
+	}
+	return k.current, k.keys[k.current], nil
+}
+
+// Key implements KeyProvider
+func (k *MemoryKeyring) Key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, exists := k.keys[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// FileKeyring is a KeyProvider persisted as a JSON file. Every change is
+// written back to disk with owner-only permissions.
+type FileKeyring struct {
+	path string
+	ring *MemoryKeyring
+}
+
+// keyringFile is the on-disk layout of a FileKeyring
+type keyringFile struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"` // ID to base64 key
+}
+
+// NewFileKeyring loads the keyring at path, starting empty if the file does
+// not exist. A file holding keys must name its current key.
+func NewFileKeyring(path string) (*FileKeyring, error) {
+	k := &FileKeyring{path: path, ring: NewMemoryKeyring()}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read keyring: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse keyring %s: %w", path, err)
+	}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode key %s: %w", id, err)
+		}
+		if err := k.ring.Add(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if len(file.Keys) == 0 {
+		return k, nil
+	}
+
+	// Map order is random, so the current key must be named explicitly
+	if file.Current == "" {
+		return nil, fmt.Errorf("keyring %s has keys but no current key", path)
+	}
+	if err := k.ring.SetCurrent(file.Current); err != nil {
+		return nil, fmt.Errorf("keyring %s: %w", path, err)
+	}
+	return k, nil
+}
+
+// Add stores a master key, makes it current and saves the file
+func (k *FileKeyring) Add(id string, key []byte) error {
+	if err := k.ring.Add(id, key); err != nil {
+		return err
+	}
+	return k.save()
+}
+
+// SetCurrent selects an existing key for new encryptions and saves the file
+func (k *FileKeyring) SetCurrent(id string) error {
+	if err := k.ring.SetCurrent(id); err != nil {
+		return err
+	}
+	return k.save()
+}
+
+// Rotate generates a new current key, saves the file and returns the key's ID
+func (k *FileKeyring) Rotate() (string, error) {
+	id, err := k.ring.Rotate()
+	if err != nil {
+		return "", err
+	}
+	return id, k.save()
+}
+
+// IDs returns the IDs of every key in the ring, sorted
+func (k *FileKeyring) IDs() []string {
+	return k.ring.IDs()
+}
+
+// CurrentKey implements KeyProvider
+func (k *FileKeyring) CurrentKey() (string, []byte, error) {
+	return k.ring.CurrentKey()
+}
+
+// Key implements KeyProvider
+func (k *FileKeyring) Key(id string) ([]byte, error) {
+	return k.ring.Key(id)
+}
+
+// save writes the keyring atomically through a temporary file
+func (k *FileKeyring) save() error {
+	k.ring.mu.RLock()
+	file := keyringFile{Current: k.ring.current, Keys: make(map[string]string, len(k.ring.keys))}
+	for id, key := range k.ring.keys {
+		file.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	}
+	k.ring.mu.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), ".keyring-*")
+	if err != nil {
+		return fmt.Errorf("save keyring: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save keyring: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save keyring: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save keyring: %w", err)
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
+
+// staticKeyring adapts a single master key, e.g. SecurityContext.OrgMasterKey,
+// to KeyProvider. Its ID is the key's fingerprint so ciphertext written with
+// it can be told apart from other keys.
+type staticKeyring struct {
+	id  string
+	key []byte
+}
+
+func newStaticKeyring(key []byte) staticKeyring {
+	return staticKeyring{id: keyFingerprint(key), key: key}
+}
+
+// CurrentKey implements KeyProvider
+func (k staticKeyring) CurrentKey() (string, []byte, error) {
+	if len(k.key) == 0 {
+		return "", nil, fmt.Errorf("no organization master key provided")
+	}
+	return k.id, k.key, nil
+}
+
+// Key implements KeyProvider
+func (k staticKeyring) Key(id string) ([]byte, error) {
+	if len(k.key) == 0 || id != k.id {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return k.key, nil
+}
+
+// keyFingerprint derives a short, stable ID from key material
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// validateKeyID rejects IDs that would break the ciphertext format
+func validateKeyID(id string) error {
+	if id == "" {
+		return fmt.Errorf("key ID is required")
+	}
+	if strings.ContainsAny(id, envelopeSeparator+" \t\n") {
+		return fmt.Errorf("key ID %q must not contain %q or whitespace", id, envelopeSeparator)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package cereal
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type rotatedRecord struct {
+	Name    string `json:"name"`
+	Token   string `json:"token" encrypt:"owner"`
+	Archive string `json:"archive" encrypt:"owner" key_rotation:"false"`
+}
+
+func TestEnvelope_SelfDescribing(t *testing.T) {
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	service := NewEncryptionServiceWithKeys(keys)
+
+	sealed, err := service.EncryptOwner([]byte("secret"), SecurityContext{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	parsed, err := ParseCiphertext(string(sealed))
+	if err != nil {
+		t.Fatalf("Expected parseable ciphertext, got: %v", err)
+	}
+	if parsed.Version != 1 || parsed.KeyID != "k1" || parsed.Algorithm != AlgorithmAES256GCM {
+		t.Errorf("Expected v1/k1/%s header, got %+v", AlgorithmAES256GCM, parsed)
+	}
+
+	plaintext, err := service.DecryptOwner(sealed, SecurityContext{})
+	if err != nil || string(plaintext) != "secret" {
+		t.Errorf("Expected round trip, got %q (%v)", plaintext, err)
+	}
+
+	// The header is authenticated, so relabeling the key fails
+	tampered := strings.Replace(string(sealed), ":k1:", ":k2:", 1)
+	keys.Add("k2", []byte("first master key"))
+	if _, err := service.DecryptOwner([]byte(tampered), SecurityContext{}); err == nil {
+		t.Error("Expected tampered header to fail authentication")
+	}
+}
+
+func TestEnvelope_UnknownKey(t *testing.T) {
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	sealed, _ := NewEncryptionServiceWithKeys(keys).EncryptOwner([]byte("secret"), SecurityContext{})
+
+	other := NewMemoryKeyring()
+	other.Add("k9", []byte("unrelated"))
+	_, err := NewEncryptionServiceWithKeys(other).DecryptOwner(sealed, SecurityContext{})
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestEnvelope_LegacyCiphertext(t *testing.T) {
+	key := []byte("legacy key")
+	legacy, err := sealGCM(deriveMasterKey(key), []byte("old value"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := base64.StdEncoding.EncodeToString(legacy)
+
+	service := NewEncryptionService(key)
+	plaintext, err := service.DecryptOwner(legacy, SecurityContext{})
+	if err != nil || string(plaintext) != "old value" {
+		t.Fatalf("Expected legacy decrypt, got %q (%v)", plaintext, err)
+	}
+
+	upgraded, changed, err := service.Reencrypt(stored, SecurityContext{})
+	if err != nil || !changed || !IsCiphertext(upgraded) {
+		t.Fatalf("Expected legacy value to be upgraded, got %q changed=%v (%v)", upgraded, changed, err)
+	}
+}
+
+func TestEnvelope_LegacyKeyOnKeyring(t *testing.T) {
+	oldKey := []byte("legacy key")
+	raw, _ := sealGCM(deriveMasterKey(oldKey), []byte("old value"), nil)
+	legacy := base64.StdEncoding.EncodeToString(raw)
+	sealed, _ := NewEncryptionService(oldKey).EncryptOwner([]byte("sealed value"), SecurityContext{})
+
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	service := NewEncryptionServiceWithLegacyKey(keys, oldKey)
+
+	for stored, want := range map[string]string{legacy: "old value", string(sealed): "sealed value"} {
+		migrated, changed, err := service.Reencrypt(stored, SecurityContext{})
+		if err != nil || !changed {
+			t.Fatalf("Expected %q to be migrated, got changed=%v (%v)", want, changed, err)
+		}
+		if parsed, _ := ParseCiphertext(migrated); parsed.KeyID != "k1" {
+			t.Errorf("Expected %q to be re-sealed under k1, got %s", want, parsed.KeyID)
+		}
+		if plaintext, err := service.DecryptOwner([]byte(migrated), SecurityContext{}); err != nil || string(plaintext) != want {
+			t.Errorf("Expected migrated value %q, got %q (%v)", want, plaintext, err)
+		}
+	}
+
+	// The legacy key never seals new values, even with an empty keyring
+	if _, err := NewEncryptionServiceWithLegacyKey(NewMemoryKeyring(), oldKey).EncryptOwner([]byte("new"), SecurityContext{}); err == nil {
+		t.Error("Expected the legacy key to be decrypt-only")
+	}
+}
+
+func TestReencryptFields_KeyRotation(t *testing.T) {
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	service := NewEncryptionServiceWithKeys(keys)
+	ctx := SecurityContext{}
+
+	record := rotatedRecord{Name: "a"}
+	token, _ := service.EncryptOwner([]byte("tok"), ctx)
+	archive, _ := service.EncryptOwner([]byte("arc"), ctx)
+	record.Token, record.Archive = string(token), string(archive)
+
+	newID, err := keys.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := service.ReencryptFields(&record, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if migrated != 1 {
+		t.Errorf("Expected 1 migrated field, got %d", migrated)
+	}
+
+	parsedToken, _ := ParseCiphertext(record.Token)
+	parsedArchive, _ := ParseCiphertext(record.Archive)
+	if parsedToken.KeyID != newID {
+		t.Errorf("Expected token under %s, got %s", newID, parsedToken.KeyID)
+	}
+	if parsedArchive.KeyID != "k1" {
+		t.Errorf("Expected pinned archive to stay under k1, got %s", parsedArchive.KeyID)
+	}
+
+	if again, _ := service.ReencryptFields(&record, ctx); again != 0 {
+		t.Errorf("Expected second pass to be a no-op, got %d", again)
+	}
+
+	plaintext, err := service.DecryptOwner([]byte(record.Token), ctx)
+	if err != nil || string(plaintext) != "tok" {
+		t.Errorf("Expected migrated token to decrypt, got %q (%v)", plaintext, err)
+	}
+}
+
+type rotatedBalance struct {
+	Cents int `json:"cents" encrypt:"owner"`
+}
+
+type rotatedAccount struct {
+	rotatedRecord
+	Nested   rotatedRecord            `json:"nested"`
+	Pointer  *rotatedRecord           `json:"pointer"`
+	List     []rotatedRecord          `json:"list"`
+	ByName   map[string]rotatedRecord `json:"by_name"`
+	Balances []rotatedBalance         `json:"balances"`
+}
+
+func TestReencryptFields_Nested(t *testing.T) {
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	service := NewEncryptionServiceWithKeys(keys)
+	ctx := SecurityContext{}
+
+	seal := func() rotatedRecord {
+		token, _ := service.EncryptOwner([]byte("tok"), ctx)
+		archive, _ := service.EncryptOwner([]byte("arc"), ctx)
+		return rotatedRecord{Token: string(token), Archive: string(archive)}
+	}
+	account := rotatedAccount{
+		rotatedRecord: seal(),
+		Nested:        seal(),
+		Pointer:       &rotatedRecord{},
+		List:          []rotatedRecord{seal(), seal()},
+		ByName:        map[string]rotatedRecord{"a": seal()},
+	}
+	*account.Pointer = seal()
+
+	newID, err := keys.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := service.ReencryptFields(&account, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if migrated != 6 {
+		t.Errorf("Expected 6 migrated fields, got %d", migrated)
+	}
+
+	for name, record := range map[string]rotatedRecord{
+		"embedded": account.rotatedRecord,
+		"nested":   account.Nested,
+		"pointer":  *account.Pointer,
+		"list[1]":  account.List[1],
+		"map":      account.ByName["a"],
+	} {
+		if parsed, _ := ParseCiphertext(record.Token); parsed.KeyID != newID {
+			t.Errorf("Expected %s token under %s, got %s", name, newID, parsed.KeyID)
+		}
+		if parsed, _ := ParseCiphertext(record.Archive); parsed.KeyID != "k1" {
+			t.Errorf("Expected pinned %s archive to stay under k1, got %s", name, parsed.KeyID)
+		}
+	}
+}
+
+func TestReencryptEncoded_TypedFields(t *testing.T) {
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	service := NewEncryptionServiceWithKeys(keys)
+	pipeline := NewSecurePipeline(jsonFormat, service)
+	ctx := SecurityContext{}
+
+	stored, err := pipeline.MarshalWithContext(rotatedAccount{
+		Balances: []rotatedBalance{{Cents: 250}},
+		ByName:   map[string]rotatedRecord{"a": {Token: "tok"}},
+	}, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	newID, err := keys.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, migrated, err := service.ReencryptEncoded(stored, rotatedAccount{}, jsonFormat, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Token in the root, nested and map records, plus the int balance
+	if migrated != 4 {
+		t.Errorf("Expected 4 migrated fields, got %d", migrated)
+	}
+	tree, _ := decodeTree(jsonFormat, rotated)
+	balance := tree.(map[string]any)["balances"].([]any)[0].(map[string]any)["cents"].(string)
+	if parsed, _ := ParseCiphertext(balance); parsed.KeyID != newID {
+		t.Errorf("Expected typed balance under %s, got %s", newID, parsed.KeyID)
+	}
+
+	var account rotatedAccount
+	if err := pipeline.UnmarshalWithContext(rotated, &account, ctx); err != nil {
+		t.Fatalf("Expected rotated document to decrypt, got: %v", err)
+	}
+	if len(account.Balances) != 1 || account.Balances[0].Cents != 250 || account.ByName["a"].Token != "tok" {
+		t.Errorf("Expected values to survive rotation, got %+v", account)
+	}
+}
+
+func TestFileKeyring_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+
+	keys, err := NewFileKeyring(path)
+	if err != nil {
+		t.Fatalf("Expected empty keyring, got: %v", err)
+	}
+	if _, _, err := keys.CurrentKey(); err == nil {
+		t.Error("Expected empty keyring to have no current key")
+	}
+	if err := keys.Add("k1", []byte("first master key")); err != nil {
+		t.Fatal(err)
+	}
+	second, err := keys.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected keyring file, got: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	reloaded, err := NewFileKeyring(path)
+	if err != nil {
+		t.Fatalf("Expected keyring to reload, got: %v", err)
+	}
+	if id, _, _ := reloaded.CurrentKey(); id != second {
+		t.Errorf("Expected current key %s, got %s", second, id)
+	}
+	if key, err := reloaded.Key("k1"); err != nil || string(key) != "first master key" {
+		t.Errorf("Expected k1 to survive reload, got %q (%v)", key, err)
+	}
+	if err := reloaded.Add("bad:id", []byte("x")); err == nil {
+		t.Error("Expected key ID with separator to be rejected")
+	}
+}
+
+func TestFileKeyring_RequiresCurrentKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	keys := `{"keys": {"k1": "Zmlyc3QgbWFzdGVyIGtleQ==", "k2": "c2Vjb25kIG1hc3RlciBrZXk="}}`
+	if err := os.WriteFile(path, []byte(keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileKeyring(path); err == nil {
+		t.Error("Expected a keyring without a current key to be rejected")
+	}
+
+	missing := `{"current": "k3", "keys": {"k1": "Zmlyc3QgbWFzdGVyIGtleQ=="}}`
+	if err := os.WriteFile(path, []byte(missing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileKeyring(path); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for an unknown current key, got %v", err)
+	}
+
+	named := `{"current": "k1", "keys": {"k1": "Zmlyc3QgbWFzdGVyIGtleQ==", "k2": "c2Vjb25kIG1hc3RlciBrZXk="}}`
+	if err := os.WriteFile(path, []byte(named), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := NewFileKeyring(path)
+	if err != nil {
+		t.Fatalf("Expected keyring to load, got: %v", err)
+	}
+	if id, _, _ := loaded.CurrentKey(); id != "k1" {
+		t.Errorf("Expected current key k1, got %s", id)
+	}
+}
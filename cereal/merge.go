@@ -3,8 +3,26 @@ package cereal
 import (
 	"fmt"
 	"reflect"
+
+	"zbz/catalog"
 )
 
+// Register the merge tag so catalog exposes parsed rules as FieldMetadata extensions
+func init() {
+	catalog.RegisterTag("merge", parseMergeTag)
+}
+
+// parseMergeTag validates a merge:"..." tag value and returns the strategy.
+// The extension stays plain data so catalog metadata remains JSON encodable.
+func parseMergeTag(value string) (string, error) {
+	switch value {
+	case "replace", "deep", "skip", "union", "append":
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy %q", value)
+	}
+}
+
 // MergeOptions defines how merging should be performed
 type MergeOptions struct {
 	ArrayStrategy  ArrayMergeStrategy  // How to handle array fields
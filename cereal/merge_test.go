@@ -1,10 +1,14 @@
 package cereal
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
 	"time"
+
+	"zbz/catalog"
 )
 
 // Test structs for merge testing
@@ -452,4 +456,24 @@ func TestComplexNestedMerging(t *testing.T) {
 	if !reflect.DeepEqual(merged.Level1.Array, expectedArray) {
 		t.Errorf("Expected nested Array %v, got %v", expectedArray, merged.Level1.Array)
 	}
-}
\ No newline at end of file
+}
+
+func TestMergeTagMetadataIsExportable(t *testing.T) {
+	metadata := catalog.Select[Metadata]()
+	if _, err := json.Marshal(metadata); err != nil {
+		t.Fatalf("Expected metadata with merge tags to marshal, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := catalog.WriteSnapshot(&buf, catalog.Export(), catalog.SnapshotJSON); err != nil {
+		t.Fatalf("Expected a snapshot with merge tags to be written, got %v", err)
+	}
+
+	for _, field := range metadata.Fields {
+		if field.Name != "Tags" {
+			continue
+		}
+		if strategy, ok := catalog.Extension[string](field, "merge"); !ok || strategy != "append" {
+			t.Errorf("Expected merge strategy 'append', got %q", strategy)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package cereal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MessageCatalog supplies validation messages per locale. Message returns
+// false when it has no message for the error.
+type MessageCatalog interface {
+	Message(locale string, err ValidationError) (string, bool)
+}
+
+// TemplateCatalog is a MessageCatalog of templates keyed by locale and
+// validation tag. Templates may use {field}, {param} and {value}. Lookups
+// fall back from a regional locale to its language ("pt-BR" to "pt"), then
+// to English.
+type TemplateCatalog struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]string
+}
+
+// NewTemplateCatalog creates an empty template catalog
+func NewTemplateCatalog() *TemplateCatalog {
+	return &TemplateCatalog{templates: make(map[string]map[string]string)}
+}
+
+// Register adds templates for a locale, replacing existing ones for the same tags
+func (c *TemplateCatalog) Register(locale string, templates map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locale = normalizeLocale(locale)
+	if c.templates[locale] == nil {
+		c.templates[locale] = make(map[string]string)
+	}
+	for tag, template := range templates {
+		c.templates[locale][tag] = template
+	}
+}
+
+// Message renders the template for the error's tag in locale
+func (c *TemplateCatalog) Message(locale string, err ValidationError) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, candidate := range localeFallbacks(locale) {
+		if template, exists := c.templates[candidate][err.Tag]; exists {
+			return strings.NewReplacer(
+				"{field}", err.Field,
+				"{param}", err.Param,
+				"{value}", err.Value,
+			).Replace(template), true
+		}
+	}
+	return "", false
+}
+
+// normalizeLocale lowercases a locale and uses - as the region separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// localeFallbacks lists the locales to try for a requested one, most specific first
+func localeFallbacks(locale string) []string {
+	locale = normalizeLocale(locale)
+	fallbacks := []string{}
+	if locale != "" {
+		fallbacks = append(fallbacks, locale)
+		if language, _, regional := strings.Cut(locale, "-"); regional {
+			fallbacks = append(fallbacks, language)
+		}
+	}
+	return append(fallbacks, "en")
+}
+
+// englishMessages are the built-in messages
+var englishMessages = map[string]string{
+	"required":         "field is required",
+	"email":            "must be a valid email address",
+	"min":              "must be at least {param} characters long",
+	"max":              "must be at most {param} characters long",
+	"len":              "must be exactly {param} characters long",
+	"gt":               "must be greater than {param}",
+	"gte":              "must be greater than or equal to {param}",
+	"lt":               "must be less than {param}",
+	"lte":              "must be less than or equal to {param}",
+	"oneof":            "must be one of: {param}",
+	"url":              "must be a valid URL",
+	"uri":              "must be a valid URI",
+	"alpha":            "must contain only alphabetic characters",
+	"alphanum":         "must contain only alphanumeric characters",
+	"numeric":          "must be a valid number",
+	"uuid":             "must be a valid UUID",
+	"json":             "must be valid JSON",
+	"required_if":      "is required when {param}",
+	"required_unless":  "is required unless {param}",
+	"required_with":    "is required when {param} is present",
+	"required_without": "is required when {param} is missing",
+	"excluded_if":      "must be empty when {param}",
+	"excluded_unless":  "must be empty unless {param}",
+	"excluded_with":    "must be empty when {param} is present",
+	"excluded_without": "must be empty when {param} is missing",
+}
+
+var (
+	builtinMessages = NewTemplateCatalog()
+	messageCatalog  MessageCatalog
+	messageMu       sync.RWMutex
+)
+
+func init() {
+	builtinMessages.Register("en", englishMessages)
+}
+
+// RegisterMessages adds message templates for a locale to the built-in catalog
+//
+//	cereal.RegisterMessages("fr", map[string]string{
+//	    "required": "champ obligatoire",
+//	    "min":      "doit contenir au moins {param} caractères",
+//	})
+func RegisterMessages(locale string, templates map[string]string) {
+	builtinMessages.Register(locale, templates)
+}
+
+// SetMessageCatalog installs a catalog consulted before the built-in
+// messages, e.g. one backed by a translation service. nil removes it.
+func SetMessageCatalog(catalog MessageCatalog) {
+	messageMu.Lock()
+	defer messageMu.Unlock()
+	messageCatalog = catalog
+}
+
+// Message returns the message for a validation error in locale
+func Message(locale string, err ValidationError) string {
+	messageMu.RLock()
+	custom := messageCatalog
+	messageMu.RUnlock()
+
+	if custom != nil {
+		if message, ok := custom.Message(locale, err); ok {
+			return message
+		}
+	}
+	if message, ok := builtinMessages.Message(locale, err); ok {
+		return message
+	}
+	return fmt.Sprintf("validation failed for tag '%s'", err.Tag)
+}
@@ -0,0 +1,267 @@
+package cereal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrSchemaVersion is returned when a payload's schema version can't be
+// read or brought up to the current version
+var ErrSchemaVersion = errors.New("unsupported schema version")
+
+// Upcaster moves a decoded document one schema version forward, from N to
+// N+1. It may change the document in place or return a new one; the version
+// field is updated for it.
+type Upcaster func(document map[string]any) (map[string]any, error)
+
+// Schema describes how a type's payloads are versioned
+type Schema struct {
+	VersionField string // Document field holding the version, e.g. "apiVersion" or "_v"
+	Version      int    // Current version, stamped on marshal
+	Format       string // fmt pattern for string versions, e.g. "config.zbz/v%d"; empty writes a number
+	Missing      int    // Version assumed when the field is absent; 0 means 1
+}
+
+// schemaRegistration is a type's schema and its upcasters keyed by source version
+type schemaRegistration struct {
+	schema    Schema
+	upcasters map[int]Upcaster
+}
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[reflect.Type]*schemaRegistration)
+)
+
+// RegisterSchema declares the current schema version of T and where
+// payloads carry it. Marshal stamps the version; Unmarshal runs the
+// registered upcasters on older payloads before decoding.
+//
+//	cereal.RegisterSchema[Config](cereal.Schema{VersionField: "apiVersion", Version: 3})
+//	cereal.RegisterUpcaster[Config](1, renameHostToAddress)
+//	cereal.RegisterUpcaster[Config](2, splitAddressPort)
+func RegisterSchema[T any](schema Schema) error {
+	if schema.VersionField == "" {
+		return fmt.Errorf("schema version field cannot be empty")
+	}
+	if schema.Version < 1 {
+		return fmt.Errorf("schema version must be at least 1, got %d", schema.Version)
+	}
+	if schema.Format != "" && !strings.Contains(schema.Format, "%d") {
+		return fmt.Errorf("schema version format %q must contain %%d", schema.Format)
+	}
+	if schema.Missing == 0 {
+		schema.Missing = 1
+	}
+
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	registration := schemaRegistrationFor(schemaType[T]())
+	registration.schema = schema
+	return nil
+}
+
+// RegisterUpcaster registers the step that moves T payloads from version
+// from to from+1
+func RegisterUpcaster[T any](from int, upcast Upcaster) error {
+	if from < 1 {
+		return fmt.Errorf("upcaster source version must be at least 1, got %d", from)
+	}
+	if upcast == nil {
+		return fmt.Errorf("upcaster cannot be nil")
+	}
+
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	t := schemaType[T]()
+	registration := schemaRegistrationFor(t)
+	if _, exists := registration.upcasters[from]; exists {
+		return fmt.Errorf("upcaster for %s v%d is already registered", t, from)
+	}
+	registration.upcasters[from] = upcast
+	return nil
+}
+
+// Migrate brings a decoded T document up to the current schema version. It
+// is what Unmarshal does before decoding, for documents read some other way.
+func Migrate[T any](document map[string]any) (map[string]any, error) {
+	registration := lookupSchema(schemaType[T]())
+	if registration == nil {
+		return document, nil
+	}
+	migrated, _, err := registration.migrate(document)
+	return migrated, err
+}
+
+// schemaRegistrationFor returns t's registration, creating it; callers hold schemasMu
+func schemaRegistrationFor(t reflect.Type) *schemaRegistration {
+	registration, exists := schemas[t]
+	if !exists {
+		registration = &schemaRegistration{upcasters: make(map[int]Upcaster)}
+		schemas[t] = registration
+	}
+	return registration
+}
+
+// lookupSchema returns a copy of the registration for t (or what it points
+// to) when a schema has been declared for it
+func lookupSchema(t reflect.Type) *schemaRegistration {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	registration, exists := schemas[t]
+	if !exists || registration.schema.VersionField == "" {
+		return nil
+	}
+	snapshot := &schemaRegistration{schema: registration.schema, upcasters: make(map[int]Upcaster, len(registration.upcasters))}
+	for from, upcast := range registration.upcasters {
+		snapshot.upcasters[from] = upcast
+	}
+	return snapshot
+}
+
+func schemaType[T any]() reflect.Type {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// migrate runs the upcasters from the document's version to the current
+// one; changed is false when the document was already current
+func (r *schemaRegistration) migrate(document map[string]any) (map[string]any, bool, error) {
+	version, err := r.version(document)
+	if err != nil {
+		return nil, false, err
+	}
+	if version > r.schema.Version {
+		return nil, false, fmt.Errorf("%w: payload is v%d, newest known is v%d", ErrSchemaVersion, version, r.schema.Version)
+	}
+
+	changed := false
+	for ; version < r.schema.Version; version++ {
+		upcast, exists := r.upcasters[version]
+		if !exists {
+			return nil, false, fmt.Errorf("%w: no upcaster from v%d to v%d", ErrSchemaVersion, version, version+1)
+		}
+		document, err = upcast(document)
+		if err != nil {
+			return nil, false, fmt.Errorf("upcast v%d to v%d: %w", version, version+1, err)
+		}
+		if document == nil {
+			return nil, false, fmt.Errorf("upcast v%d to v%d returned no document", version, version+1)
+		}
+		document[r.schema.VersionField] = r.stamp(version + 1)
+		changed = true
+	}
+	return document, changed, nil
+}
+
+// version reads the schema version from a document: a number, or a string
+// matching the schema's format, a bare number or a trailing "vN"
+func (r *schemaRegistration) version(document map[string]any) (int, error) {
+	raw, exists := document[r.schema.VersionField]
+	if !exists || raw == nil {
+		return r.schema.Missing, nil
+	}
+
+	switch value := raw.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return int(n), nil
+		}
+	case float64:
+		if value == float64(int(value)) {
+			return int(value), nil
+		}
+	case string:
+		if n, ok := parseVersionString(value, r.schema.Format); ok {
+			return n, nil
+		}
+	default:
+		if v := reflect.ValueOf(raw); v.CanInt() {
+			return int(v.Int()), nil
+		} else if v.CanUint() {
+			return int(v.Uint()), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: can't read %s %v", ErrSchemaVersion, r.schema.VersionField, raw)
+}
+
+func parseVersionString(value, format string) (int, bool) {
+	var n int
+	if format != "" {
+		if _, err := fmt.Sscanf(value, format, &n); err == nil {
+			return n, true
+		}
+	}
+	if i := strings.LastIndex(value, "v"); i >= 0 {
+		value = value[i+1:]
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// stamp is the value written to the version field for version
+func (r *schemaRegistration) stamp(version int) any {
+	if r.schema.Format != "" {
+		return fmt.Sprintf(r.schema.Format, version)
+	}
+	return version
+}
+
+// upcastPayload migrates an encoded payload for v's type when it is older
+// than the type's schema, returning the payload to decode
+func upcastPayload(data []byte, v any, format Format) ([]byte, error) {
+	registration := lookupSchema(reflect.TypeOf(v))
+	if registration == nil {
+		return data, nil
+	}
+
+	tree, err := decodeTree(format, data)
+	if err != nil {
+		return nil, err
+	}
+	document, ok := tree.(map[string]any)
+	if !ok {
+		return data, nil // Not a document; the decoder reports the mismatch
+	}
+
+	migrated, changed, err := registration.migrate(document)
+	if err != nil || !changed {
+		return data, err
+	}
+	return format.Encode(migrated)
+}
+
+// stampVersion writes the current schema version of v's type into an
+// encoded payload
+func stampVersion(data []byte, v any, format Format) ([]byte, error) {
+	registration := lookupSchema(reflect.TypeOf(v))
+	if registration == nil {
+		return data, nil
+	}
+
+	tree, err := decodeTree(format, data)
+	if err != nil {
+		return nil, err
+	}
+	document, ok := tree.(map[string]any)
+	if !ok {
+		return data, nil
+	}
+	document[registration.schema.VersionField] = registration.stamp(registration.schema.Version)
+	return format.Encode(document)
+}
@@ -0,0 +1,162 @@
+package cereal
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// v1 had "host"; v2 renamed it to "address"; v3 split out "port"
+type migratedConfig struct {
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Address    string `json:"address" yaml:"address"`
+	Port       int    `json:"port" yaml:"port"`
+}
+
+type migratedEvent struct {
+	Name string `json:"name"`
+}
+
+func init() {
+	RegisterSchema[migratedConfig](Schema{VersionField: "apiVersion", Version: 3, Format: "config.zbz/v%d"})
+	RegisterUpcaster[migratedConfig](1, func(doc map[string]any) (map[string]any, error) {
+		doc["address"] = doc["host"]
+		delete(doc, "host")
+		return doc, nil
+	})
+	RegisterUpcaster[migratedConfig](2, func(doc map[string]any) (map[string]any, error) {
+		address, _ := doc["address"].(string)
+		host, port, found := strings.Cut(address, ":")
+		if !found {
+			return nil, errors.New("address has no port")
+		}
+		doc["address"] = host
+		doc["port"], _ = strconv.Atoi(port)
+		return doc, nil
+	})
+
+	RegisterSchema[migratedEvent](Schema{VersionField: "_v", Version: 2})
+}
+
+func TestUnmarshal_Upcasts(t *testing.T) {
+	payloads := map[string]struct {
+		format Serializer
+		data   string
+	}{
+		"json v1":      {JSON, `{"host":"db:5432"}`},
+		"json v2":      {JSON, `{"apiVersion":"config.zbz/v2","address":"db:5432"}`},
+		"yaml v1":      {YAML, "apiVersion: config.zbz/v1\nhost: db:5432\n"},
+		"json current": {JSON, `{"apiVersion":"config.zbz/v3","address":"db","port":5432}`},
+	}
+
+	for name, payload := range payloads {
+		t.Run(name, func(t *testing.T) {
+			var config migratedConfig
+			if err := payload.format.Unmarshal([]byte(payload.data), &config); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			expected := migratedConfig{APIVersion: "config.zbz/v3", Address: "db", Port: 5432}
+			if config != expected {
+				t.Errorf("Expected %+v, got %+v", expected, config)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_UpcastErrors(t *testing.T) {
+	var config migratedConfig
+	err := JSON.Unmarshal([]byte(`{"apiVersion":"config.zbz/v4"}`), &config)
+	if !errors.Is(err, ErrSchemaVersion) {
+		t.Errorf("Expected ErrSchemaVersion for a newer payload, got: %v", err)
+	}
+
+	err = JSON.Unmarshal([]byte(`{"host":"db"}`), &config)
+	if err == nil || !strings.Contains(err.Error(), "upcast v2 to v3") {
+		t.Errorf("Expected the failing upcaster to be reported, got: %v", err)
+	}
+
+	var event migratedEvent
+	err = JSON.Unmarshal([]byte(`{"name":"signup"}`), &event)
+	if !errors.Is(err, ErrSchemaVersion) {
+		t.Errorf("Expected ErrSchemaVersion without an upcaster, got: %v", err)
+	}
+}
+
+func TestMarshal_StampsVersion(t *testing.T) {
+	data, err := JSON.Marshal(migratedConfig{Address: "db", Port: 5432})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(string(data), `"apiVersion":"config.zbz/v3"`) {
+		t.Errorf("Expected version to be stamped, got %s", data)
+	}
+
+	for _, format := range []Serializer{JSON, YAML, TOML, MSGPACK, CBOR} {
+		data, err := format.Marshal(&migratedEvent{Name: "signup"})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		var decoded migratedEvent
+		if err := format.Unmarshal(data, &decoded); err != nil || decoded.Name != "signup" {
+			t.Errorf("Expected stamped payload to round trip, got %+v (%v)", decoded, err)
+		}
+	}
+}
+
+func TestStream_Upcasts(t *testing.T) {
+	input := `{"host":"a:1"}
+{"host":"b"}
+{"apiVersion":"config.zbz/v3","address":"c","port":3}
+`
+	decoder := NewDecoder[migratedConfig](strings.NewReader(input))
+	var addresses []string
+	failures := 0
+	for {
+		var config migratedConfig
+		err := decoder.Decode(&config)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			failures++
+			continue
+		}
+		addresses = append(addresses, config.Address)
+	}
+	if strings.Join(addresses, ",") != "a,c" || failures != 1 {
+		t.Errorf("Expected the bad record to be skipped, got %v with %d failures", addresses, failures)
+	}
+
+	var buf bytes.Buffer
+	encoder := NewEncoder[migratedConfig](&buf)
+	encoder.SetFraming(NDJSON)
+	encoder.Encode(migratedConfig{Address: "a", Port: 1})
+	if !strings.Contains(buf.String(), `"apiVersion":"config.zbz/v3"`) {
+		t.Errorf("Expected streamed records to be stamped, got %s", buf.String())
+	}
+}
+
+func TestRegisterSchema_Errors(t *testing.T) {
+	if err := RegisterSchema[migratedEvent](Schema{VersionField: "_v"}); err == nil {
+		t.Error("Expected error for a zero version")
+	}
+	if err := RegisterSchema[migratedEvent](Schema{VersionField: "_v", Version: 2, Format: "v"}); err == nil {
+		t.Error("Expected error for a format without a verb")
+	}
+	if err := RegisterUpcaster[migratedConfig](1, func(doc map[string]any) (map[string]any, error) { return doc, nil }); err == nil {
+		t.Error("Expected error for a duplicate upcaster")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	doc, err := Migrate[migratedConfig](map[string]any{"apiVersion": 1.0, "host": "db:80"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if doc["apiVersion"] != "config.zbz/v3" || doc["address"] != "db" || doc["port"] != 80 {
+		t.Errorf("Expected migrated document, got %v", doc)
+	}
+}
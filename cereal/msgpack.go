@@ -0,0 +1,44 @@
+package cereal
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackFormat encodes MessagePack. Fields are keyed by their msgpack tag,
+// falling back to the json tag, so existing models need no extra tags.
+var msgpackFormat = &funcFormat{
+	name:   "msgpack",
+	encode: msgpackMarshal,
+	decode: msgpackUnmarshal,
+}
+
+// Register MessagePack so it can be selected by extension or content type
+func init() {
+	mustRegisterFormat(FormatInfo{
+		Name:         "msgpack",
+		ContentTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+		Extensions:   []string{".msgpack", ".mpk"},
+		Binary:       true,
+		Serializer:   MSGPACK,
+	})
+}
+
+// msgpackMarshal encodes v keying struct fields like the other formats
+func msgpackMarshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// msgpackUnmarshal decodes data into v keying struct fields like the other formats
+func msgpackUnmarshal(data []byte, v any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
@@ -0,0 +1,253 @@
+package cereal
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NegotiationError reports that a request's Accept or Content-Type header
+// names no registered format. Status is 406 or 415.
+type NegotiationError struct {
+	Status    int      // http.StatusNotAcceptable or http.StatusUnsupportedMediaType
+	Header    string   // "Accept" or "Content-Type"
+	Value     string   // Header value as received
+	Supported []string // Canonical content types of the registered formats
+}
+
+// Error implements error
+func (e *NegotiationError) Error() string {
+	return fmt.Sprintf("%s: %s %q (supported: %s)",
+		strings.ToLower(http.StatusText(e.Status)), e.Header, e.Value, strings.Join(e.Supported, ", "))
+}
+
+// StatusCode returns the HTTP status a handler should respond with
+func (e *NegotiationError) StatusCode() int {
+	return e.Status
+}
+
+// defaultFormat is used when the client expresses no preference
+const defaultFormat = "json"
+
+// acceptRange is one entry of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+	order     int
+}
+
+// specificity ranks exact types over type/* over */*
+func (a acceptRange) specificity() int {
+	switch {
+	case a.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(a.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// Negotiate picks the response format from the request's Accept header,
+// honoring q-values. A missing Accept header, or */*, selects JSON.
+// Structured syntax suffixes such as application/vnd.api+json match the
+// format named by the suffix.
+func Negotiate(r *http.Request) (FormatInfo, error) {
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		info, _ := FormatByName(defaultFormat)
+		return info, nil
+	}
+
+	ranges := parseAccept(header)
+
+	// q=0 explicitly refuses a type, even when a wildcard would match it
+	refused := make(map[string]bool)
+	for _, accept := range ranges {
+		if accept.q == 0 {
+			if info, found := matchMediaType(accept.mediaType, nil); found {
+				refused[info.Name] = true
+			}
+		}
+	}
+
+	for _, accept := range ranges {
+		if accept.q == 0 {
+			continue
+		}
+		if info, found := matchMediaType(accept.mediaType, refused); found {
+			return info, nil
+		}
+	}
+
+	return FormatInfo{}, &NegotiationError{
+		Status:    http.StatusNotAcceptable,
+		Header:    "Accept",
+		Value:     header,
+		Supported: supportedContentTypes(),
+	}
+}
+
+// Decode reads the request body into v with the format named by the
+// Content-Type header, applying the same scoping and validation as Unmarshal.
+// A missing Content-Type is treated as JSON. The request context's identity
+// and purpose are passed on to the access audit.
+func Decode(r *http.Request, v any, permissions ...string) error {
+	info, err := requestFormat(r)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("read request body: %w", err)
+	}
+	if serializer, ok := info.Serializer.(ContextSerializer); ok {
+		return serializer.UnmarshalContext(r.Context(), body, v, permissions...)
+	}
+	return info.Serializer.Unmarshal(body, v, permissions...)
+}
+
+// Encode negotiates the response format, marshals v with scoping and writes
+// it with the given status. Nothing is written when negotiation or
+// marshaling fails, so the caller can still send an error response.
+func Encode(w http.ResponseWriter, r *http.Request, status int, v any, permissions ...string) error {
+	info, err := Negotiate(r)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if serializer, ok := info.Serializer.(ContextSerializer); ok {
+		data, err = serializer.MarshalContext(r.Context(), v, permissions...)
+	} else {
+		data, err = info.Serializer.Marshal(v, permissions...)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", info.ContentType())
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
+
+// requestFormat resolves the format of a request body
+func requestFormat(r *http.Request) (FormatInfo, error) {
+	contentType := r.Header.Get("Content-Type")
+	if strings.TrimSpace(contentType) == "" {
+		info, _ := FormatByName(defaultFormat)
+		return info, nil
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if info, found := matchMediaType(mediaType, nil); found && !strings.Contains(mediaType, "*") {
+			return info, nil
+		}
+	}
+
+	return FormatInfo{}, &NegotiationError{
+		Status:    http.StatusUnsupportedMediaType,
+		Header:    "Content-Type",
+		Value:     contentType,
+		Supported: supportedContentTypes(),
+	}
+}
+
+// parseAccept splits an Accept header into ranges ordered by preference:
+// q-value, then specificity, then position
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for i, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, exists := params["q"]; exists {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			q = parsed
+		}
+
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q, order: i})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		if ranges[i].specificity() != ranges[j].specificity() {
+			return ranges[i].specificity() > ranges[j].specificity()
+		}
+		return ranges[i].order < ranges[j].order
+	})
+	return ranges
+}
+
+// matchMediaType finds a registered format for a media range, skipping refused formats
+func matchMediaType(mediaType string, refused map[string]bool) (FormatInfo, bool) {
+	allowed := func(info FormatInfo) bool { return !refused[info.Name] }
+
+	switch {
+	case mediaType == "*/*":
+		if info, found := FormatByName(defaultFormat); found && allowed(info) {
+			return info, true
+		}
+		for _, info := range Formats() {
+			// Formats without a media type can't be served over HTTP
+			if len(info.ContentTypes) > 0 && allowed(info) {
+				return info, true
+			}
+		}
+		return FormatInfo{}, false
+
+	case strings.HasSuffix(mediaType, "/*"):
+		prefix := strings.TrimSuffix(mediaType, "*")
+		// Prefer the default format when the range covers it
+		candidates := Formats()
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Name == defaultFormat })
+		for _, info := range candidates {
+			for _, contentType := range info.ContentTypes {
+				if strings.HasPrefix(contentType, prefix) && allowed(info) {
+					return info, true
+				}
+			}
+		}
+		return FormatInfo{}, false
+	}
+
+	if info, found := FormatByContentType(mediaType); found && allowed(info) {
+		return info, true
+	}
+
+	// Structured syntax suffix, e.g. application/problem+json
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if info, found := FormatByName(mediaType[i+1:]); found && allowed(info) {
+			return info, true
+		}
+	}
+	return FormatInfo{}, false
+}
+
+// supportedContentTypes lists the canonical content type of every format
+func supportedContentTypes() []string {
+	var types []string
+	for _, info := range Formats() {
+		types = append(types, info.ContentType())
+	}
+	return types
+}
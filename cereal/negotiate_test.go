@@ -0,0 +1,111 @@
+package cereal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate_Accept(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "json"},
+		{"*/*", "json"},
+		{"application/yaml", "yaml"},
+		{"text/html, application/cbor;q=0.9, */*;q=0.1", "cbor"},
+		{"application/json;q=0.5, application/x-yaml", "yaml"},
+		{"application/json;q=0.8, application/toml;q=0.9", "toml"},
+		{"application/*;q=0.9, application/msgpack", "msgpack"},
+		{"application/json;q=0, */*", "cbor"},
+		{"application/problem+json", "json"},
+		{"text/*", "json"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		info, err := Negotiate(r)
+		if err != nil {
+			t.Errorf("Accept %q: expected no error, got: %v", tt.accept, err)
+			continue
+		}
+		if info.Name != tt.want {
+			t.Errorf("Accept %q: expected %s, got %s", tt.accept, tt.want, info.Name)
+		}
+	}
+}
+
+func TestNegotiate_NotAcceptable(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/html, image/png;q=0.5")
+
+	_, err := Negotiate(r)
+	var negotiationErr *NegotiationError
+	if !errors.As(err, &negotiationErr) {
+		t.Fatalf("Expected *NegotiationError, got %v", err)
+	}
+	if negotiationErr.StatusCode() != http.StatusNotAcceptable {
+		t.Errorf("Expected 406, got %d", negotiationErr.StatusCode())
+	}
+}
+
+func TestDecode_ContentType(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name" yaml:"name" validate:"required"`
+		Level string `json:"level" yaml:"level" scope:"admin"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name: widget\nlevel: high\n"))
+	r.Header.Set("Content-Type", "application/yaml; charset=utf-8")
+
+	var v payload
+	if err := Decode(r, &v, "user"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if v.Name != "widget" || v.Level != "" {
+		t.Errorf("Expected scoped YAML decode, got %+v", v)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"level":"x"}`))
+	var missing payload
+	if err := Decode(r, &missing); err == nil {
+		t.Error("Expected validation error for missing name")
+	}
+}
+
+func TestDecode_UnsupportedMediaType(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a=b"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var v map[string]any
+	err := Decode(r, &v)
+	var negotiationErr *NegotiationError
+	if !errors.As(err, &negotiationErr) || negotiationErr.StatusCode() != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected 415 NegotiationError, got %v", err)
+	}
+}
+
+func TestEncode_WritesNegotiatedFormat(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/yaml")
+	w := httptest.NewRecorder()
+
+	if err := Encode(w, r, http.StatusCreated, map[string]string{"status": "ok"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected 201, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/yaml" {
+		t.Errorf("Expected yaml content type, got %s", w.Header().Get("Content-Type"))
+	}
+	if strings.TrimSpace(w.Body.String()) != "status: ok" {
+		t.Errorf("Expected yaml body, got %q", w.Body.String())
+	}
+}
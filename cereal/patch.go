@@ -0,0 +1,733 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"zbz/catalog"
+)
+
+// ErrFieldNotWritable is returned when a patch changes a field the caller's
+// permissions do not allow them to write
+var ErrFieldNotWritable = errors.New("field is not writable")
+
+// ErrFieldNotReadable is returned when a patch copies, moves or tests a value
+// the caller's permissions do not allow them to read
+var ErrFieldNotReadable = errors.New("field is not readable")
+
+// PatchError reports a patch that could not be applied: the operation, the
+// JSON pointer it targeted and why it failed
+type PatchError struct {
+	Op   string // RFC 6902 operation, or "merge" for RFC 7396 merge patches
+	Path string // JSON pointer into the document
+	Err  error
+}
+
+// Error implements error
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch %s %s: %v", e.Op, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// PatchOperation is one operation of an RFC 6902 JSON Patch document
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// DefaultPatchOptions are the merge options patches use: the defaults, except
+// that a patch may clear a field
+func DefaultPatchOptions() MergeOptions {
+	options := DefaultMergeOptions()
+	options.NilStrategy = NilAllowOverride
+	return options
+}
+
+// ApplyMergePatch applies an RFC 7396 merge patch to current and returns the
+// result with the JSON pointers of the fields that changed
+func ApplyMergePatch[T any](current T, patch []byte, permissions ...string) (T, []string, error) {
+	return ApplyMergePatchWithOptions(current, patch, DefaultPatchOptions(), permissions...)
+}
+
+// ApplyMergePatchWithOptions applies an RFC 7396 merge patch using custom merge options
+func ApplyMergePatchWithOptions[T any](current T, patch []byte, options MergeOptions, permissions ...string) (T, []string, error) {
+	document, err := decodeTree(jsonFormat, patch)
+	if err != nil {
+		return current, nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	// A merge patch replaces every value it names as a whole
+	written := func(string) bool { return true }
+	return applyPatch(current, "merge", options, permissions, written, func(target any, _ func(string) bool) (any, error) {
+		return mergePatch(target, document), nil
+	})
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to current and returns the
+// result with the JSON pointers of the fields that changed
+func ApplyJSONPatch[T any](current T, patch []byte, permissions ...string) (T, []string, error) {
+	return ApplyJSONPatchWithOptions(current, patch, DefaultPatchOptions(), permissions...)
+}
+
+// ApplyJSONPatchWithOptions applies an RFC 6902 JSON Patch using custom merge options
+func ApplyJSONPatchWithOptions[T any](current T, patch []byte, options MergeOptions, permissions ...string) (T, []string, error) {
+	var operations []PatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return current, nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+	return applyPatch(current, "json-patch", options, permissions, writtenBy(operations), func(target any, readable func(string) bool) (any, error) {
+		for _, operation := range operations {
+			source := operation.From
+			if operation.Op == "test" {
+				source = operation.Path
+			} else if operation.Op != "copy" && operation.Op != "move" {
+				continue
+			}
+			if !readable(source) {
+				return nil, &PatchError{Op: operation.Op, Path: source, Err: ErrFieldNotReadable}
+			}
+		}
+		return applyOperations(target, operations)
+	})
+}
+
+// ApplyUpdate replaces current with updated, such as a PUT body, as the caller
+// sees it: values hidden from the caller keep their current value and
+// everything else is taken from updated as is, without merge rules. Write
+// scopes and validation apply as they do for a patch.
+func ApplyUpdate[T any](current T, updated T, permissions ...string) (T, []string, error) {
+	scoped, _, err := catalogScoper.scope(updated, "json", permissions, nil)
+	if err != nil {
+		return current, nil, err
+	}
+	encoded, err := jsonFormat.Encode(scoped)
+	if err != nil {
+		return current, nil, err
+	}
+	document, err := decodeTree(jsonFormat, encoded)
+	if err != nil {
+		return current, nil, err
+	}
+	written := func(string) bool { return false }
+	return applyPatch(current, "update", DefaultPatchOptions(), permissions, written, func(target any, readable func(string) bool) (any, error) {
+		return keepHidden(target, document, "", readable), nil
+	})
+}
+
+// keepHidden replaces a decoded JSON document with another, except for the
+// values under pointers the caller cannot read, which keep their shown form
+func keepHidden(shown, updated any, pointer string, readable func(string) bool) any {
+	if readable(pointer) {
+		return updated
+	}
+	switch shownNode := shown.(type) {
+	case map[string]any:
+		updatedObject, ok := updated.(map[string]any)
+		if !ok {
+			return shown
+		}
+		result := make(map[string]any, len(updatedObject))
+		for key, value := range updatedObject {
+			result[key] = keepHidden(shownNode[key], value, pointer+"/"+escapePointerToken(key), readable)
+		}
+		for key, value := range shownNode {
+			if _, sent := updatedObject[key]; !sent && !readable(pointer+"/"+escapePointerToken(key)) {
+				result[key] = value
+			}
+		}
+		return result
+	case []any:
+		updatedArray, ok := updated.([]any)
+		if !ok {
+			return shown
+		}
+		result := make([]any, len(updatedArray))
+		for i, value := range updatedArray {
+			if i < len(shownNode) {
+				value = keepHidden(shownNode[i], value, pointer+"/"+strconv.Itoa(i), readable)
+			}
+			result[i] = value
+		}
+		return result
+	}
+	return shown
+}
+
+// writtenBy reports whether a JSON Patch replaces the value at a pointer as a
+// whole, rather than editing inside it (e.g. appending with /tags/-)
+func writtenBy(operations []PatchOperation) func(string) bool {
+	return func(pointer string) bool {
+		for _, operation := range operations {
+			targets := []string{operation.Path}
+			if operation.Op == "move" {
+				targets = append(targets, operation.From)
+			}
+			for _, target := range targets {
+				if target == "" || target == pointer || strings.HasPrefix(pointer, target+"/") {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// applyPatch runs a patch against the JSON form of current as the caller
+// sees it, so redacted fields hold their placeholders, and decodes the
+// result. Values the patch left alone are taken from current, merge rules are
+// re-applied to the fields it touched, then write scopes are checked and the
+// result validated. On error current is returned unchanged.
+func applyPatch[T any](current T, kind string, options MergeOptions, permissions []string, written func(string) bool, apply func(document any, readable func(string) bool) (any, error)) (T, []string, error) {
+	scoped, redacted, err := catalogScoper.scope(current, "json", permissions, nil)
+	if err != nil {
+		return current, nil, err
+	}
+	shown := reflect.ValueOf(scoped)
+
+	encoded, err := jsonFormat.Encode(scoped)
+	if err != nil {
+		return current, nil, err
+	}
+	document, err := decodeTree(jsonFormat, encoded)
+	if err != nil {
+		return current, nil, err
+	}
+
+	document, err = apply(document, readableBy(redacted))
+	if err != nil {
+		return current, nil, err
+	}
+
+	encoded, err = jsonFormat.Encode(document)
+	if err != nil {
+		return current, nil, err
+	}
+	var patched T
+	if err := jsonFormat.Decode(encoded, &patched); err != nil {
+		return current, nil, &PatchError{Op: kind, Err: fmt.Errorf("patched document does not fit %T: %w", current, err)}
+	}
+
+	engine := NewMergeEngine(options)
+	result := engine.patchValues(reflect.ValueOf(current), shown, reflect.ValueOf(patched), "", written).Interface().(T)
+
+	metadata := catalog.ExtractAndCacheMetadata(current)
+	walker := &diffWalker{op: kind, model: metadata.TypeName, permissions: permissions}
+	before, after := reflect.ValueOf(current), reflect.ValueOf(result)
+	changes, err := walker.diff(before, after, before, after, metadata.Fields, "", false, true)
+	if err != nil {
+		return current, nil, err
+	}
+
+	if err := Validate(result); err != nil {
+		return current, nil, fmt.Errorf("patch validation failed: %w", err)
+	}
+	return result, changes.Paths(), nil
+}
+
+// readableBy reports whether a JSON pointer names only values the caller can
+// read, given the field paths scoping redacted
+func readableBy(redacted map[string]bool) func(string) bool {
+	pointers := make([]string, 0, len(redacted))
+	for path := range redacted {
+		pointers = append(pointers, fieldPathPointer(path))
+	}
+	return func(pointer string) bool {
+		for _, hidden := range pointers {
+			if pointer == hidden || strings.HasPrefix(pointer, hidden+"/") || strings.HasPrefix(hidden, pointer+"/") {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// fieldPathPointer turns a field path as scoping reports it, such as
+// "items[0].price", into a JSON pointer
+func fieldPathPointer(path string) string {
+	var pointer strings.Builder
+	for _, segment := range strings.Split(path, ".") {
+		name, keys, _ := strings.Cut(segment, "[")
+		if name != "" {
+			pointer.WriteString("/" + escapePointerToken(name))
+		}
+		for keys != "" {
+			key, rest, _ := strings.Cut(keys, "]")
+			pointer.WriteString("/" + escapePointerToken(key))
+			keys = strings.TrimPrefix(rest, "[")
+		}
+	}
+	return pointer.String()
+}
+
+// patchValues combines a value with its patched form. base is the value the
+// patch was applied to, the caller's scoped view of current: parts of the
+// patched value still equal to it were left alone and keep their current
+// value, redacted fields included, and merge:"skip" fields cannot be patched.
+// Fields the patch wrote as a whole are merged through their FieldMergeRule
+// or merge tag, so a merge:"append" list grows by the patched items; edits
+// inside a field (a JSON Patch to /tags/-) are taken as written. Lists are
+// matched by index only while their length is unchanged.
+func (me *MergeEngine) patchValues(current, base, patched reflect.Value, pointer string, written func(string) bool) reflect.Value {
+	if sameJSON(base, patched) {
+		return current
+	}
+
+	switch current.Kind() {
+	case reflect.Pointer:
+		if current.IsNil() || base.IsNil() || patched.IsNil() {
+			return patched
+		}
+		result := reflect.New(current.Type().Elem())
+		result.Elem().Set(me.patchValues(current.Elem(), base.Elem(), patched.Elem(), pointer, written))
+		return result
+	case reflect.Slice, reflect.Array:
+		if current.Kind() == reflect.Slice && (current.IsNil() || patched.IsNil()) {
+			return patched
+		}
+		if current.Len() != patched.Len() || base.Len() != patched.Len() || !needsWalk(current.Type().Elem()) {
+			return patched
+		}
+		result := reflect.New(current.Type()).Elem()
+		if current.Kind() == reflect.Slice {
+			result.Set(reflect.MakeSlice(current.Type(), current.Len(), current.Len()))
+		}
+		for i := 0; i < current.Len(); i++ {
+			elemPointer := pointer + "/" + strconv.Itoa(i)
+			result.Index(i).Set(me.patchValues(current.Index(i), base.Index(i), patched.Index(i), elemPointer, written))
+		}
+		return result
+	case reflect.Map:
+		if current.IsNil() || patched.IsNil() || current.Type().Key().Kind() != reflect.String || !needsWalk(current.Type().Elem()) {
+			return patched
+		}
+		result := reflect.MakeMapWithSize(current.Type(), patched.Len())
+		iter := patched.MapRange()
+		for iter.Next() {
+			currentElem, baseElem := current.MapIndex(iter.Key()), base.MapIndex(iter.Key())
+			if !currentElem.IsValid() || !baseElem.IsValid() {
+				result.SetMapIndex(iter.Key(), iter.Value())
+				continue
+			}
+			elemPointer := pointer + "/" + escapePointerToken(iter.Key().String())
+			result.SetMapIndex(iter.Key(), me.patchValues(currentElem, baseElem, iter.Value(), elemPointer, written))
+		}
+		return result
+	case reflect.Struct:
+		if !hasExportedFields(current.Type()) {
+			return patched
+		}
+	default:
+		return patched
+	}
+
+	// Start from the current value so fields json never sees are kept
+	result := reflect.New(current.Type()).Elem()
+	result.Set(current)
+
+	for i := 0; i < current.NumField(); i++ {
+		field := current.Type().Field(i)
+		name, skip := formatFieldName(field, "json")
+		if skip || !field.IsExported() {
+			continue
+		}
+		currentField, baseField, patchedField := current.Field(i), base.Field(i), patched.Field(i)
+		if sameJSON(baseField, patchedField) {
+			continue
+		}
+		fieldPointer := pointer
+		if name != "" {
+			fieldPointer = pointer + "/" + escapePointerToken(name)
+		}
+
+		rule, hasRule := me.getFieldRule(field)
+		mergeTag := field.Tag.Get("merge")
+		switch {
+		case (hasRule && rule.Transform == nil && rule.Strategy == "skip") || (!hasRule && mergeTag == "skip"):
+			continue
+		case hasRule && written(fieldPointer):
+			result.Field(i).Set(me.applyFieldRule(rule, currentField, patchedField))
+		case !hasRule && mergeTag != "" && written(fieldPointer):
+			result.Field(i).Set(me.applyMergeTag(mergeTag, currentField, patchedField))
+		default:
+			result.Field(i).Set(me.patchValues(currentField, baseField, patchedField, fieldPointer, written))
+		}
+	}
+
+	return result
+}
+
+// sameJSON reports whether two values encode to the same JSON, which is how
+// a patch sees them; it ignores what JSON cannot carry, such as monotonic clocks
+func sameJSON(a, b reflect.Value) bool {
+	left, err := json.Marshal(a.Interface())
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b.Interface())
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
+
+// mergePatch applies an RFC 7396 merge patch to a decoded JSON document:
+// objects merge key by key, null removes a key, anything else replaces
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// applyOperations applies RFC 6902 operations in order to a decoded JSON document
+func applyOperations(document any, operations []PatchOperation) (any, error) {
+	for _, operation := range operations {
+		var err error
+		document, err = applyOperation(document, operation)
+		if err != nil {
+			return nil, &PatchError{Op: operation.Op, Path: operation.Path, Err: err}
+		}
+	}
+	return document, nil
+}
+
+// applyOperation applies a single RFC 6902 operation
+func applyOperation(document any, operation PatchOperation) (any, error) {
+	path, err := parsePointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%s requires a value", operation.Op)
+		}
+		value, err := decodeTree(jsonFormat, operation.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		switch operation.Op {
+		case "add":
+			return addValue(document, path, value)
+		case "replace":
+			if _, err := pointerValue(document, path); err != nil {
+				return nil, err
+			}
+			if len(path) == 0 {
+				return value, nil
+			}
+			return updateParent(document, path, func(parent any, token string) (any, error) {
+				return setChild(parent, token, value)
+			})
+		default:
+			current, err := pointerValue(document, path)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(current, value) {
+				return nil, fmt.Errorf("test failed")
+			}
+			return document, nil
+		}
+
+	case "remove":
+		document, _, err := removeValue(document, path)
+		return document, err
+
+	case "move", "copy":
+		from, err := parsePointer(operation.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		if operation.Op == "copy" {
+			value, err := pointerValue(document, from)
+			if err != nil {
+				return nil, err
+			}
+			return addValue(document, path, copyTree(value))
+		}
+		if operation.From == operation.Path {
+			return document, nil
+		}
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into itself", operation.From)
+		}
+		document, value, err := removeValue(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(document, path, value)
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", operation.Op)
+}
+
+// addValue adds value at path; array members shift right and "-" appends
+func addValue(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(document, path, func(parent any, token string) (any, error) {
+		switch container := parent.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			index, err := arrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot add to %s", jsonKind(parent))
+	})
+}
+
+// removeValue removes the value at path and returns it
+func removeValue(document any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the document root")
+	}
+	var removed any
+	document, err := updateParent(document, path, func(parent any, token string) (any, error) {
+		switch container := parent.(type) {
+		case map[string]any:
+			value, exists := container[token]
+			if !exists {
+				return nil, fmt.Errorf("path not found")
+			}
+			removed = value
+			delete(container, token)
+			return container, nil
+		case []any:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = container[index]
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove from %s", jsonKind(parent))
+	})
+	return document, removed, err
+}
+
+// updateParent walks to the container holding the last token of path, lets
+// update change it, and stores the (possibly reallocated) container back
+func updateParent(node any, path []string, update func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return update(node, path[0])
+	}
+	child, err := childValue(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := updateParent(child, path[1:], update)
+	if err != nil {
+		return nil, err
+	}
+	return setChild(node, path[0], updated)
+}
+
+// pointerValue returns the value a parsed JSON pointer refers to
+func pointerValue(document any, path []string) (any, error) {
+	node := document
+	for _, token := range path {
+		child, err := childValue(node, token)
+		if err != nil {
+			return nil, err
+		}
+		node = child
+	}
+	return node, nil
+}
+
+func childValue(node any, token string) (any, error) {
+	switch container := node.(type) {
+	case map[string]any:
+		value, exists := container[token]
+		if !exists {
+			return nil, fmt.Errorf("path not found")
+		}
+		return value, nil
+	case []any:
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		return container[index], nil
+	}
+	return nil, fmt.Errorf("path not found: %s has no members", jsonKind(node))
+}
+
+// setChild replaces an existing member of an object or array
+func setChild(node any, token string, value any) (any, error) {
+	switch container := node.(type) {
+	case map[string]any:
+		if _, exists := container[token]; !exists {
+			return nil, fmt.Errorf("path not found")
+		}
+		container[token] = value
+		return container, nil
+	case []any:
+		index, err := arrayIndex(token, len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = value
+		return container, nil
+	}
+	return nil, fmt.Errorf("path not found: %s has no members", jsonKind(node))
+}
+
+// arrayIndex parses an array index token; "-" (the end) is only valid when adding
+func arrayIndex(token string, length int, adding bool) (int, error) {
+	if token == "-" && adding {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if adding {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+	return index, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+var (
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+)
+
+// escapePointerToken escapes a member name for use in a JSON pointer
+func escapePointerToken(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+// jsonEqual compares decoded JSON values; numbers compare by value
+func jsonEqual(a, b any) bool {
+	switch left := a.(type) {
+	case map[string]any:
+		right, ok := b.(map[string]any)
+		if !ok || len(left) != len(right) {
+			return false
+		}
+		for key, value := range left {
+			other, exists := right[key]
+			if !exists || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		right, ok := b.([]any)
+		if !ok || len(left) != len(right) {
+			return false
+		}
+		for i := range left {
+			if !jsonEqual(left[i], right[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		right, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if left == right {
+			return true
+		}
+		x, errX := left.Float64()
+		y, errY := right.Float64()
+		return errX == nil && errY == nil && x == y
+	}
+	return a == b
+}
+
+// copyTree deep-copies a decoded JSON value
+func copyTree(node any) any {
+	switch container := node.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(container))
+		for key, value := range container {
+			copied[key] = copyTree(value)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(container))
+		for i, value := range container {
+			copied[i] = copyTree(value)
+		}
+		return copied
+	}
+	return node
+}
+
+// jsonKind names the JSON type of a decoded value, for errors
+func jsonKind(node any) string {
+	switch node.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number, float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", node)
+}
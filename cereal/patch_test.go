@@ -0,0 +1,299 @@
+package cereal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type patchAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+type patchAccount struct {
+	ID      int           `json:"id" merge:"skip"`
+	Name    string        `json:"name" validate:"required"`
+	Email   string        `json:"email" scope:"admin"`
+	Tags    []string      `json:"tags" merge:"union"`
+	History []string      `json:"history" merge:"append"`
+	Address *patchAddress `json:"address"`
+	Notes   string        `json:"-"`
+}
+
+func newPatchAccount() patchAccount {
+	return patchAccount{
+		ID:      7,
+		Name:    "Ada",
+		Email:   "ada@example.com",
+		Tags:    []string{"a", "b"},
+		History: []string{"created"},
+		Address: &patchAddress{City: "London", Zip: "N1"},
+		Notes:   "internal",
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	patched, changed, err := ApplyMergePatch(newPatchAccount(), []byte(`{
+		"name": "Ada L.",
+		"tags": ["b", "c"],
+		"history": ["renamed"],
+		"address": {"zip": null},
+		"id": 99
+	}`), "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if patched.Name != "Ada L." {
+		t.Errorf("Expected name to be patched, got %q", patched.Name)
+	}
+	if !reflect.DeepEqual(patched.Tags, []string{"a", "b", "c"}) {
+		t.Errorf("Expected union of tags, got %v", patched.Tags)
+	}
+	if !reflect.DeepEqual(patched.History, []string{"created", "renamed"}) {
+		t.Errorf("Expected history to be appended, got %v", patched.History)
+	}
+	if patched.Address.City != "London" || patched.Address.Zip != "" {
+		t.Errorf("Expected zip removed and city kept, got %+v", patched.Address)
+	}
+	if patched.ID != 7 {
+		t.Errorf("Expected merge:\"skip\" field to keep its value, got %d", patched.ID)
+	}
+	if patched.Notes != "internal" {
+		t.Errorf("Expected json:\"-\" field to be kept, got %q", patched.Notes)
+	}
+
+	expected := []string{"/name", "/tags", "/history", "/address/zip"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected changed fields %v, got %v", expected, changed)
+	}
+}
+
+func TestApplyMergePatch_Scopes(t *testing.T) {
+	current := newPatchAccount()
+
+	_, _, err := ApplyMergePatch(current, []byte(`{"email":"eve@example.com"}`), "user")
+	var patchErr *PatchError
+	if !errors.As(err, &patchErr) || !errors.Is(err, ErrFieldNotWritable) || patchErr.Path != "/email" {
+		t.Fatalf("Expected ErrFieldNotWritable at /email, got %v", err)
+	}
+
+	// Sending the current value back is not a change
+	if _, changed, err := ApplyMergePatch(current, []byte(`{"email":"ada@example.com"}`), "user"); err != nil || len(changed) != 0 {
+		t.Errorf("Expected unchanged scoped field to be accepted, got %v (%v)", changed, err)
+	}
+
+	patched, changed, err := ApplyMergePatch(current, []byte(`{"email":"eve@example.com"}`), "admin")
+	if err != nil || patched.Email != "eve@example.com" || !reflect.DeepEqual(changed, []string{"/email"}) {
+		t.Errorf("Expected admin to patch email, got %q %v (%v)", patched.Email, changed, err)
+	}
+}
+
+func TestApplyMergePatch_Validation(t *testing.T) {
+	current := newPatchAccount()
+	patched, _, err := ApplyMergePatch(current, []byte(`{"name":null}`))
+	if err == nil || !strings.Contains(err.Error(), "patch validation failed") {
+		t.Fatalf("Expected validation error, got %v", err)
+	}
+	if patched.Name != "Ada" {
+		t.Errorf("Expected current value back on error, got %q", patched.Name)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	patched, changed, err := ApplyJSONPatch(newPatchAccount(), []byte(`[
+		{"op": "test", "path": "/name", "value": "Ada"},
+		{"op": "replace", "path": "/name", "value": "Grace"},
+		{"op": "add", "path": "/history/-", "value": "renamed"},
+		{"op": "remove", "path": "/tags/0"},
+		{"op": "copy", "from": "/address/city", "path": "/address/zip"}
+	]`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if patched.Name != "Grace" {
+		t.Errorf("Expected name replaced, got %q", patched.Name)
+	}
+	if !reflect.DeepEqual(patched.History, []string{"created", "renamed"}) {
+		t.Errorf("Expected one appended history item, got %v", patched.History)
+	}
+	if !reflect.DeepEqual(patched.Tags, []string{"b"}) {
+		t.Errorf("Expected tag removed in place, got %v", patched.Tags)
+	}
+	if patched.Address.Zip != "London" {
+		t.Errorf("Expected copied zip, got %q", patched.Address.Zip)
+	}
+
+	expected := []string{"/name", "/tags", "/history", "/address/zip"}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("Expected changed fields %v, got %v", expected, changed)
+	}
+}
+
+func TestApplyJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		path  string
+		want  error
+	}{
+		{"failed test", `[{"op":"test","path":"/name","value":"Eve"}]`, "/name", nil},
+		{"missing member", `[{"op":"replace","path":"/nope","value":1}]`, "/nope", nil},
+		{"bad index", `[{"op":"add","path":"/tags/9","value":"x"}]`, "/tags/9", nil},
+		{"scoped field", `[{"op":"replace","path":"/email","value":"x@y.z"}]`, "/email", ErrFieldNotWritable},
+		{"scoped move", `[{"op":"move","from":"/email","path":"/name"}]`, "/email", ErrFieldNotReadable},
+		{"scoped copy", `[{"op":"copy","from":"/email","path":"/name"}]`, "/email", ErrFieldNotReadable},
+		{"scoped test", `[{"op":"test","path":"/email","value":"ada@example.com"}]`, "/email", ErrFieldNotReadable},
+		{"scoped parent copy", `[{"op":"copy","from":"","path":"/name"}]`, "", ErrFieldNotReadable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := newPatchAccount()
+			result, _, err := ApplyJSONPatch(current, []byte(tt.patch), "user")
+
+			var patchErr *PatchError
+			if !errors.As(err, &patchErr) || patchErr.Path != tt.path {
+				t.Fatalf("Expected PatchError at %s, got %v", tt.path, err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+			if !reflect.DeepEqual(result, current) {
+				t.Errorf("Expected current value back on error, got %+v", result)
+			}
+		})
+	}
+}
+
+type patchMember struct {
+	Nick    string        `json:"nick"`
+	SSN     string        `json:"ssn" scope:"admin"`
+	Friends []patchMember `json:"friends"`
+}
+
+func TestApplyJSONPatch_ScopedDocument(t *testing.T) {
+	current := patchMember{Nick: "ada", SSN: "123-45-6789", Friends: []patchMember{{Nick: "bob", SSN: "987-65-4321"}}}
+
+	patched, changed, err := ApplyJSONPatch(current, []byte(`[{"op":"replace","path":"/nick","value":"ada l."},{"op":"replace","path":"/friends/0/nick","value":"rob"}]`), "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if patched.SSN != "123-45-6789" || patched.Friends[0].SSN != "987-65-4321" {
+		t.Errorf("Expected hidden fields the patch left alone to keep their values, got %+v", patched)
+	}
+	if !reflect.DeepEqual(changed, []string{"/nick", "/friends/0/nick"}) {
+		t.Errorf("Expected only the visible fields to change, got %v", changed)
+	}
+
+	for _, patch := range []string{
+		`[{"op":"copy","from":"/ssn","path":"/nick"}]`,
+		`[{"op":"copy","from":"/friends/0","path":"/friends/-"}]`,
+		`[{"op":"test","path":"/friends/0/ssn","value":"987-65-4321"}]`,
+	} {
+		if _, _, err := ApplyJSONPatch(current, []byte(patch), "user"); !errors.Is(err, ErrFieldNotReadable) {
+			t.Errorf("Expected %s to be rejected as unreadable, got %v", patch, err)
+		}
+	}
+
+	merged, _, err := ApplyMergePatch(current, []byte(`{"nick":"grace"}`), "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if merged.Nick != "grace" || merged.SSN != "123-45-6789" {
+		t.Errorf("Expected merge patch to keep the hidden SSN, got %+v", merged)
+	}
+
+	copied, _, err := ApplyJSONPatch(current, []byte(`[{"op":"copy","from":"/ssn","path":"/nick"}]`), "admin")
+	if err != nil {
+		t.Fatalf("Expected admin to copy the SSN, got: %v", err)
+	}
+	if copied.Nick != "123-45-6789" {
+		t.Errorf("Expected copied SSN, got %q", copied.Nick)
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	current := patchMember{Nick: "ada", SSN: "123-45-6789", Friends: []patchMember{{Nick: "bob", SSN: "987-65-4321"}}}
+
+	// Write scopes zero hidden fields when a body is decoded
+	updated := patchMember{Nick: "ada l.", Friends: []patchMember{{Nick: "rob"}}}
+	result, changed, err := ApplyUpdate(current, updated, "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Nick != "ada l." || result.SSN != "123-45-6789" {
+		t.Errorf("Expected the nick replaced and the hidden SSN kept, got %+v", result)
+	}
+	if len(result.Friends) != 1 || result.Friends[0].Nick != "rob" || result.Friends[0].SSN != "987-65-4321" {
+		t.Errorf("Expected friends replaced with hidden values kept, got %+v", result.Friends)
+	}
+	if !reflect.DeepEqual(changed, []string{"/nick", "/friends/0/nick"}) {
+		t.Errorf("Expected the replaced fields to change, got %v", changed)
+	}
+
+	// Resized lists are replaced whole, so their hidden values cannot be kept
+	updated.Friends = append(updated.Friends, patchMember{Nick: "eve"})
+	if _, _, err := ApplyUpdate(current, updated, "user"); !errors.Is(err, ErrFieldNotWritable) {
+		t.Errorf("Expected a resized list with hidden values to be rejected, got %v", err)
+	}
+
+	// Fields the caller can see are replaced, not merged
+	account := newPatchAccount()
+	replaced, _, err := ApplyUpdate(account, patchAccount{ID: 7, Name: "Ada", Tags: []string{"c"}}, "user")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(replaced.Tags, []string{"c"}) || replaced.Address != nil || replaced.Email != "ada@example.com" {
+		t.Errorf("Expected visible fields replaced and the hidden email kept, got %+v", replaced)
+	}
+
+	if _, _, err := ApplyUpdate(current, patchMember{Nick: "x", SSN: "000-00-0000"}, "admin"); err != nil {
+		t.Errorf("Expected admin to replace the SSN, got: %v", err)
+	}
+}
+
+func TestFieldPathPointer(t *testing.T) {
+	tests := map[string]string{
+		"ssn":            "/ssn",
+		"friends[0].ssn": "/friends/0/ssn",
+		"grid[1][2]":     "/grid/1/2",
+		"labels.a/b":     "/labels/a~1b",
+	}
+	for path, want := range tests {
+		if got := fieldPathPointer(path); got != want {
+			t.Errorf("Expected %s for %s, got %s", want, path, got)
+		}
+	}
+}
+
+func TestApplyJSONPatch_FieldRules(t *testing.T) {
+	options := DefaultPatchOptions()
+	options.FieldRules["Name"] = FieldMergeRule{Transform: func(current, patched any) any {
+		return strings.ToUpper(patched.(string))
+	}}
+
+	patched, _, err := ApplyJSONPatchWithOptions(newPatchAccount(), []byte(`[{"op":"replace","path":"/name","value":"grace"}]`), options)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if patched.Name != "GRACE" {
+		t.Errorf("Expected field rule to transform the patched value, got %q", patched.Name)
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	tokens, err := parsePointer("/a~1b/c~0d/~01")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reflect.DeepEqual(tokens, []string{"a/b", "c~d", "~1"}) {
+		t.Errorf("Expected unescaped tokens, got %q", tokens)
+	}
+	if _, err := parsePointer("name"); err == nil {
+		t.Error("Expected pointer without leading / to be rejected")
+	}
+}
@@ -0,0 +1,169 @@
+package cereal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"zbz/catalog"
+)
+
+// Pipeline is the security pipeline every format runs through. Marshal
+// scopes, validates, encrypts and encodes; Unmarshal decodes, decrypts,
+// enforces write scopes and validates. Both run registered security
+// actions, upcast or stamp schema versions, emit events and write access
+// audits, whatever the Format.
+type Pipeline struct {
+	format            Format
+	encryptionService *EncryptionService // nil leaves encrypt-tagged fields as they are
+}
+
+// NewPipeline wraps a format in the security pipeline without field encryption
+func NewPipeline(format Format) *Pipeline {
+	return &Pipeline{format: format}
+}
+
+// NewSecurePipeline wraps a format in the security pipeline with field
+// encryption: encrypt-tagged fields are sealed on marshal and opened on
+// unmarshal
+func NewSecurePipeline(format Format, encryption *EncryptionService) *Pipeline {
+	return &Pipeline{format: format, encryptionService: encryption}
+}
+
+// Format returns the wire format the pipeline encodes with
+func (p *Pipeline) Format() Format {
+	return p.format
+}
+
+// Secure returns a pipeline for the same format that also encrypts fields
+func (p *Pipeline) Secure(encryption *EncryptionService) *Pipeline {
+	return NewSecurePipeline(p.format, encryption)
+}
+
+// Marshal serializes v with scoping (always applied)
+func (p *Pipeline) Marshal(v any, permissions ...string) ([]byte, error) {
+	return p.marshal(context.Background(), v, SecurityContext{Permissions: permissions})
+}
+
+// MarshalContext is Marshal on behalf of the identity and purpose in ctx,
+// which the access audit records
+func (p *Pipeline) MarshalContext(ctx context.Context, v any, permissions ...string) ([]byte, error) {
+	return p.marshal(ctx, v, SecurityContext{UserID: IdentityFrom(ctx), Permissions: permissions})
+}
+
+// MarshalWithContext serializes v for a full security context; its UserID
+// is the identity audits record
+func (p *Pipeline) MarshalWithContext(v any, ctx SecurityContext) ([]byte, error) {
+	return p.marshal(identityContext(ctx), v, ctx)
+}
+
+// Unmarshal deserializes data into v with scoping and validation
+func (p *Pipeline) Unmarshal(data []byte, v any, permissions ...string) error {
+	return p.unmarshal(context.Background(), data, v, SecurityContext{Permissions: permissions})
+}
+
+// UnmarshalContext is Unmarshal on behalf of the identity and purpose in
+// ctx, which the access audit records
+func (p *Pipeline) UnmarshalContext(ctx context.Context, data []byte, v any, permissions ...string) error {
+	return p.unmarshal(ctx, data, v, SecurityContext{UserID: IdentityFrom(ctx), Permissions: permissions})
+}
+
+// UnmarshalWithContext deserializes data into v for a full security context
+func (p *Pipeline) UnmarshalWithContext(data []byte, v any, ctx SecurityContext) error {
+	return p.unmarshal(identityContext(ctx), data, v, ctx)
+}
+
+// marshal is scope → validate → encrypt → encode
+func (p *Pipeline) marshal(ctx context.Context, v any, security SecurityContext) (result []byte, err error) {
+	format := p.format.Name()
+	audit := beginAudit(ctx, "marshal", format, v, security.Permissions)
+
+	// Emit marshal event for monitoring/auditing
+	defer func() {
+		if err = audit.finish(err); err != nil {
+			result = nil
+		}
+		emitMarshalEvent(ctx, modelTypeOf(v), security.Permissions, err == nil, err)
+	}()
+
+	if err = runSecurityActions(v, "marshal", security); err != nil {
+		return nil, fmt.Errorf("security action failed: %w", err)
+	}
+
+	// Values and nesting are preserved, denied fields redacted
+	filtered, redacted, err := catalogScoper.scope(v, format, security.Permissions, audit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the scoped data; redacted placeholders are not validated
+	if err = validateScoped(ctx, filtered, format, redacted); err != nil {
+		return nil, err
+	}
+
+	if p.encryptionService != nil && filtered != nil {
+		result, err = sealFields(reflect.ValueOf(filtered), p.format, security, p.encryptionService)
+	} else {
+		result, err = p.format.Encode(filtered)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Stamp the schema version for types that declare one
+	return stampVersion(result, v, p.format)
+}
+
+// unmarshal is decode → decrypt → enforce scopes → validate
+func (p *Pipeline) unmarshal(ctx context.Context, data []byte, v any, security SecurityContext) (err error) {
+	format := p.format.Name()
+	audit := beginAudit(ctx, "unmarshal", format, v, security.Permissions)
+
+	// Emit unmarshal event for monitoring/auditing
+	defer func() {
+		err = audit.finish(err)
+		emitUnmarshalEvent(ctx, modelTypeOf(v), security.Permissions, err == nil, err)
+	}()
+
+	if err = runSecurityActions(v, "unmarshal", security); err != nil {
+		return fmt.Errorf("security action failed: %w", err)
+	}
+
+	// Bring older payloads up to the current schema version before decoding
+	if data, err = upcastPayload(data, v, p.format); err != nil {
+		return err
+	}
+
+	if p.encryptionService != nil {
+		err = openFields(data, v, p.format, security, p.encryptionService)
+	} else {
+		err = p.format.Decode(data, v)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fields the caller may not write are zeroed at any depth
+	if err = catalogScoper.enforce(v, format, security.Permissions, audit); err != nil {
+		return err
+	}
+
+	return ValidateContext(ctx, v)
+}
+
+// identityContext carries a SecurityContext's user into a context for events and audits
+func identityContext(security SecurityContext) context.Context {
+	ctx := context.Background()
+	if security.UserID != "" {
+		ctx = WithIdentity(ctx, security.UserID)
+	}
+	return ctx
+}
+
+// modelTypeOf names v's type in events
+func modelTypeOf(v any) string {
+	if metadata := catalog.ExtractAndCacheMetadata(v); metadata.TypeName != "" {
+		return metadata.TypeName
+	}
+	return "unknown"
+}
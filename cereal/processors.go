@@ -1,8 +1,15 @@
 package cereal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
+	
+	"zbz/catalog"
 )
 
 // DefaultScopingProcessor applies permission-based scoping to fields
@@ -35,7 +42,7 @@ func SecurityFieldProcessor(field Field) []Field {
 		// PII requires 'pii' permission
 		if !slices.Contains(field.Permissions, "pii") {
 			redactedField := field
-			redactedField.Value = getRedactedPII(field)
+			redactedField.Value = getRedactedSensitive(field, getRedactedPII)
 			return []Field{redactedField}
 		}
 		
@@ -43,11 +50,24 @@ func SecurityFieldProcessor(field Field) []Field {
 		// Financial data requires 'financial' permission
 		if !slices.Contains(field.Permissions, "financial") {
 			redactedField := field
-			redactedField.Value = getRedactedFinancial(field)
+			redactedField.Value = getRedactedSensitive(field, getRedactedFinancial)
+			return []Field{redactedField}
+		}
+		
+	case MedicalType:
+		// Medical data requires 'medical' permission
+		if !slices.Contains(field.Permissions, "medical") {
+			redactedField := field
+			redactedField.Value = getRedactedSensitive(field, getRedactedMedical)
 			return []Field{redactedField}
 		}
 	}
 	
+	// Scope tags apply on top of the type's own permission
+	if !hasPermissionForField(field.Metadata.Scopes, field.Permissions) {
+		return DefaultScopingProcessor(field)
+	}
+	
 	// Field is permitted or not a security field - return as-is
 	return []Field{field}
 }
@@ -88,42 +108,53 @@ func satisfiesScopeGroup(scopeGroup string, userPermissions []string) bool {
 	return true // User has all required permissions
 }
 
-// getRedactedValueForField creates appropriate redacted value based on field metadata
+// getRedactedValueForField creates appropriate redacted value based on field metadata.
+// Explicit redact strategies win; otherwise the value is chosen to still satisfy
+// the field's validation rules, since scoped output is validated before encoding.
 func getRedactedValueForField(field Field) any {
 	// Use catalog redaction info if available
-	if field.Metadata.Redaction.Value != "" {
+	switch field.Metadata.Redaction.Strategy {
+	case "tokenize":
+		if token, ok := tokenizeField(field); ok {
+			return token
+		}
+		return getDefaultRedactedValue(field)
+	case "custom":
 		return field.Metadata.Redaction.Value
-	}
-	
-	// Check validation constraints for appropriate redaction
-	if field.Metadata.Validation.Required {
-		// Need validation-satisfying redaction
-		for _, rule := range field.Metadata.Validation.CustomRules {
-			switch rule {
-			case "email":
-				return "redacted@example.com"
-			case "uuid":
-				return "00000000-0000-0000-0000-000000000000"
-			case "phone":
-				return "XXX-XXX-XXXX"
-			case "ssn":
-				return "XXX-XX-XXXX"
-			case "creditcard":
-				return "0000-0000-0000-0000"
-			}
+	case "zero":
+		return nil
+	case "mask":
+		if s, ok := field.Value.(string); ok {
+			return strings.Repeat("*", utf8.RuneCountInString(s))
+		}
+		return nil
+	case "partial":
+		if s, ok := field.Value.(string); ok {
+			return maskPartial(s, field.Metadata.Redaction.Keep)
 		}
+		return nil
+	case "hash":
+		if field.Value == nil {
+			return nil
+		}
+		sum := sha256.Sum256([]byte(fmt.Sprint(field.Value)))
+		return hex.EncodeToString(sum[:])
 	}
-	
-	// Default redaction based on field type
+	if field.Metadata.Redaction.Value != "" {
+		return field.Metadata.Redaction.Value
+	}
+	return getDefaultRedactedValue(field)
+}
+
+// getDefaultRedactedValue redacts by field type when no strategy applies
+func getDefaultRedactedValue(field Field) any {
 	switch field.Type {
 	case StringType:
-		return "[REDACTED]"
-	case IntType:
-		return 0
-	case FloatType:
-		return 0.0
+		return getRedactedString(field.Metadata.Validation)
+	case IntType, FloatType:
+		return getRedactedNumber(field.Metadata.Validation)
 	case BoolType:
-		return false
+		return true // false would fail required
 	case SliceType:
 		return []any{}
 	case MapType:
@@ -133,6 +164,125 @@ func getRedactedValueForField(field Field) any {
 	}
 }
 
+// lengthPatterns are the documented placeholders for common fixed-length values
+var lengthPatterns = map[int]string{
+	11: "XXX-XX-XXXX",                          // SSN
+	12: "XXX-XXX-XXXX",                         // Phone
+	16: "0000000000000000",                     // Credit card
+	36: "00000000-0000-0000-0000-000000000000", // UUID
+}
+
+// getRedactedString picks a placeholder that passes the field's format and length rules
+func getRedactedString(validation catalog.ValidationInfo) string {
+	charset := ""
+	for _, rule := range validation.CustomRules {
+		if value, exists := getRegisteredRedactionValue(rule); exists {
+			return value
+		}
+		switch rule {
+		case "email":
+			return "redacted@example.com"
+		case "url", "uri", "http_url":
+			return "https://redacted.example.com"
+		case "uuid", "uuid4":
+			return "00000000-0000-0000-0000-000000000000"
+		case "json":
+			return `{"redacted":true}`
+		case "credit_card":
+			return lengthPatterns[16] // All zeros passes the Luhn check
+		case "numeric", "number", "alpha", "alphanum":
+			charset = rule
+		}
+	}
+	
+	if n, ok := constraintInt(validation, "len"); ok {
+		switch charset {
+		case "numeric", "number":
+			return strings.Repeat("0", n)
+		case "alpha":
+			return strings.Repeat("X", n)
+		case "alphanum":
+			if n == 0 {
+				return ""
+			}
+			return "R" + strings.Repeat("X", n-1)
+		}
+		if pattern, exists := lengthPatterns[n]; exists {
+			return pattern
+		}
+		return strings.Repeat("X", n)
+	}
+	
+	redacted := "[REDACTED]"
+	switch charset {
+	case "numeric", "number":
+		redacted = "0"
+	case "alpha":
+		redacted = "REDACTED"
+	case "alphanum":
+		redacted = "REDACTED123"
+	}
+	length := utf8.RuneCountInString(redacted)
+	if n, ok := constraintInt(validation, "min", "gte"); ok && length < n {
+		filler := "X"
+		if charset == "numeric" || charset == "number" {
+			filler = "0"
+		}
+		return redacted + strings.Repeat(filler, n-length)
+	}
+	if n, ok := constraintInt(validation, "max", "lte"); ok && length > n {
+		return strings.Repeat("X", n)
+	}
+	return redacted
+}
+
+// getRedactedNumber returns zero, raised to the field's lower bound if it has one
+func getRedactedNumber(validation catalog.ValidationInfo) any {
+	if n, ok := constraintInt(validation, "min", "gte"); ok && n > 0 {
+		return n
+	}
+	if n, ok := constraintInt(validation, "gt"); ok && n >= 0 {
+		return n + 1
+	}
+	return 0
+}
+
+// constraintInt reads the first integer constraint present among rules
+func constraintInt(validation catalog.ValidationInfo, rules ...string) (int, bool) {
+	for _, rule := range rules {
+		if value, exists := validation.Constraints[rule]; exists {
+			if n, err := strconv.Atoi(value); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// maskPartial masks all but the last keep characters
+func maskPartial(s string, keep int) string {
+	runes := []rune(s)
+	if keep >= len(runes) {
+		return s
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+// getRedactedSensitive honors a field's explicit redact strategy, e.g.
+// tokenize, before the type-specific placeholder
+func getRedactedSensitive(field Field, placeholder func(Field) any) any {
+	switch field.Metadata.Redaction.Strategy {
+	case "":
+		return placeholder(field)
+	case "tokenize":
+		if token, ok := tokenizeField(field); ok {
+			return token
+		}
+		return placeholder(field)
+	}
+	return getRedactedValueForField(field)
+}
+
 // getRedactedPII provides PII-specific redaction patterns
 func getRedactedPII(field Field) any {
 	for _, rule := range field.Metadata.Validation.CustomRules {
@@ -156,6 +306,8 @@ func getRedactedFinancial(field Field) any {
 		switch rule {
 		case "creditcard":
 			return "0000-0000-0000-0000"
+		case "credit_card":
+			return lengthPatterns[16]
 		case "bank_account":
 			return "XXXXXXXXXXXX"
 		case "routing":
@@ -165,6 +317,11 @@ func getRedactedFinancial(field Field) any {
 	return "[FINANCIAL_REDACTED]"
 }
 
+// getRedactedMedical provides the medical redaction placeholder
+func getRedactedMedical(field Field) any {
+	return "[MEDICAL_REDACTED]"
+}
+
 // init registers default processors
 func init() {
 	// Register default scoping processor for all basic types
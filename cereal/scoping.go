@@ -0,0 +1,578 @@
+package cereal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"zbz/catalog"
+)
+
+// ScopeError reports that the caller's permissions do not satisfy the scopes
+// a model requires through the ScopeProvider convention
+type ScopeError struct {
+	Model       string   // Qualified type name of the model
+	Path        string   // Field path in the request format; empty for the root model
+	Required    []string // Scopes the model requires
+	Permissions []string // Permissions the caller presented
+}
+
+// Error implements error
+func (e *ScopeError) Error() string {
+	target := e.Model
+	if e.Path != "" {
+		target = fmt.Sprintf("%s at %s", e.Model, e.Path)
+	}
+	return fmt.Sprintf("scope check failed: %s requires %s", target, strings.Join(e.Required, "+"))
+}
+
+var scopeProviderType = reflect.TypeOf((*ScopeProvider)(nil)).Elem()
+
+// scopeWalker copies a value graph, redacting every field the caller may not
+// read. The original value is never modified.
+type scopeWalker struct {
+	format      string
+	permissions []string
+	visited     map[visitKey]reflect.Value // original pointer -> copy, cuts cycles
+	model       string                     // Type name reported in field decisions
+	audit       *accessAudit               // Collects field decisions; nil only emits events
+	redacted    map[string]bool            // Paths whose values were replaced, for validation to skip
+}
+
+// visitKey identifies a pointer by address and type: a struct and its first
+// field share an address but must not share a copy
+type visitKey struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// redact records that the value at path is no longer the caller's data
+func (w *scopeWalker) redact(path string) {
+	if w.redacted == nil {
+		w.redacted = make(map[string]bool)
+	}
+	w.redacted[path] = true
+}
+
+// walk returns a scoped copy of v. root is true until the first struct is
+// reached, so a top-level model (or a top-level list of models) that fails its
+// ScopeProvider check is an error while nested ones are zeroed.
+func (w *scopeWalker) walk(v reflect.Value, fields []catalog.FieldMetadata, path string, root bool) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, nil
+		}
+		key := visitKey{addr: v.Pointer(), typ: v.Type()}
+		if copied, seen := w.visited[key]; seen {
+			return copied, nil
+		}
+		copied := reflect.New(v.Type().Elem())
+		w.visited[key] = copied
+		inner, err := w.walk(v.Elem(), fields, path, root)
+		if err != nil {
+			return v, err
+		}
+		copied.Elem().Set(inner)
+		return copied, nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		// The dynamic type is only known now, so its fields come from the catalog
+		inner, err := w.walk(v.Elem(), nil, path, root)
+		if err != nil {
+			return v, err
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(inner)
+		return copied, nil
+
+	case reflect.Struct:
+		return w.walkStruct(v, fields, path, root)
+
+	case reflect.Slice:
+		if v.IsNil() || !needsWalk(v.Type().Elem()) {
+			return v, nil
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, err := w.walk(v.Index(i), fields, fmt.Sprintf("%s[%d]", path, i), root)
+			if err != nil {
+				return v, err
+			}
+			copied.Index(i).Set(elem)
+		}
+		return copied, nil
+
+	case reflect.Array:
+		if !needsWalk(v.Type().Elem()) {
+			return v, nil
+		}
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			elem, err := w.walk(v.Index(i), fields, fmt.Sprintf("%s[%d]", path, i), root)
+			if err != nil {
+				return v, err
+			}
+			copied.Index(i).Set(elem)
+		}
+		return copied, nil
+
+	case reflect.Map:
+		if v.IsNil() || !needsWalk(v.Type().Elem()) {
+			return v, nil
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := w.walk(iter.Value(), fields, joinFieldPath(path, fmt.Sprint(iter.Key().Interface())), root)
+			if err != nil {
+				return v, err
+			}
+			copied.SetMapIndex(iter.Key(), elem)
+		}
+		return copied, nil
+	}
+
+	return v, nil
+}
+
+// walkStruct copies one struct, applying field scopes and redaction and
+// descending into nested values
+func (w *scopeWalker) walkStruct(v reflect.Value, fields []catalog.FieldMetadata, path string, root bool) (reflect.Value, error) {
+	t := v.Type()
+	if !hasExportedFields(t) {
+		return v, nil
+	}
+
+	if required, ok := requiredScopes(v); ok && !hasAllScopes(w.permissions, required) {
+		if root {
+			return v, &ScopeError{Model: qualifiedName(t), Path: path, Required: required, Permissions: w.permissions}
+		}
+		w.redact(path)
+		return reflect.Zero(t), nil
+	}
+
+	if fields == nil {
+		fields = structFields(t)
+	}
+
+	copied := reflect.New(t).Elem()
+	copied.Set(v)
+	if err := w.scopeFields(v, copied, fields, path); err != nil {
+		return v, err
+	}
+	return copied, nil
+}
+
+// scopeFields scopes each field of v into the matching field of copied
+func (w *scopeWalker) scopeFields(v, copied reflect.Value, fields []catalog.FieldMetadata, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name, skip := formatFieldName(structField, w.format)
+		if skip {
+			continue
+		}
+		fieldPath := joinFieldPath(path, name)
+		value := v.Field(i)
+
+		// Flattened embeds carry their promoted fields in the parent's list
+		if isPromoted(structField) {
+			promoted := promotedFields(fields, i)
+			if !structField.IsExported() {
+				// Unexported embeds cannot be replaced, but their exported
+				// fields (the ones encoders see) can be scoped in place
+				if value.Kind() == reflect.Struct {
+					if err := w.scopeFields(value, copied.Field(i), promoted, fieldPath); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			embedded, err := w.walk(value, promoted, fieldPath, false)
+			if err != nil {
+				return err
+			}
+			copied.Field(i).Set(embedded)
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+
+		meta, known := directField(fields, i)
+		if !known {
+			scoped, err := w.walk(value, nil, fieldPath, false)
+			if err != nil {
+				return err
+			}
+			copied.Field(i).Set(scoped)
+			continue
+		}
+
+		permitted := hasPermissionForField(meta.Scopes, w.permissions)
+		if permitted && needsWalk(structField.Type) {
+			scoped, err := w.walk(value, meta.Fields, fieldPath, false)
+			if err != nil {
+				return err
+			}
+			copied.Field(i).Set(scoped)
+			continue
+		}
+
+		// Leaves go through the registered field processors. Denied values
+		// are always redacted, whichever processor their type maps to.
+		field := Field{
+			Key:         name,
+			Type:        catalogScoper.mapCatalogFieldType(meta),
+			Value:       value.Interface(),
+			Permissions: w.permissions,
+			Metadata:    meta,
+		}
+		processed := ProcessField(field)
+		if !permitted {
+			processed = DefaultScopingProcessor(field)
+		}
+		decision := DecisionReturned
+		if !permitted || redactedBy(processed, value) {
+			decision = DecisionRedacted
+			w.redact(fieldPath)
+		}
+		w.audit.field(w.model, fieldPath, meta, decision, w.permissions)
+		if len(processed) == 0 {
+			assignValue(copied.Field(i), nil)
+			continue
+		}
+		assignValue(copied.Field(i), processed[0].Value)
+	}
+
+	return nil
+}
+
+// enforceWrites zeroes, in place, every field the caller may not write, at
+// any depth, and rejects models whose ScopeProvider requirements are not met
+func (w *scopeWalker) enforceWrites(v reflect.Value, fields []catalog.FieldMetadata, model, path string, root bool) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			// Interface contents are not addressable; decoded maps and slices are still walked
+			fields = nil
+		}
+		return w.enforceWrites(v.Elem(), fields, model, path, root)
+
+	case reflect.Slice, reflect.Array:
+		if !needsWalk(v.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := w.enforceWrites(v.Index(i), fields, model, fmt.Sprintf("%s[%d]", path, i), root); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		// Map values are not addressable; struct values are scoped by copy
+		if v.IsNil() || !needsWalk(v.Type().Elem()) {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := w.enforceWrites(elem, fields, model, joinFieldPath(path, fmt.Sprint(iter.Key().Interface())), root); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+		return nil
+
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	t := v.Type()
+	if !hasExportedFields(t) || !v.CanSet() {
+		return nil
+	}
+
+	if required, ok := requiredScopes(v); ok && !hasAllScopes(w.permissions, required) {
+		if root {
+			return &ScopeError{Model: qualifiedName(t), Path: path, Required: required, Permissions: w.permissions}
+		}
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	if fields == nil {
+		fields = structFields(t)
+	}
+	return w.enforceFields(v, fields, model, path)
+}
+
+// enforceFields applies write scopes to each field of the struct v
+func (w *scopeWalker) enforceFields(v reflect.Value, fields []catalog.FieldMetadata, model, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name, skip := formatFieldName(structField, w.format)
+		if skip {
+			continue
+		}
+		fieldPath := joinFieldPath(path, name)
+
+		if isPromoted(structField) {
+			promoted := promotedFields(fields, i)
+			if !structField.IsExported() {
+				if v.Field(i).Kind() == reflect.Struct {
+					if err := w.enforceFields(v.Field(i), promoted, model, fieldPath); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := w.enforceWrites(v.Field(i), promoted, model, fieldPath, false); err != nil {
+				return err
+			}
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+
+		meta, known := directField(fields, i)
+		if known {
+			granted := hasPermissionForField(meta.Scopes, w.permissions)
+			switch {
+			case !granted && !v.Field(i).IsZero():
+				// Only values the caller actually sent count as rejected
+				w.audit.field(model, fieldPath, meta, DecisionRejected, w.permissions)
+			case !needsWalk(structField.Type):
+				w.audit.field(model, fieldPath, meta, DecisionAccepted, w.permissions)
+			}
+			if !granted {
+				v.Field(i).Set(reflect.Zero(structField.Type))
+				continue
+			}
+			if err := w.enforceWrites(v.Field(i), meta.Fields, model, fieldPath, false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := w.enforceWrites(v.Field(i), nil, model, fieldPath, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requiredScopes returns the scopes a ScopeProvider value demands, whether
+// GetRequiredScopes has a value or a pointer receiver
+func requiredScopes(v reflect.Value) ([]string, bool) {
+	t := v.Type()
+	switch {
+	case t.Implements(scopeProviderType):
+		return v.Interface().(ScopeProvider).GetRequiredScopes(), true
+	case reflect.PointerTo(t).Implements(scopeProviderType):
+		ptr := reflect.New(t)
+		ptr.Elem().Set(v)
+		return ptr.Interface().(ScopeProvider).GetRequiredScopes(), true
+	}
+	return nil, false
+}
+
+// formatFieldName returns the key a format encodes a struct field under;
+// skip is true when the field is excluded with "-"
+func formatFieldName(field reflect.StructField, format string) (name string, skip bool) {
+	tag := formatTag(field, format)
+	name, _, _ = strings.Cut(tag, ",")
+	if tag == "-" {
+		return "", true
+	}
+	if isFlattened(field, format) {
+		return "", false
+	}
+	if name != "" {
+		return name, false
+	}
+	if format == "yaml" {
+		// yaml.v3 lowercases untagged field names
+		return strings.ToLower(field.Name), false
+	}
+	return field.Name, false
+}
+
+// formatTag returns the struct tag a format reads; formats other than the
+// text ones fall back to the json tag when they have none of their own
+func formatTag(field reflect.StructField, format string) string {
+	tag, exists := field.Tag.Lookup(format)
+	if !exists && format != "json" && format != "yaml" && format != "toml" {
+		return field.Tag.Get("json")
+	}
+	return tag
+}
+
+// isFlattened reports whether a format inlines an embedded struct's fields
+// into the parent: json and toml flatten untagged embeds, yaml needs ",inline"
+func isFlattened(field reflect.StructField, format string) bool {
+	if derefKind(field.Type) != reflect.Struct {
+		return false
+	}
+	name, opts, _ := strings.Cut(formatTag(field, format), ",")
+	if format == "yaml" {
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "inline" {
+				return true
+			}
+		}
+		return false
+	}
+	return field.Anonymous && name == ""
+}
+
+// isPromoted mirrors the catalog, which lists the fields of an untagged
+// embedded struct in the parent the way encoding/json does
+func isPromoted(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return field.Anonymous && derefKind(field.Type) == reflect.Struct && name == ""
+}
+
+// needsWalk reports whether values of t can contain fields to scope
+func needsWalk(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return needsWalk(t.Elem())
+	case reflect.Interface:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return needsWalk(t.Elem())
+	case reflect.Struct:
+		return hasExportedFields(t)
+	}
+	return false
+}
+
+// hasExportedFields is false for opaque structs like time.Time
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() || field.Anonymous {
+			return true
+		}
+	}
+	return false
+}
+
+// structFields looks up catalog metadata for a struct reached without a
+// parent field, e.g. through an interface or a recursive type
+func structFields(t reflect.Type) []catalog.FieldMetadata {
+	return catalog.ExtractAndCacheMetadata(reflect.Zero(t).Interface()).Fields
+}
+
+// qualifiedName is the catalog identity of t, used in errors
+func qualifiedName(t reflect.Type) string {
+	return catalog.ExtractAndCacheMetadata(reflect.Zero(t).Interface()).QualifiedName
+}
+
+// directField finds the metadata for the struct's own field at index i
+func directField(fields []catalog.FieldMetadata, i int) (catalog.FieldMetadata, bool) {
+	for _, field := range fields {
+		if len(field.Index) == 1 && field.Index[0] == i {
+			return field, true
+		}
+	}
+	return catalog.FieldMetadata{}, false
+}
+
+// promotedFields returns the fields promoted from the embed at index i,
+// re-indexed relative to the embedded struct
+func promotedFields(fields []catalog.FieldMetadata, i int) []catalog.FieldMetadata {
+	promoted := []catalog.FieldMetadata{}
+	for _, field := range fields {
+		if len(field.Index) > 1 && field.Index[0] == i {
+			field.Index = field.Index[1:]
+			promoted = append(promoted, field)
+		}
+	}
+	return promoted
+}
+
+// assignValue stores a processor result in a field, converting between
+// compatible kinds and falling back to an empty value when it cannot
+func assignValue(target reflect.Value, value any) {
+	t := target.Type()
+	if value == nil {
+		target.Set(emptyValue(t))
+		return
+	}
+
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Type().AssignableTo(t):
+		target.Set(rv)
+	case convertible(rv, t):
+		target.Set(rv.Convert(t))
+	case t.Kind() == reflect.Pointer && convertible(rv, t.Elem()):
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(rv.Convert(t.Elem()))
+		target.Set(ptr)
+	default:
+		target.Set(emptyValue(t))
+	}
+}
+
+// convertible allows conversions within the same family of kinds only, so an
+// int is never turned into a one-rune string
+func convertible(v reflect.Value, t reflect.Type) bool {
+	if !v.Type().ConvertibleTo(t) {
+		return false
+	}
+	return kindFamily(v.Kind()) == kindFamily(t.Kind())
+}
+
+func kindFamily(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return kind.String()
+	}
+}
+
+// emptyValue is the redacted form of a value with no better replacement:
+// containers become empty rather than null so required rules still pass
+func emptyValue(t reflect.Type) reflect.Value {
+	switch t.Kind() {
+	case reflect.Slice:
+		return reflect.MakeSlice(t, 0, 0)
+	case reflect.Map:
+		return reflect.MakeMap(t)
+	}
+	return reflect.Zero(t)
+}
+
+func derefKind(t reflect.Type) reflect.Kind {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind()
+}
+
+func joinFieldPath(path, name string) string {
+	switch {
+	case name == "":
+		return path
+	case path == "":
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,315 @@
+package cereal
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type scopeAddress struct {
+	Street string `json:"street" yaml:"street_line" toml:"street_line"`
+	Code   string `json:"code" yaml:"code" toml:"code" scope:"admin"`
+}
+
+type scopeAudit struct {
+	CreatedBy string `json:"created_by" scope:"admin"`
+}
+
+type scopeOrder struct {
+	scopeAudit `yaml:"-"`           // yaml.v3 cannot encode unexported embeds
+	ID         int                  `json:"id"`
+	Address    scopeAddress         `json:"address" yaml:"address"`
+	Previous   *scopeAddress        `json:"previous,omitempty" yaml:"previous,omitempty"`
+	Items      []scopeItem          `json:"items"`
+	Labels     map[string]scopeItem `json:"labels"`
+	Internal   scopeAddress         `json:"internal" scope:"admin"`
+	Ignored    string               `json:"-" scope:"admin"`
+	Extra      any                  `json:"extra,omitempty"`
+	Parent     *scopeOrder          `json:"parent,omitempty"`
+	private    string
+}
+
+type scopeItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price" scope:"finance"`
+}
+
+type scopePointerProvider struct {
+	Name string `json:"name"`
+}
+
+func (p *scopePointerProvider) GetRequiredScopes() []string {
+	return []string{"ops"}
+}
+
+func newScopeOrder() scopeOrder {
+	return scopeOrder{
+		scopeAudit: scopeAudit{CreatedBy: "alice"},
+		ID:         7,
+		Address:    scopeAddress{Street: "1 Main St", Code: "A1"},
+		Previous:   &scopeAddress{Street: "2 Side St", Code: "B2"},
+		Items:      []scopeItem{{Name: "pen", Price: 1.5}, {Name: "ink", Price: 3}},
+		Labels:     map[string]scopeItem{"gift": {Name: "wrap", Price: 2}},
+		Internal:   scopeAddress{Street: "vault", Code: "C3"},
+		Ignored:    "hidden",
+		Extra:      scopeItem{Name: "bonus", Price: 9},
+		private:    "kept",
+	}
+}
+
+func TestScope_NestedValuesPreserved(t *testing.T) {
+	order := newScopeOrder()
+
+	data, err := JSON.Marshal(order, "finance")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+
+	if result["id"] != float64(7) {
+		t.Errorf("Expected id 7, got %v", result["id"])
+	}
+	if result["created_by"] != "[REDACTED]" {
+		t.Errorf("Expected promoted field to be redacted, got %v", result["created_by"])
+	}
+
+	address := result["address"].(map[string]any)
+	if address["street"] != "1 Main St" {
+		t.Errorf("Expected nested street to be kept, got %v", address["street"])
+	}
+	if address["code"] != "[REDACTED]" {
+		t.Errorf("Expected nested code to be redacted, got %v", address["code"])
+	}
+
+	previous := result["previous"].(map[string]any)
+	if previous["code"] != "[REDACTED]" {
+		t.Errorf("Expected pointer field code to be redacted, got %v", previous["code"])
+	}
+
+	items := result["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	if item := items[1].(map[string]any); item["name"] != "ink" || item["price"] != float64(3) {
+		t.Errorf("Expected finance scope to see item price, got %v", item)
+	}
+
+	internal := result["internal"].(map[string]any)
+	if internal["street"] != "" || internal["code"] != "" {
+		t.Errorf("Expected denied struct to be zeroed, got %v", internal)
+	}
+}
+
+func TestScope_SliceAndMapElements(t *testing.T) {
+	order := newScopeOrder()
+
+	scoped, err := catalogScoper.Scope(order, "json", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	result := scoped.(scopeOrder)
+
+	for _, item := range result.Items {
+		if item.Price != 0 {
+			t.Errorf("Expected item price to be redacted, got %v", item.Price)
+		}
+	}
+	if result.Labels["gift"].Price != 0 || result.Labels["gift"].Name != "wrap" {
+		t.Errorf("Expected map value price redacted and name kept, got %+v", result.Labels["gift"])
+	}
+	if extra := result.Extra.(scopeItem); extra.Price != 0 || extra.Name != "bonus" {
+		t.Errorf("Expected interface value to be scoped, got %+v", extra)
+	}
+	if result.private != "kept" {
+		t.Errorf("Expected unexported field to be copied, got %q", result.private)
+	}
+}
+
+func TestScope_OriginalUntouched(t *testing.T) {
+	order := newScopeOrder()
+
+	if _, err := JSON.Marshal(&order); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if order.Items[0].Price != 1.5 || order.Previous.Code != "B2" || order.Labels["gift"].Price != 2 {
+		t.Errorf("Expected original value to be untouched, got %+v", order)
+	}
+	if order.CreatedBy != "alice" {
+		t.Errorf("Expected embedded field to be untouched, got %q", order.CreatedBy)
+	}
+}
+
+func TestScope_Cycle(t *testing.T) {
+	order := newScopeOrder()
+	order.Parent = &order
+
+	scoped, err := catalogScoper.Scope(&order, "json", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	result := scoped.(*scopeOrder)
+	if result.Parent != result {
+		t.Error("Expected cycle to be preserved in the copy")
+	}
+}
+
+type scopeInner struct {
+	Code string `json:"code" scope:"admin"`
+}
+
+type scopeOuter struct {
+	In  scopeInner  `json:"in"`
+	Ptr *scopeInner `json:"ptr"`
+}
+
+func TestScope_PointerToFirstField(t *testing.T) {
+	// &outer and &outer.In share an address but are different values
+	outer := &scopeOuter{In: scopeInner{Code: "secret"}}
+	outer.Ptr = &outer.In
+
+	data, err := JSON.Marshal(outer)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected both paths to the inner value to be scoped, got %s", data)
+	}
+}
+
+func TestScope_FormatFieldNames(t *testing.T) {
+	order := newScopeOrder()
+
+	data, err := YAML.Marshal(order)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var result map[string]any
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	address := result["address"].(map[string]any)
+	if address["street_line"] != "1 Main St" {
+		t.Errorf("Expected yaml field name to be honored, got %v", address)
+	}
+	if address["code"] != "[REDACTED]" {
+		t.Errorf("Expected yaml nested code to be redacted, got %v", address["code"])
+	}
+	// json:"-" does not apply to yaml, so the field is encoded there - redacted
+	if result["ignored"] != "[REDACTED]" {
+		t.Errorf("Expected yaml to encode the redacted field, got %v", result["ignored"])
+	}
+}
+
+func TestScope_TypedError(t *testing.T) {
+	user := ScopedUser{Name: "Ann", Email: "ann@example.com", Role: "admin"}
+
+	data, err := JSON.Marshal(user, "user")
+	if data != nil {
+		t.Errorf("Expected no body on scope failure, got %s", data)
+	}
+
+	var scopeErr *ScopeError
+	if !errors.As(err, &scopeErr) {
+		t.Fatalf("Expected *ScopeError, got %T: %v", err, err)
+	}
+	if len(scopeErr.Required) != 1 || scopeErr.Required[0] != "admin_data" {
+		t.Errorf("Expected admin_data requirement, got %v", scopeErr.Required)
+	}
+	if !strings.HasSuffix(scopeErr.Model, ".ScopedUser") {
+		t.Errorf("Expected qualified model name, got %s", scopeErr.Model)
+	}
+}
+
+func TestScope_PointerReceiverProvider(t *testing.T) {
+	_, err := JSON.Marshal(scopePointerProvider{Name: "x"})
+	var scopeErr *ScopeError
+	if !errors.As(err, &scopeErr) {
+		t.Fatalf("Expected *ScopeError for pointer receiver provider, got %v", err)
+	}
+
+	if _, err := JSON.Marshal(scopePointerProvider{Name: "x"}, "ops"); err != nil {
+		t.Errorf("Expected no error with ops scope, got: %v", err)
+	}
+}
+
+func TestScope_UnmarshalDropsDeniedWrites(t *testing.T) {
+	input := `{"id":1,"created_by":"mallory","address":{"street":"s","code":"X9"},"items":[{"name":"n","price":100}]}`
+
+	var order scopeOrder
+	if err := JSON.Unmarshal([]byte(input), &order, "finance"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if order.CreatedBy != "" {
+		t.Errorf("Expected promoted admin field to be dropped, got %q", order.CreatedBy)
+	}
+	if order.Address.Street != "s" || order.Address.Code != "" {
+		t.Errorf("Expected nested admin field to be dropped, got %+v", order.Address)
+	}
+	if order.Items[0].Price != 100 {
+		t.Errorf("Expected finance caller to write price, got %v", order.Items[0].Price)
+	}
+
+	var provider scopePointerProvider
+	err := JSON.Unmarshal([]byte(`{"name":"x"}`), &provider)
+	var scopeErr *ScopeError
+	if !errors.As(err, &scopeErr) {
+		t.Errorf("Expected *ScopeError on unmarshal, got %v", err)
+	}
+}
+
+type scopedSensitive struct {
+	Name      string `json:"name"`
+	SSN       string `json:"ssn" scope:"admin" encrypt:"pii"`
+	IBAN      string `json:"iban" scope:"admin" encrypt:"financial"`
+	Diagnosis string `json:"diagnosis" scope:"doctor" encrypt:"medical"`
+}
+
+func TestScope_SensitiveTypesHonorScopes(t *testing.T) {
+	sink := NewMemoryAuditSink()
+	useAuditSink(t, sink)
+	record := scopedSensitive{Name: "Ada", SSN: "123-45-6789", IBAN: "DE89370400440532013000", Diagnosis: "flu"}
+
+	cases := []struct {
+		permissions []string
+		visible     map[string]bool
+	}{
+		{nil, map[string]bool{}},
+		{[]string{"pii", "financial", "medical"}, map[string]bool{}}, // Type permission alone does not satisfy the scope
+		{[]string{"admin"}, map[string]bool{}},                       // Scope alone does not satisfy the type permission
+		{[]string{"admin", "pii"}, map[string]bool{"ssn": true}},
+		{[]string{"doctor", "medical"}, map[string]bool{"diagnosis": true}},
+	}
+	originals := map[string]string{"ssn": record.SSN, "iban": record.IBAN, "diagnosis": record.Diagnosis}
+
+	for _, c := range cases {
+		data, err := JSON.Marshal(record, c.permissions...)
+		if err != nil {
+			t.Fatalf("Expected no error for %v, got: %v", c.permissions, err)
+		}
+		var doc map[string]any
+		json.Unmarshal(data, &doc)
+
+		records := sink.Records()
+		audit := records[len(records)-1]
+		for key, original := range originals {
+			returned := doc[key] == original
+			if returned != c.visible[key] {
+				t.Errorf("With %v expected %s visible=%v, got %v", c.permissions, key, c.visible[key], doc[key])
+			}
+			access, _ := audit.Field(key)
+			if (access.Decision == DecisionReturned) != returned {
+				t.Errorf("With %v the audit records %s as %s but it was returned=%v", c.permissions, key, access.Decision, returned)
+			}
+		}
+	}
+}
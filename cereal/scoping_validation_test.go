@@ -229,6 +229,32 @@ func TestScopingWithValidation_RequiredFieldRedacted(t *testing.T) {
 	}
 }
 
+// Redacted placeholders cannot satisfy every rule: a denied nested struct is
+// zeroed, so its required fields are empty. Validation after scoping checks
+// the caller's data and skips values it never saw.
+func TestScopingWithValidation_RedactedFieldsSkipped(t *testing.T) {
+	type Flags struct {
+		Enabled bool `json:"enabled" scope:"admin" validate:"required"`
+	}
+	type Account struct {
+		Name   string  `json:"name" validate:"required"`
+		Active bool    `json:"active" scope:"admin" validate:"required"`
+		Flags  []Flags `json:"flags"`
+		Limits Flags   `json:"limits" scope:"admin" validate:"required"`
+	}
+
+	data := Account{Name: "Ada", Active: true, Flags: []Flags{{Enabled: true}}, Limits: Flags{Enabled: true}}
+	if _, err := JSON.Marshal(data, "user"); err != nil {
+		t.Fatalf("Expected redacted fields to skip validation, got: %v", err)
+	}
+
+	// Fields the caller can read are still validated
+	data.Name = ""
+	if _, err := JSON.Marshal(data, "user"); err == nil || !strings.Contains(err.Error(), "Name") {
+		t.Errorf("Expected a readable required field to fail validation, got %v", err)
+	}
+}
+
 func TestFormatSpecificRedaction(t *testing.T) {
 	// Test that redacted values match expected format validators
 	type FormatTest struct {
@@ -0,0 +1,140 @@
+package cereal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"zbz/catalog"
+)
+
+// ErrNotSearchable is returned by SearchTerm for fields whose stored form
+// can't be matched by equality
+var ErrNotSearchable = errors.New("field is not searchable")
+
+// blindIndexSize is the number of HMAC bytes kept in a blind index
+const blindIndexSize = 16
+
+// encryptAlgorithm reads a field's encrypt_algo for the given mode
+func encryptAlgorithm(field reflect.StructField, mode string) (string, error) {
+	algorithm := field.Tag.Get("encrypt_algo")
+	switch algorithm {
+	case "", "AES-256":
+		return AlgorithmAES256GCM, nil
+	case AlgorithmAES256GCM:
+		return algorithm, nil
+	case AlgorithmAESSIV, catalog.AlgorithmBlindIndex:
+		if mode != "owner" {
+			return "", fmt.Errorf("encrypt_algo %s requires encrypt:\"owner\"", algorithm)
+		}
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("unsupported encrypt_algo %s", algorithm)
+}
+
+// fieldContext names a field for key derivation: the catalog identity of
+// the struct that declares it and its Go name
+func fieldContext(owner reflect.Type, field reflect.StructField) string {
+	return qualifiedName(owner) + "." + field.Name
+}
+
+// blindIndexColumn is the key a field's companion index is written under.
+// It comes from the catalog, which SearchTerm and query builders read too.
+func blindIndexColumn(owner reflect.Type, field reflect.StructField) string {
+	meta, _ := directField(structFields(owner), field.Index[len(field.Index)-1])
+	return meta.Encryption.BlindIndex
+}
+
+// blindIndex computes the companion value of a blind-indexed field: a
+// truncated HMAC of the typed plaintext under a key derived for that field.
+// The master key ID is kept in front so indexes written before a rotation
+// can be found and rebuilt.
+func (e *EncryptionService) blindIndex(context string, plaintext []byte, ctx SecurityContext) (string, error) {
+	keyID, masterKey, err := e.keyProvider(ctx).CurrentKey()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, deriveSubkey(masterKey, "blind-index/"+context, sha256.New))
+	mac.Write(plaintext)
+	return keyID + envelopeSeparator + envelopeEncoding.EncodeToString(mac.Sum(nil)[:blindIndexSize]), nil
+}
+
+// SearchTerm returns the column and value a query compares against to find
+// records of model whose field equals value. AES-SIV fields match on their
+// own column with the deterministic ciphertext; blind-index fields match on
+// their companion column. field is the Go or json name.
+//
+//	column, term, err := service.SearchTerm(User{}, "email", "a@b.test", ctx)
+//	query := astql.Select("users").Where(column, astql.EQ, term)
+func (e *EncryptionService) SearchTerm(model any, field string, value any, ctx SecurityContext) (string, string, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", "", fmt.Errorf("SearchTerm requires a struct model, got %T", model)
+	}
+
+	for _, meta := range structFields(t) {
+		if meta.Name != field && meta.JSONName != field {
+			continue
+		}
+
+		structField := t.FieldByIndex(meta.Index)
+		owner := t
+		if len(meta.Index) > 1 {
+			owner = t.FieldByIndex(meta.Index[:len(meta.Index)-1]).Type
+			for owner.Kind() == reflect.Pointer {
+				owner = owner.Elem()
+			}
+		}
+
+		algorithm, err := encryptAlgorithm(structField, meta.Encryption.Type)
+		if err != nil {
+			return "", "", err
+		}
+		if meta.Encryption.Type != "owner" || (algorithm != AlgorithmAESSIV && algorithm != catalog.AlgorithmBlindIndex) {
+			return "", "", fmt.Errorf("%w: %s is not encrypted with AES-SIV or a blind index", ErrNotSearchable, field)
+		}
+
+		plaintext, err := searchPlaintext(structField.Type, value)
+		if err != nil {
+			return "", "", fmt.Errorf("search %s: %w", field, err)
+		}
+
+		if algorithm == AlgorithmAESSIV {
+			sealed, err := e.EncryptDeterministic(plaintext, ctx)
+			return columnName(meta), string(sealed), err
+		}
+		index, err := e.blindIndex(fieldContext(owner, structField), plaintext, ctx)
+		return meta.Encryption.BlindIndex, index, err
+	}
+
+	return "", "", fmt.Errorf("%s has no field %s", qualifiedName(t), field)
+}
+
+// searchPlaintext seals a query value the way a stored field of fieldType is sealed
+func searchPlaintext(fieldType reflect.Type, value any) ([]byte, error) {
+	if value == nil {
+		return nil, fmt.Errorf("search value is nil")
+	}
+	v := reflect.ValueOf(value)
+	if want, got := typeFamily(fieldType), typeFamily(v.Type()); want != got {
+		return nil, fmt.Errorf("value is %s, field is %s", got, want)
+	}
+	return sealTyped(v)
+}
+
+// columnName is the stored name of a field: its db column, json name or Go name
+func columnName(meta catalog.FieldMetadata) string {
+	if meta.DBColumn != "" && meta.DBColumn != "-" {
+		return meta.DBColumn
+	}
+	if meta.JSONName != "" {
+		return meta.JSONName
+	}
+	return meta.Name
+}
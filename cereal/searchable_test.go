@@ -0,0 +1,147 @@
+package cereal
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type searchableUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email" encrypt:"owner" encrypt_algo:"blind-index"`
+	SSN   string `json:"ssn" db:"tax_id" encrypt:"owner" encrypt_algo:"AES-SIV"`
+	Age   int    `json:"age" encrypt:"owner" encrypt_algo:"AES-SIV"`
+	Notes string `json:"notes" encrypt:"owner"`
+}
+
+func sealedDocument(t *testing.T, user searchableUser, ctx SecurityContext) map[string]any {
+	t.Helper()
+	data, err := MarshalSecure(user, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestSearchable_Deterministic(t *testing.T) {
+	ctx := SecurityContext{OrgMasterKey: []byte("searchable-key")}
+	first := sealedDocument(t, searchableUser{Name: "a", Email: "a@b.test", SSN: "123-45-6789", Age: 40, Notes: "n"}, ctx)
+	second := sealedDocument(t, searchableUser{Name: "b", Email: "a@b.test", SSN: "123-45-6789", Age: 41, Notes: "n"}, ctx)
+
+	if first["ssn"] != second["ssn"] {
+		t.Error("Expected AES-SIV ciphertext to be equal for equal values")
+	}
+	if first["age"] == second["age"] {
+		t.Error("Expected AES-SIV ciphertext to differ for different values")
+	}
+	if first["notes"] == second["notes"] {
+		t.Error("Expected randomized ciphertext to differ for equal values")
+	}
+	if first["email"] == second["email"] {
+		t.Error("Expected blind-indexed value to stay randomized")
+	}
+	if first["email_bidx"] == nil || first["email_bidx"] != second["email_bidx"] {
+		t.Errorf("Expected equal blind indexes, got %v and %v", first["email_bidx"], second["email_bidx"])
+	}
+
+	var decoded searchableUser
+	data, _ := json.Marshal(first)
+	if err := UnmarshalSecure(data, &decoded, ctx); err != nil {
+		t.Fatalf("Expected no error on unmarshal, got: %v", err)
+	}
+	if decoded.SSN != "123-45-6789" || decoded.Age != 40 || decoded.Email != "a@b.test" {
+		t.Errorf("Expected searchable fields to decrypt, got %+v", decoded)
+	}
+}
+
+func TestSearchable_SearchTerm(t *testing.T) {
+	ctx := SecurityContext{OrgMasterKey: []byte("searchable-key")}
+	doc := sealedDocument(t, searchableUser{Name: "a", Email: "a@b.test", SSN: "123-45-6789", Age: 40}, ctx)
+	service := SecureJSON_DefaultKey.encryptionService
+
+	column, term, err := service.SearchTerm(searchableUser{}, "email", "a@b.test", ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if column != "email_bidx" || term != doc["email_bidx"] {
+		t.Errorf("Expected email_bidx term to match the stored index, got %s=%s", column, term)
+	}
+
+	column, term, err = service.SearchTerm(&searchableUser{}, "SSN", "123-45-6789", ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if column != "tax_id" || term != doc["ssn"] {
+		t.Errorf("Expected tax_id term to match the stored ciphertext, got %s=%s", column, term)
+	}
+
+	if _, term, _ := service.SearchTerm(searchableUser{}, "age", int64(40), ctx); term != doc["age"] {
+		t.Error("Expected int64 search value to match an int field")
+	}
+	if _, _, err := service.SearchTerm(searchableUser{}, "notes", "n", ctx); !errors.Is(err, ErrNotSearchable) {
+		t.Errorf("Expected ErrNotSearchable for randomized field, got %v", err)
+	}
+	if _, _, err := service.SearchTerm(searchableUser{}, "age", "40", ctx); err == nil {
+		t.Error("Expected type mismatch to be rejected")
+	}
+}
+
+func TestSearchable_BlindIndexUsesCatalogColumn(t *testing.T) {
+	type contact struct {
+		Email string `db:"email_address" json:"email" encrypt:"owner" encrypt_algo:"blind-index"`
+	}
+	ctx := SecurityContext{OrgMasterKey: []byte("searchable-key")}
+	data, err := MarshalSecure(contact{Email: "a@b.test"}, ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var doc map[string]any
+	json.Unmarshal(data, &doc)
+
+	column, term, err := SecureJSON_DefaultKey.encryptionService.SearchTerm(contact{}, "email", "a@b.test", ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if column != "email_address_bidx" || doc[column] != term {
+		t.Errorf("Expected the index under %s to match the search term, got %v", column, doc)
+	}
+	if _, exists := doc["email_bidx"]; exists {
+		t.Error("Expected no index under the json name")
+	}
+}
+
+func TestSearchable_ReencryptKeepsAlgorithm(t *testing.T) {
+	keys := NewMemoryKeyring()
+	keys.Add("k1", []byte("first master key"))
+	service := NewEncryptionServiceWithKeys(keys)
+
+	sealed, _ := service.EncryptDeterministic([]byte("value"), SecurityContext{})
+	keys.Rotate()
+
+	migrated, changed, err := service.Reencrypt(string(sealed), SecurityContext{})
+	if err != nil || !changed {
+		t.Fatalf("Expected value to migrate, got changed=%v (%v)", changed, err)
+	}
+	parsed, _ := ParseCiphertext(migrated)
+	if parsed.Algorithm != AlgorithmAESSIV {
+		t.Errorf("Expected AES-SIV to be kept, got %s", parsed.Algorithm)
+	}
+
+	again, _ := service.EncryptDeterministic([]byte("value"), SecurityContext{})
+	if string(again) != migrated {
+		t.Error("Expected migrated value to match fresh deterministic ciphertext")
+	}
+}
+
+func TestSearchable_RequiresOwnerMode(t *testing.T) {
+	type invalid struct {
+		Token string `json:"token" encrypt:"subscriber" encrypt_algo:"AES-SIV"`
+	}
+	if _, err := MarshalSecure(invalid{Token: "x"}, SecurityContext{OrgMasterKey: []byte("k")}); err == nil {
+		t.Error("Expected AES-SIV with subscriber encryption to be rejected")
+	}
+}
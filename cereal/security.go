@@ -6,10 +6,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
-	"encoding/base64"
 	"fmt"
-	"io"
-	"reflect"
 )
 
 // SecurityContext carries user permissions and encryption keys for cereal operations
@@ -30,32 +27,86 @@ type SecurityAction func(model interface{}, operation string, ctx SecurityContex
 
 // EncryptionService handles field-level encryption/decryption
 type EncryptionService struct {
-	defaultOrgKey []byte
+	defaultOrgKey []byte      // Decrypts legacy values; never seals new ones on a keyring service
+	keys          KeyProvider // Master keys for "owner" envelope encryption
 }
 
 // NewEncryptionService creates a new encryption service with org master key
 func NewEncryptionService(orgMasterKey []byte) *EncryptionService {
 	return &EncryptionService{
 		defaultOrgKey: orgMasterKey,
+		keys:          newStaticKeyring(orgMasterKey),
 	}
 }
 
-// EncryptOwner encrypts data with organization's key (org can decrypt)
-func (e *EncryptionService) EncryptOwner(plaintext []byte, ctx SecurityContext) ([]byte, error) {
-	key := ctx.OrgMasterKey
-	if len(key) == 0 {
-		key = e.defaultOrgKey
+// NewEncryptionServiceWithKeys creates an encryption service backed by a
+// keyring, so master keys can be rotated and old values re-encrypted
+func NewEncryptionServiceWithKeys(keys KeyProvider) *EncryptionService {
+	return &EncryptionService{
+		keys: keys,
 	}
-	
-	if len(key) == 0 {
-		return nil, fmt.Errorf("no organization master key provided")
+}
+
+// NewEncryptionServiceWithLegacyKey creates a keyring-backed service that
+// can still decrypt values written under a single pre-keyring master key:
+// base64 ciphertext from before envelope encryption and envelopes sealed by
+// NewEncryptionService(legacyKey). The legacy key only decrypts, so Reencrypt
+// moves those values onto the keyring's current key.
+func NewEncryptionServiceWithLegacyKey(keys KeyProvider, legacyKey []byte) *EncryptionService {
+	return &EncryptionService{
+		defaultOrgKey: legacyKey,
+		keys:          keyChain{keys, decryptOnly{newStaticKeyring(legacyKey)}},
 	}
-	
-	return e.encryptAES(plaintext, key)
 }
 
-// DecryptOwner decrypts owner-encrypted data with organization's key
+// keyProvider resolves the master keys for an operation. A key in the
+// context takes precedence for new values; the service's keys still
+// decrypt values they wrote.
+func (e *EncryptionService) keyProvider(ctx SecurityContext) KeyProvider {
+	chain := keyChain{}
+	if len(ctx.OrgMasterKey) > 0 {
+		chain = append(chain, newStaticKeyring(ctx.OrgMasterKey))
+	}
+	if e.keys != nil {
+		chain = append(chain, e.keys)
+	}
+	return chain
+}
+
+// EncryptOwner encrypts data with organization's key (org can decrypt).
+// The result is a self-describing ciphertext: a fresh data key encrypts the
+// value and is wrapped by the current master key.
+func (e *EncryptionService) EncryptOwner(plaintext []byte, ctx SecurityContext) ([]byte, error) {
+	return e.encryptOwner(plaintext, AlgorithmAES256GCM, ctx)
+}
+
+// EncryptDeterministic encrypts data with organization's key using AES-SIV.
+// Equal plaintexts under the same master key give equal ciphertext, which
+// makes the value searchable by equality and reveals which values repeat.
+func (e *EncryptionService) EncryptDeterministic(plaintext []byte, ctx SecurityContext) ([]byte, error) {
+	return e.encryptOwner(plaintext, AlgorithmAESSIV, ctx)
+}
+
+// encryptOwner seals plaintext with the current master key
+func (e *EncryptionService) encryptOwner(plaintext []byte, algorithm string, ctx SecurityContext) ([]byte, error) {
+	sealed, err := sealEnvelope(plaintext, e.keyProvider(ctx), algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sealed.String()), nil
+}
+
+// DecryptOwner decrypts owner-encrypted data with organization's key.
+// Ciphertext from before envelope encryption is still accepted.
 func (e *EncryptionService) DecryptOwner(ciphertext []byte, ctx SecurityContext) ([]byte, error) {
+	if IsCiphertext(string(ciphertext)) {
+		parsed, err := ParseCiphertext(string(ciphertext))
+		if err != nil {
+			return nil, err
+		}
+		return openEnvelope(parsed, e.keyProvider(ctx))
+	}
+	
 	key := ctx.OrgMasterKey
 	if len(key) == 0 {
 		key = e.defaultOrgKey
@@ -100,32 +151,7 @@ func (e *EncryptionService) DecryptSubscriber(ciphertext []byte, ctx SecurityCon
 	return rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, ciphertext, nil)
 }
 
-// encryptAES encrypts data using AES-GCM
-func (e *EncryptionService) encryptAES(plaintext, key []byte) ([]byte, error) {
-	// Ensure key is 32 bytes for AES-256
-	hasher := sha256.Sum256(key)
-	aesKey := hasher[:]
-	
-	block, err := aes.NewCipher(aesKey)
-	if err != nil {
-		return nil, err
-	}
-	
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-	
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
-	}
-	
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-	return ciphertext, nil
-}
-
-// decryptAES decrypts data using AES-GCM
+// decryptAES decrypts legacy ciphertext: AES-GCM directly under the master key
 func (e *EncryptionService) decryptAES(ciphertext, key []byte) ([]byte, error) {
 	// Ensure key is 32 bytes for AES-256
 	hasher := sha256.Sum256(key)
@@ -180,212 +206,3 @@ func runSecurityActions(model interface{}, operation string, ctx SecurityContext
 	}
 	return nil
 }
-
-// applyFieldEncryption encrypts fields based on catalog metadata
-func applyFieldEncryption(value reflect.Value, ctx SecurityContext, encService *EncryptionService) error {
-	if value.Kind() == reflect.Ptr {
-		if value.IsNil() {
-			return nil
-		}
-		value = value.Elem()
-	}
-	
-	if value.Kind() != reflect.Struct {
-		return nil
-	}
-	
-	// Get type name for catalog lookup
-	typeName := value.Type().Name()
-	if typeName == "" {
-		return nil // Anonymous structs not supported
-	}
-	
-	// Get encryption metadata from catalog
-	// Note: This is a simplified approach - in a real implementation,
-	// we'd need a way to get the type for catalog.GetEncryptionFields[T]()
-	// For now, we'll demonstrate the concept with reflection on struct tags
-	
-	t := value.Type()
-	for i := 0; i < value.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := value.Field(i)
-		
-		if !fieldValue.CanSet() {
-			continue
-		}
-		
-		encryptTag := field.Tag.Get("encrypt")
-		if encryptTag == "" {
-			continue
-		}
-		
-		// Only encrypt string fields for this demo
-		if fieldValue.Kind() != reflect.String {
-			continue
-		}
-		
-		plaintext := fieldValue.String()
-		if plaintext == "" {
-			continue
-		}
-		
-		var encrypted []byte
-		var err error
-		
-		switch encryptTag {
-		case "owner":
-			encrypted, err = encService.EncryptOwner([]byte(plaintext), ctx)
-		case "subscriber":
-			encrypted, err = encService.EncryptSubscriber([]byte(plaintext), ctx)
-		default:
-			continue // Unknown encryption type
-		}
-		
-		if err != nil {
-			return fmt.Errorf("failed to encrypt field %s: %w", field.Name, err)
-		}
-		
-		// Store encrypted data as base64 string
-		// In production, might use a structured format with metadata
-		encryptedStr := base64.StdEncoding.EncodeToString(encrypted)
-		fieldValue.SetString(encryptedStr)
-	}
-	
-	return nil
-}
-
-// applyFieldDecryption decrypts fields based on catalog metadata
-func applyFieldDecryption(value reflect.Value, ctx SecurityContext, encService *EncryptionService) error {
-	if value.Kind() == reflect.Ptr {
-		if value.IsNil() {
-			return nil
-		}
-		value = value.Elem()
-	}
-	
-	if value.Kind() != reflect.Struct {
-		return nil
-	}
-	
-	t := value.Type()
-	for i := 0; i < value.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := value.Field(i)
-		
-		if !fieldValue.CanSet() {
-			continue
-		}
-		
-		encryptTag := field.Tag.Get("encrypt")
-		if encryptTag == "" {
-			continue
-		}
-		
-		// Only decrypt string fields for this demo
-		if fieldValue.Kind() != reflect.String {
-			continue
-		}
-		
-		encryptedStr := fieldValue.String()
-		if encryptedStr == "" {
-			continue
-		}
-		
-		// Decode base64
-		encrypted, err := base64.StdEncoding.DecodeString(encryptedStr)
-		if err != nil {
-			// If it's not base64, assume it's already plaintext
-			continue
-		}
-		
-		var plaintext []byte
-		
-		switch encryptTag {
-		case "owner":
-			plaintext, err = encService.DecryptOwner(encrypted, ctx)
-		case "subscriber":
-			plaintext, err = encService.DecryptSubscriber(encrypted, ctx)
-		default:
-			continue // Unknown encryption type
-		}
-		
-		if err != nil {
-			return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
-		}
-		
-		fieldValue.SetString(string(plaintext))
-	}
-	
-	return nil
-}
-
-// applyScopeRedaction applies scope-based redaction using catalog metadata
-func applyScopeRedaction(value reflect.Value, permissions []string) error {
-	if value.Kind() == reflect.Ptr {
-		if value.IsNil() {
-			return nil
-		}
-		value = value.Elem()
-	}
-	
-	if value.Kind() != reflect.Struct {
-		return nil
-	}
-	
-	t := value.Type()
-	for i := 0; i < value.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := value.Field(i)
-		
-		if !fieldValue.CanSet() {
-			continue
-		}
-		
-		// Check scope requirements
-		scopeTag := field.Tag.Get("scope")
-		if scopeTag == "" {
-			continue // No scope restriction
-		}
-		
-		// Check if user has required scope
-		hasScope := false
-		for _, userScope := range permissions {
-			if userScope == scopeTag {
-				hasScope = true
-				break
-			}
-		}
-		
-		if !hasScope {
-			// Apply redaction based on field type
-			redactTag := field.Tag.Get("redact")
-			
-			switch fieldValue.Kind() {
-			case reflect.String:
-				if redactTag != "" {
-					fieldValue.SetString(redactTag)
-				} else {
-					fieldValue.SetString("[REDACTED]")
-				}
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				if redactTag != "" {
-					// Try to parse redactTag as int, default to 0
-					fieldValue.SetInt(0)
-				} else {
-					fieldValue.SetInt(0)
-				}
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				fieldValue.SetUint(0)
-			case reflect.Float32, reflect.Float64:
-				fieldValue.SetFloat(0.0)
-			case reflect.Bool:
-				fieldValue.SetBool(false)
-			default:
-				// For complex types, set to zero value
-				fieldValue.Set(reflect.Zero(fieldValue.Type()))
-			}
-		}
-	}
-	
-	return nil
-}
\ No newline at end of file
@@ -2,14 +2,9 @@ package cereal
 
 // Public singletons for each format
 var (
-	JSON *zJSON
-	YAML *zYaml
-	TOML *zTOML
+	JSON    = NewPipeline(jsonFormat)
+	YAML    = NewPipeline(yamlFormat)
+	TOML    = NewPipeline(tomlFormat)
+	MSGPACK = NewPipeline(msgpackFormat)
+	CBOR    = NewPipeline(cborFormat)
 )
-
-func init() {
-	// Initialize format handlers
-	JSON = &zJSON{}
-	YAML = &zYaml{}
-	TOML = &zTOML{}
-}
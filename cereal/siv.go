@@ -0,0 +1,179 @@
+package cereal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+)
+
+// AES-SIV (RFC 5297) is a deterministic AEAD: the synthetic IV is a CMAC of
+// the associated data and plaintext, so equal inputs under one key always
+// produce equal ciphertext. The key is split in half: the first half keys
+// S2V, the second half keys CTR mode.
+
+const sivBlockSize = aes.BlockSize
+
+// sivSeal returns V || C for plaintext under key (32, 48 or 64 bytes)
+func sivSeal(key, plaintext []byte, associated ...[]byte) ([]byte, error) {
+	macKey, ctrKey, err := splitSIVKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s2v(macKey, append(associated, plaintext))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, sivBlockSize+len(plaintext))
+	copy(out, v)
+	if err := sivCTR(ctrKey, v, out[sivBlockSize:], plaintext); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sivOpen verifies and decrypts V || C
+func sivOpen(key, sealed []byte, associated ...[]byte) ([]byte, error) {
+	if len(sealed) < sivBlockSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	macKey, ctrKey, err := splitSIVKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := sealed[:sivBlockSize]
+	plaintext := make([]byte, len(sealed)-sivBlockSize)
+	if err := sivCTR(ctrKey, v, plaintext, sealed[sivBlockSize:]); err != nil {
+		return nil, err
+	}
+
+	expected, err := s2v(macKey, append(associated, plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expected, v) != 1 {
+		return nil, fmt.Errorf("message authentication failed")
+	}
+	return plaintext, nil
+}
+
+func splitSIVKey(key []byte) ([]byte, []byte, error) {
+	switch len(key) {
+	case 32, 48, 64:
+		return key[:len(key)/2], key[len(key)/2:], nil
+	}
+	return nil, nil, fmt.Errorf("AES-SIV key must be 32, 48 or 64 bytes, got %d", len(key))
+}
+
+// sivCTR runs AES-CTR with the synthetic IV, clearing bits 31 and 63 as the RFC requires
+func sivCTR(key, v, dst, src []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	iv := make([]byte, sivBlockSize)
+	copy(iv, v)
+	iv[8] &= 0x7f
+	iv[12] &= 0x7f
+	cipher.NewCTR(block, iv).XORKeyStream(dst, src)
+	return nil
+}
+
+// s2v derives the synthetic IV from a vector of strings, the last being the plaintext
+func s2v(key []byte, inputs [][]byte) ([]byte, error) {
+	mac, err := newCMAC(key)
+	if err != nil {
+		return nil, err
+	}
+
+	d := mac.sum(make([]byte, sivBlockSize))
+	for _, s := range inputs[:len(inputs)-1] {
+		d = dbl(d)
+		xorInto(d, mac.sum(s))
+	}
+
+	last := inputs[len(inputs)-1]
+	var t []byte
+	if len(last) >= sivBlockSize {
+		t = append([]byte(nil), last...)
+		xorInto(t[len(t)-sivBlockSize:], d)
+	} else {
+		t = dbl(d)
+		xorInto(t, pad(last))
+	}
+	return mac.sum(t), nil
+}
+
+// cmac is AES-CMAC (RFC 4493)
+type cmac struct {
+	block  cipher.Block
+	k1, k2 []byte
+}
+
+func newCMAC(key []byte) (*cmac, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]byte, sivBlockSize)
+	block.Encrypt(l, l)
+	k1 := dbl(l)
+	return &cmac{block: block, k1: k1, k2: dbl(k1)}, nil
+}
+
+func (m *cmac) sum(msg []byte) []byte {
+	n := (len(msg) + sivBlockSize - 1) / sivBlockSize
+	complete := n > 0 && len(msg)%sivBlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	var last []byte
+	if complete {
+		last = append([]byte(nil), msg[(n-1)*sivBlockSize:]...)
+		xorInto(last, m.k1)
+	} else {
+		last = pad(msg[(n-1)*sivBlockSize:])
+		xorInto(last, m.k2)
+	}
+
+	x := make([]byte, sivBlockSize)
+	for i := 0; i < n-1; i++ {
+		xorInto(x, msg[i*sivBlockSize:(i+1)*sivBlockSize])
+		m.block.Encrypt(x, x)
+	}
+	xorInto(x, last)
+	m.block.Encrypt(x, x)
+	return x
+}
+
+// dbl multiplies a block by x in GF(2^128)
+func dbl(b []byte) []byte {
+	out := make([]byte, sivBlockSize)
+	var carry byte
+	for i := sivBlockSize - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry == 1 {
+		out[sivBlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+// pad appends the 10* padding to a partial block
+func pad(b []byte) []byte {
+	out := make([]byte, sivBlockSize)
+	copy(out, b)
+	out[len(b)] = 0x80
+	return out
+}
+
+func xorInto(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,60 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSIV_RFC5297Vector(t *testing.T) {
+	key, _ := hex.DecodeString("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad, _ := hex.DecodeString("101112131415161718191a1b1c1d1e1f2021222324252627")
+	plaintext, _ := hex.DecodeString("112233445566778899aabbccddee")
+	want, _ := hex.DecodeString("85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+
+	sealed, err := sivSeal(key, plaintext, ad)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !bytes.Equal(sealed, want) {
+		t.Errorf("Expected %x, got %x", want, sealed)
+	}
+
+	opened, err := sivOpen(key, sealed, ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Expected round trip, got %x (%v)", opened, err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := sivOpen(key, sealed, ad); err == nil {
+		t.Error("Expected tampered ciphertext to fail")
+	}
+}
+
+// A.2 has several associated data inputs (the nonce is the last) and a
+// plaintext longer than a block, which takes the xorend path in S2V
+func TestSIV_RFC5297NonceVector(t *testing.T) {
+	key, _ := hex.DecodeString("7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f")
+	ad1, _ := hex.DecodeString("00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100")
+	ad2, _ := hex.DecodeString("102030405060708090a0")
+	nonce, _ := hex.DecodeString("09f911029d74e35bd84156c5635688c0")
+	plaintext, _ := hex.DecodeString("7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553")
+	want, _ := hex.DecodeString("7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d")
+
+	sealed, err := sivSeal(key, plaintext, ad1, ad2, nonce)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !bytes.Equal(sealed, want) {
+		t.Errorf("Expected %x, got %x", want, sealed)
+	}
+
+	opened, err := sivOpen(key, sealed, ad1, ad2, nonce)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Expected round trip, got %x (%v)", opened, err)
+	}
+
+	if _, err := sivOpen(key, sealed, ad2, ad1, nonce); err == nil {
+		t.Error("Expected reordered associated data to fail")
+	}
+}
@@ -0,0 +1,267 @@
+package cereal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"zbz/catalog"
+)
+
+// Framing selects how an Encoder separates records in a JSON stream
+type Framing int
+
+const (
+	// JSONArray writes records as the elements of a single JSON array
+	JSONArray Framing = iota
+	// NDJSON writes one JSON document per line (newline-delimited JSON)
+	NDJSON
+)
+
+// Encoder writes a stream of T as JSON without buffering the whole payload.
+// Each record runs the security actions and is scoped, redacted and
+// validated exactly like JSON.Marshal;
+// a record that fails is reported and nothing is written for it.
+type Encoder[T any] struct {
+	w           io.Writer
+	permissions []string
+	framing     Framing
+	count       int
+	closed      bool
+	modelType   string
+	ctx         context.Context
+}
+
+// NewEncoder creates a JSON array encoder; call SetFraming(NDJSON) before
+// the first Encode for newline-delimited output
+func NewEncoder[T any](w io.Writer, permissions ...string) *Encoder[T] {
+	return &Encoder[T]{
+		w:           w,
+		permissions: permissions,
+		framing:     JSONArray,
+		modelType:   catalog.GetTypeName[T](),
+		ctx:         context.Background(),
+	}
+}
+
+// SetFraming selects array or NDJSON output; it has no effect after the first record
+func (e *Encoder[T]) SetFraming(framing Framing) {
+	if e.count == 0 {
+		e.framing = framing
+	}
+}
+
+// SetContext sets the context records are encoded in; access audits record
+// its identity and purpose once per record, and validators receive it
+func (e *Encoder[T]) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// Encode scopes, validates and writes one record
+func (e *Encoder[T]) Encode(v T) (err error) {
+	if e.closed {
+		return errors.New("cereal: encode on closed encoder")
+	}
+
+	audit := beginAudit(e.ctx, "marshal", "json", v, e.permissions)
+	defer func() {
+		err = audit.finish(err)
+		emitMarshalEvent(e.ctx, e.modelType, e.permissions, err == nil, err)
+	}()
+
+	security := SecurityContext{UserID: IdentityFrom(e.ctx), Permissions: e.permissions}
+	if err = runSecurityActions(v, "marshal", security); err != nil {
+		return fmt.Errorf("security action failed: %w", err)
+	}
+
+	scoped, redacted, err := catalogScoper.scope(v, "json", e.permissions, audit)
+	if err != nil {
+		return err
+	}
+	if err = validateScoped(e.ctx, scoped, "json", redacted); err != nil {
+		return err
+	}
+	data, err := json.Marshal(scoped)
+	if err != nil {
+		return err
+	}
+	if data, err = stampVersion(data, v, jsonFormat); err != nil {
+		return err
+	}
+
+	var prefix, suffix string
+	switch e.framing {
+	case JSONArray:
+		prefix = ","
+		if e.count == 0 {
+			prefix = "["
+		}
+	case NDJSON:
+		suffix = "\n"
+	}
+
+	if _, err = io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	if _, err = e.w.Write(data); err != nil {
+		return err
+	}
+	if _, err = io.WriteString(e.w, suffix); err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Close terminates the stream; a JSON array is closed (or written as [] when empty).
+// It does not close the underlying writer.
+func (e *Encoder[T]) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	if e.framing != JSONArray {
+		return nil
+	}
+	terminator := "]"
+	if e.count == 0 {
+		terminator = "[]"
+	}
+	_, err := io.WriteString(e.w, terminator)
+	return err
+}
+
+// Decoder reads a stream of T from either a JSON array or NDJSON, detected
+// from the first byte. Each record runs the security actions and is
+// write-scoped and validated exactly like JSON.Unmarshal.
+type Decoder[T any] struct {
+	r           *bufio.Reader
+	dec         *json.Decoder
+	permissions []string
+	array       bool
+	done        bool
+	modelType   string
+	ctx         context.Context
+}
+
+// NewDecoder creates a streaming decoder
+func NewDecoder[T any](r io.Reader, permissions ...string) *Decoder[T] {
+	return &Decoder[T]{
+		r:           bufio.NewReader(r),
+		permissions: permissions,
+		modelType:   catalog.GetTypeName[T](),
+		ctx:         context.Background(),
+	}
+}
+
+// SetContext sets the context records are decoded in; access audits record
+// its identity and purpose once per record, and validators receive it
+func (d *Decoder[T]) SetContext(ctx context.Context) {
+	d.ctx = ctx
+}
+
+// Decode reads the next record into v. It returns io.EOF when the stream is
+// exhausted. A record that fails scoping or validation is reported and the
+// stream stays positioned at the next record.
+func (d *Decoder[T]) Decode(v *T) (err error) {
+	if d.done {
+		return io.EOF
+	}
+	if d.dec == nil {
+		if err := d.start(); err != nil {
+			return err
+		}
+		if d.done {
+			return io.EOF
+		}
+	}
+
+	if !d.dec.More() {
+		return d.finish()
+	}
+
+	var record T
+	audit := beginAudit(d.ctx, "unmarshal", "json", &record, d.permissions)
+	var raw json.RawMessage
+	err = d.dec.Decode(&raw)
+	defer func() {
+		err = audit.finish(err)
+		emitUnmarshalEvent(d.ctx, d.modelType, d.permissions, err == nil, err)
+	}()
+	if err != nil {
+		d.done = true
+		return err
+	}
+
+	security := SecurityContext{UserID: IdentityFrom(d.ctx), Permissions: d.permissions}
+	if err = runSecurityActions(&record, "unmarshal", security); err != nil {
+		return fmt.Errorf("security action failed: %w", err)
+	}
+
+	// Older records are upcast one at a time; a failure skips only this record
+	if raw, err = upcastPayload(raw, &record, jsonFormat); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(raw, &record); err != nil {
+		return err
+	}
+
+	if err = catalogScoper.enforce(&record, "json", d.permissions, audit); err != nil {
+		return err
+	}
+	if err = ValidateContext(d.ctx, &record); err != nil {
+		return err
+	}
+
+	*v = record
+	return nil
+}
+
+// start detects the framing from the first non-space byte
+func (d *Decoder[T]) start() error {
+	for {
+		b, err := d.r.Peek(1)
+		if err == io.EOF {
+			d.done = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			d.r.ReadByte()
+			continue
+		}
+		d.array = b[0] == '['
+		break
+	}
+
+	d.dec = json.NewDecoder(d.r)
+	if d.array {
+		// Consume the opening bracket
+		if _, err := d.dec.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish consumes the closing bracket of an array stream
+func (d *Decoder[T]) finish() error {
+	d.done = true
+	if d.array {
+		token, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		if token != json.Delim(']') {
+			return fmt.Errorf("cereal: unexpected token %v at end of array", token)
+		}
+	}
+	return io.EOF
+}
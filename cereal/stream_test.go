@@ -0,0 +1,174 @@
+package cereal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type streamRecord struct {
+	ID     int    `json:"id" validate:"min=1"`
+	Name   string `json:"name"`
+	Secret string `json:"secret" scope:"admin"`
+}
+
+func TestEncoder_JSONArray(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder[streamRecord](&buf, "user")
+
+	for i := 1; i <= 3; i++ {
+		if err := enc.Encode(streamRecord{ID: i, Name: "n", Secret: "s"}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Expected no error on close, got: %v", err)
+	}
+
+	var records []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatalf("Expected a valid JSON array, got %q: %v", buf.String(), err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[2]["id"] != float64(3) || records[2]["secret"] != "[REDACTED]" {
+		t.Errorf("Expected scoped record, got %v", records[2])
+	}
+}
+
+func TestEncoder_EmptyArray(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder[streamRecord](&buf)
+	enc.Close()
+
+	if buf.String() != "[]" {
+		t.Errorf("Expected [], got %q", buf.String())
+	}
+}
+
+func TestEncoder_NDJSONSkipsInvalidRecords(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder[streamRecord](&buf, "admin")
+	enc.SetFraming(NDJSON)
+
+	enc.Encode(streamRecord{ID: 1, Secret: "a"})
+	if err := enc.Encode(streamRecord{ID: 0}); err == nil {
+		t.Error("Expected validation error for id=0")
+	}
+	enc.Encode(streamRecord{ID: 2, Secret: "b"})
+	enc.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[1], `"secret":"b"`) {
+		t.Errorf("Expected admin to see secret, got %s", lines[1])
+	}
+}
+
+func TestDecoder_Framings(t *testing.T) {
+	inputs := map[string]string{
+		"array":  ` [{"id":1,"name":"a","secret":"x"}, {"id":2,"name":"b"}] `,
+		"ndjson": "{\"id\":1,\"name\":\"a\",\"secret\":\"x\"}\n{\"id\":2,\"name\":\"b\"}\n",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecoder[streamRecord](strings.NewReader(input), "user")
+
+			var got []streamRecord
+			for {
+				var record streamRecord
+				err := dec.Decode(&record)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				got = append(got, record)
+			}
+
+			if len(got) != 2 {
+				t.Fatalf("Expected 2 records, got %d", len(got))
+			}
+			if got[0].Secret != "" {
+				t.Errorf("Expected denied write to be dropped, got %q", got[0].Secret)
+			}
+			if got[1].Name != "b" {
+				t.Errorf("Expected second record name b, got %q", got[1].Name)
+			}
+		})
+	}
+}
+
+func TestDecoder_ValidationContinues(t *testing.T) {
+	dec := NewDecoder[streamRecord](strings.NewReader(`[{"id":0},{"id":5}]`))
+
+	var record streamRecord
+	if err := dec.Decode(&record); err == nil {
+		t.Error("Expected validation error for id=0")
+	}
+	if err := dec.Decode(&record); err != nil || record.ID != 5 {
+		t.Errorf("Expected next record to decode, got %v (%v)", record, err)
+	}
+	if err := dec.Decode(&record); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoder_Empty(t *testing.T) {
+	dec := NewDecoder[streamRecord](strings.NewReader("  "))
+	var record streamRecord
+	if err := dec.Decode(&record); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+type streamProbe struct {
+	Value string `json:"value" validate:"stream_probe"`
+}
+
+func TestStream_ContextAndSecurityActions(t *testing.T) {
+	var seen []string
+	RegisterValidator("stream_probe", func(ctx context.Context, field reflect.Value, param string) error {
+		seen = append(seen, "validate:"+IdentityFrom(ctx))
+		return nil
+	}, "")
+	RegisterSecurityAction("stream_probe", func(model interface{}, operation string, ctx SecurityContext) error {
+		switch model.(type) {
+		case streamProbe, *streamProbe:
+			seen = append(seen, operation+":"+ctx.UserID)
+		}
+		return nil
+	})
+	t.Cleanup(func() { delete(securityActions, "stream_probe") })
+	ctx := WithIdentity(context.Background(), "user-7")
+
+	var buf bytes.Buffer
+	enc := NewEncoder[streamProbe](&buf)
+	enc.SetContext(ctx)
+	if err := enc.Encode(streamProbe{Value: "x"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	enc.Close()
+
+	dec := NewDecoder[streamProbe](&buf)
+	dec.SetContext(ctx)
+	var record streamProbe
+	if err := dec.Decode(&record); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := []string{"marshal:user-7", "validate:user-7", "unmarshal:user-7", "validate:user-7"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Expected %v, got %v", want, seen)
+	}
+}
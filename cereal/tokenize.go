@@ -0,0 +1,508 @@
+package cereal
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"unicode"
+
+	"zbz/catalog"
+)
+
+// DetokenizePermission is the permission a caller needs to turn tokens back into values
+const DetokenizePermission = "detokenize"
+
+var (
+	// ErrDetokenizeDenied is returned when the caller lacks DetokenizePermission
+	ErrDetokenizeDenied = errors.New("detokenize requires the " + DetokenizePermission + " permission")
+	// ErrUnknownToken is returned for a token the vault never issued
+	ErrUnknownToken = errors.New("unknown token")
+	// ErrTokenTaken is returned by a vault asked to store a token already issued for another value
+	ErrTokenTaken = errors.New("token already issued")
+)
+
+// maxTokenAttempts bounds the search for a free token in a small token space
+const maxTokenAttempts = 64
+
+// TokenVault stores issued tokens. It never sees plaintext: values are
+// looked up by a keyed digest and stored sealed.
+type TokenVault interface {
+	// Lookup returns the token issued for a value digest
+	Lookup(digest string) (token string, found bool, err error)
+	// Resolve returns the sealed value a token was issued for
+	Resolve(token string) (sealed string, found bool, err error)
+	// Store records a new token; it fails with ErrTokenTaken if the token is in use
+	Store(digest, token, sealed string) error
+}
+
+// Tokenizer replaces values with stable, format-preserving tokens: the same
+// value always gets the same token, digits stay digits, letters stay letters
+// of the same case, and everything else is kept. A card number stays 16
+// digits (and Luhn-valid), an email stays an email.
+type Tokenizer struct {
+	mu       sync.Mutex
+	macKey   []byte
+	sealKey  []byte
+	tokenKey []byte
+	vault    TokenVault
+}
+
+// NewTokenizer creates a tokenizer from a secret of at least 32 bytes. The
+// same secret and vault must be used for tokens to stay stable.
+func NewTokenizer(secret []byte, vault TokenVault) (*Tokenizer, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("tokenizer secret must be at least 32 bytes, got %d", len(secret))
+	}
+	if vault == nil {
+		return nil, fmt.Errorf("tokenizer vault cannot be nil")
+	}
+	return &Tokenizer{
+		macKey:   deriveTokenKey(secret, "digest"),
+		sealKey:  deriveTokenKey(secret, "seal"),
+		tokenKey: deriveTokenKey(secret, "token"),
+		vault:    vault,
+	}, nil
+}
+
+// deriveTokenKey derives an independent key for one purpose from the secret
+func deriveTokenKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("cereal-tokenize:" + purpose))
+	return mac.Sum(nil)
+}
+
+// Tokenize returns the token for value, issuing one on first use
+func (t *Tokenizer) Tokenize(value string) (string, error) {
+	digest := t.digest(value)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	existing, found, err := t.vault.Lookup(digest)
+	if err != nil || found {
+		return existing, err
+	}
+
+	sealed, err := t.seal(value)
+	if err != nil {
+		return "", err
+	}
+	for attempt := 0; attempt < maxTokenAttempts; attempt++ {
+		token := t.candidate(value, attempt)
+		if token == value && hasTokenizable(value) {
+			continue
+		}
+		err := t.vault.Store(digest, token, sealed)
+		if errors.Is(err, ErrTokenTaken) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return token, nil
+	}
+	return "", fmt.Errorf("no free token for a %d character value after %d attempts", len([]rune(value)), maxTokenAttempts)
+}
+
+// Detokenize returns the value a token was issued for. The caller needs
+// DetokenizePermission.
+func (t *Tokenizer) Detokenize(token string, permissions ...string) (string, error) {
+	if !slices.Contains(permissions, DetokenizePermission) {
+		return "", ErrDetokenizeDenied
+	}
+
+	sealed, found, err := t.vault.Resolve(token)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", ErrUnknownToken
+	}
+	return t.open(sealed)
+}
+
+// DetokenizeFields replaces, in place, every redact:"tokenize" string field
+// of v holding a known token with its value, at any depth. v must be a
+// pointer. Fields holding anything else are left alone.
+func (t *Tokenizer) DetokenizeFields(v any, permissions ...string) error {
+	if !slices.Contains(permissions, DetokenizePermission) {
+		return ErrDetokenizeDenied
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("detokenize fields requires a non-nil pointer, got %T", v)
+	}
+	return t.detokenizeValue(value, catalog.ExtractAndCacheMetadata(v).Fields, permissions)
+}
+
+func (t *Tokenizer) detokenizeValue(v reflect.Value, fields []catalog.FieldMetadata, permissions []string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return t.detokenizeValue(v.Elem(), fields, permissions)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := t.detokenizeValue(v.Index(i), fields, permissions); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	if !hasExportedFields(v.Type()) {
+		return nil
+	}
+	if fields == nil {
+		fields = structFields(v.Type())
+	}
+	for _, field := range fields {
+		target, err := v.FieldByIndexErr(field.Index)
+		if err != nil || !target.CanSet() {
+			continue // Behind a nil embedded pointer
+		}
+		if field.Redaction.Strategy != "tokenize" || target.Kind() != reflect.String {
+			if err := t.detokenizeValue(target, field.Fields, permissions); err != nil {
+				return err
+			}
+			continue
+		}
+		if target.String() == "" {
+			continue
+		}
+		value, err := t.Detokenize(target.String(), permissions...)
+		if errors.Is(err, ErrUnknownToken) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		target.SetString(value)
+	}
+	return nil
+}
+
+// digest is the keyed lookup key for a value
+func (t *Tokenizer) digest(value string) string {
+	mac := hmac.New(sha256.New, t.macKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// candidate derives the token for value on the given attempt; later
+// attempts resolve collisions in small token spaces
+func (t *Tokenizer) candidate(value string, attempt int) string {
+	runes := []rune(value)
+	stream := t.keystream(value, attempt, len(runes))
+
+	token := make([]rune, len(runes))
+	for i, r := range runes {
+		b := int(stream[i])
+		switch {
+		case r >= '0' && r <= '9':
+			token[i] = '0' + rune(b%10)
+		case r >= 'a' && r <= 'z':
+			token[i] = 'a' + rune(b%26)
+		case r >= 'A' && r <= 'Z':
+			token[i] = 'A' + rune(b%26)
+		case unicode.IsLetter(r) && unicode.IsLower(r):
+			token[i] = 'a' + rune(b%26)
+		case unicode.IsLetter(r):
+			token[i] = 'A' + rune(b%26)
+		default:
+			token[i] = r
+		}
+	}
+	if luhnValid(runes) {
+		fixLuhn(token)
+	}
+	return string(token)
+}
+
+// keystream expands HMAC(value) to n bytes
+func (t *Tokenizer) keystream(value string, attempt, n int) []byte {
+	stream := make([]byte, 0, n+sha256.Size)
+	for block := 0; len(stream) < n; block++ {
+		mac := hmac.New(sha256.New, t.tokenKey)
+		var header [8]byte
+		binary.BigEndian.PutUint32(header[:4], uint32(attempt))
+		binary.BigEndian.PutUint32(header[4:], uint32(block))
+		mac.Write(header[:])
+		mac.Write([]byte(value))
+		stream = mac.Sum(stream)
+	}
+	return stream
+}
+
+// seal encrypts a value for the vault
+func (t *Tokenizer) seal(value string) (string, error) {
+	gcm, err := t.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+// open decrypts a value sealed by seal
+func (t *Tokenizer) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("decode sealed value: %w", err)
+	}
+	gcm, err := t.cipher()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("sealed value too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("open sealed value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (t *Tokenizer) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(t.sealKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// hasTokenizable reports whether value has any character a token replaces
+func hasTokenizable(value string) bool {
+	for _, r := range value {
+		if unicode.IsLetter(r) || (r >= '0' && r <= '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether runes look like a card number: 12 to 19
+// digits, optionally grouped with spaces or dashes, passing the Luhn check
+func luhnValid(runes []rune) bool {
+	sum, digits := 0, 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		r := runes[i]
+		if r == ' ' || r == '-' {
+			continue
+		}
+		if r < '0' || r > '9' {
+			return false
+		}
+		d := int(r - '0')
+		if digits%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 12 && digits <= 19 && sum%10 == 0
+}
+
+// fixLuhn rewrites the last digit so the token passes the Luhn check
+func fixLuhn(token []rune) {
+	last := -1
+	for i := len(token) - 1; i >= 0; i-- {
+		if token[i] >= '0' && token[i] <= '9' {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return
+	}
+	for d := '0'; d <= '9'; d++ {
+		token[last] = d
+		if luhnValid(token) {
+			return
+		}
+	}
+}
+
+// MemoryTokenVault keeps tokens in memory; they are lost when the process exits
+type MemoryTokenVault struct {
+	mu     sync.RWMutex
+	tokens map[string]string // digest -> token
+	sealed map[string]string // token -> sealed value
+}
+
+// NewMemoryTokenVault creates an empty in-memory vault
+func NewMemoryTokenVault() *MemoryTokenVault {
+	return &MemoryTokenVault{tokens: make(map[string]string), sealed: make(map[string]string)}
+}
+
+// Lookup implements TokenVault
+func (v *MemoryTokenVault) Lookup(digest string) (string, bool, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	token, found := v.tokens[digest]
+	return token, found, nil
+}
+
+// Resolve implements TokenVault
+func (v *MemoryTokenVault) Resolve(token string) (string, bool, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	sealed, found := v.sealed[token]
+	return sealed, found, nil
+}
+
+// Store implements TokenVault
+func (v *MemoryTokenVault) Store(digest, token, sealed string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.store(digest, token, sealed)
+}
+
+func (v *MemoryTokenVault) store(digest, token, sealed string) error {
+	if _, taken := v.sealed[token]; taken {
+		return ErrTokenTaken
+	}
+	v.tokens[digest] = token
+	v.sealed[token] = sealed
+	return nil
+}
+
+// FileTokenVault is a TokenVault persisted as an append-only file of JSON
+// lines with owner-only permissions. Each new token is synced to disk
+// before it is handed out.
+type FileTokenVault struct {
+	memory *MemoryTokenVault
+	file   *os.File
+}
+
+// tokenEntry is one line of a FileTokenVault
+type tokenEntry struct {
+	Digest string `json:"digest"`
+	Token  string `json:"token"`
+	Sealed string `json:"sealed"`
+}
+
+// NewFileTokenVault loads the vault at path, creating it if it does not exist
+func NewFileTokenVault(path string) (*FileTokenVault, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open token vault: %w", err)
+	}
+
+	vault := &FileTokenVault{memory: NewMemoryTokenVault(), file: file}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry tokenEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("parse token vault %s line %d: %w", path, line, err)
+		}
+		if err := vault.memory.store(entry.Digest, entry.Token, entry.Sealed); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("parse token vault %s line %d: %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read token vault: %w", err)
+	}
+	return vault, nil
+}
+
+// Lookup implements TokenVault
+func (v *FileTokenVault) Lookup(digest string) (string, bool, error) {
+	return v.memory.Lookup(digest)
+}
+
+// Resolve implements TokenVault
+func (v *FileTokenVault) Resolve(token string) (string, bool, error) {
+	return v.memory.Resolve(token)
+}
+
+// Store implements TokenVault
+func (v *FileTokenVault) Store(digest, token, sealed string) error {
+	v.memory.mu.Lock()
+	defer v.memory.mu.Unlock()
+
+	if _, taken := v.memory.sealed[token]; taken {
+		return ErrTokenTaken
+	}
+	line, err := json.Marshal(tokenEntry{Digest: digest, Token: token, Sealed: sealed})
+	if err != nil {
+		return err
+	}
+	if _, err := v.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	if err := v.file.Sync(); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	return v.memory.store(digest, token, sealed)
+}
+
+// Close closes the underlying file
+func (v *FileTokenVault) Close() error {
+	return v.file.Close()
+}
+
+var (
+	tokenizer   *Tokenizer
+	tokenizerMu sync.RWMutex
+)
+
+// SetTokenizer installs the tokenizer used by redact:"tokenize" fields.
+// Without one, those fields fall back to the default redaction.
+func SetTokenizer(t *Tokenizer) {
+	tokenizerMu.Lock()
+	defer tokenizerMu.Unlock()
+	tokenizer = t
+}
+
+// GetTokenizer returns the installed tokenizer, or nil
+func GetTokenizer() *Tokenizer {
+	tokenizerMu.RLock()
+	defer tokenizerMu.RUnlock()
+	return tokenizer
+}
+
+// tokenizeField returns the token for a redact:"tokenize" string field;
+// false means the field can't be tokenized and is redacted as usual
+func tokenizeField(field Field) (string, bool) {
+	value, ok := field.Value.(string)
+	t := GetTokenizer()
+	if !ok || t == nil {
+		return "", false
+	}
+	if value == "" {
+		return "", true
+	}
+	token, err := t.Tokenize(value)
+	if err != nil {
+		return "", false
+	}
+	return token, true
+}
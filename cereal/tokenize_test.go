@@ -0,0 +1,171 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var tokenSecret = bytes.Repeat([]byte("k"), 32)
+
+type tokenizedCustomer struct {
+	Name  string `json:"name"`
+	Email string `json:"email" scope:"support" redact:"tokenize" validate:"email"`
+	Card  string `json:"card" encrypt:"financial" redact:"tokenize" validate:"credit_card"`
+	SSN   string `json:"ssn" encrypt:"pii" redact:"tokenize"`
+}
+
+func newTestTokenizer(t *testing.T) *Tokenizer {
+	tokenizer, err := NewTokenizer(tokenSecret, NewMemoryTokenVault())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return tokenizer
+}
+
+func TestTokenizer_FormatPreserving(t *testing.T) {
+	tokenizer := newTestTokenizer(t)
+
+	tests := []struct {
+		value string
+		shape *regexp.Regexp
+	}{
+		{"4111111111111111", regexp.MustCompile(`^\d{16}$`)},
+		{"123-45-6789", regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)},
+		{"Ada.Lovelace@example.com", regexp.MustCompile(`^[A-Z][a-z]{2}\.[A-Z][a-z]{7}@[a-z]{7}\.[a-z]{3}$`)},
+	}
+	for _, tt := range tests {
+		token, err := tokenizer.Tokenize(tt.value)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if token == tt.value || !tt.shape.MatchString(token) {
+			t.Errorf("Expected %q to keep its format, got %q", tt.value, token)
+		}
+		if again, _ := tokenizer.Tokenize(tt.value); again != token {
+			t.Errorf("Expected a stable token for %q, got %q then %q", tt.value, token, again)
+		}
+	}
+
+	card, _ := tokenizer.Tokenize("4111111111111111")
+	if !luhnValid([]rune(card)) {
+		t.Errorf("Expected card token %s to pass the Luhn check", card)
+	}
+}
+
+func TestTokenizer_SmallSpaceHasNoCollisions(t *testing.T) {
+	tokenizer := newTestTokenizer(t)
+
+	seen := map[string]string{}
+	for i := 0; i < 50; i++ {
+		value := fmt.Sprintf("%02d", i)
+		token, err := tokenizer.Tokenize(value)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if other, exists := seen[token]; exists {
+			t.Fatalf("Expected unique tokens, %s and %s both got %s", other, value, token)
+		}
+		seen[token] = value
+	}
+}
+
+func TestTokenizer_Detokenize(t *testing.T) {
+	tokenizer := newTestTokenizer(t)
+	token, _ := tokenizer.Tokenize("ada@example.com")
+
+	if _, err := tokenizer.Detokenize(token, "analytics"); !errors.Is(err, ErrDetokenizeDenied) {
+		t.Errorf("Expected ErrDetokenizeDenied, got: %v", err)
+	}
+	if value, err := tokenizer.Detokenize(token, DetokenizePermission); err != nil || value != "ada@example.com" {
+		t.Errorf("Expected the original value, got %q (%v)", value, err)
+	}
+	if _, err := tokenizer.Detokenize("nobody@example.com", DetokenizePermission); !errors.Is(err, ErrUnknownToken) {
+		t.Errorf("Expected ErrUnknownToken, got: %v", err)
+	}
+}
+
+func TestFileTokenVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.jsonl")
+
+	vault, err := NewFileTokenVault(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	tokenizer, _ := NewTokenizer(tokenSecret, vault)
+	token, err := tokenizer.Tokenize("123-45-6789")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	vault.Close()
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("123-45-6789")) {
+		t.Error("Expected the vault not to hold plaintext")
+	}
+
+	reopened, err := NewFileTokenVault(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer reopened.Close()
+	tokenizer, _ = NewTokenizer(tokenSecret, reopened)
+	if again, _ := tokenizer.Tokenize("123-45-6789"); again != token {
+		t.Errorf("Expected token %s after reopening, got %s", token, again)
+	}
+	if value, err := tokenizer.Detokenize(token, DetokenizePermission); err != nil || value != "123-45-6789" {
+		t.Errorf("Expected the original value, got %q (%v)", value, err)
+	}
+}
+
+func TestMarshal_Tokenize(t *testing.T) {
+	customer := tokenizedCustomer{Name: "Ada", Email: "ada@example.com", Card: "4111111111111111", SSN: "123-45-6789"}
+
+	// Without a tokenizer, tokenized fields are redacted as usual
+	data, err := JSON.Marshal(customer, "analytics")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var redacted tokenizedCustomer
+	json.Unmarshal(data, &redacted)
+	if redacted.SSN != "[PII_REDACTED]" {
+		t.Errorf("Expected default redaction without a tokenizer, got %+v", redacted)
+	}
+
+	tokenizer := newTestTokenizer(t)
+	SetTokenizer(tokenizer)
+	t.Cleanup(func() { SetTokenizer(nil) })
+
+	first, err := JSON.Marshal(customer, "analytics")
+	if err != nil {
+		t.Fatalf("Expected tokens to pass validation, got: %v", err)
+	}
+	second, _ := JSON.Marshal(customer, "analytics")
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected stable pseudonyms, got %s then %s", first, second)
+	}
+
+	var pseudonymized tokenizedCustomer
+	json.Unmarshal(first, &pseudonymized)
+	if pseudonymized.Name != "Ada" || pseudonymized.Email == customer.Email || pseudonymized.Card == customer.Card || pseudonymized.SSN == customer.SSN {
+		t.Errorf("Expected sensitive fields to be tokenized, got %+v", pseudonymized)
+	}
+	if len(pseudonymized.Card) != 16 {
+		t.Errorf("Expected a 16 digit card token, got %q", pseudonymized.Card)
+	}
+
+	if err := tokenizer.DetokenizeFields(&pseudonymized, "analytics"); !errors.Is(err, ErrDetokenizeDenied) {
+		t.Errorf("Expected ErrDetokenizeDenied, got: %v", err)
+	}
+	if err := tokenizer.DetokenizeFields(&pseudonymized, DetokenizePermission); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if pseudonymized != customer {
+		t.Errorf("Expected %+v after detokenizing, got %+v", customer, pseudonymized)
+	}
+}
@@ -1,8 +1,11 @@
 package cereal
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -12,6 +15,12 @@ type Validator interface {
 	Validate(s interface{}) error
 }
 
+// ContextValidator is a Validator whose rules can read a context
+type ContextValidator interface {
+	Validator
+	ValidateContext(ctx context.Context, s interface{}) error
+}
+
 // DefaultValidator using go-playground/validator
 type DefaultValidator struct {
 	validate *validator.Validate
@@ -29,6 +38,11 @@ func (v *DefaultValidator) Validate(s interface{}) error {
 	return v.validate.Struct(s)
 }
 
+// ValidateContext validates a struct, passing ctx to custom and struct-level validators
+func (v *DefaultValidator) ValidateContext(ctx context.Context, s interface{}) error {
+	return v.validate.StructCtx(ctx, s)
+}
+
 // Global validator instance
 var globalValidator Validator = NewDefaultValidator()
 
@@ -39,6 +53,13 @@ func SetValidator(v Validator) {
 
 // Validate validates a struct using the global validator
 func Validate(s interface{}) error {
+	return ValidateContext(context.Background(), s)
+}
+
+// ValidateContext validates a struct using the global validator. ctx reaches
+// custom and struct-level validators, and its locale (see WithLocale) picks
+// the language of the messages. Failed rules come back as ValidationErrors.
+func ValidateContext(ctx context.Context, s interface{}) error {
 	if globalValidator == nil {
 		return nil // No validation if no validator set
 	}
@@ -57,77 +78,217 @@ func Validate(s interface{}) error {
 	}
 
 	// Run struct tag validation (custom validators handle their own business logic)
-	return globalValidator.Validate(s)
+	var err error
+	if cv, ok := globalValidator.(ContextValidator); ok {
+		err = cv.ValidateContext(ctx, s)
+	} else {
+		err = globalValidator.Validate(s)
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	return formatFieldErrors(ctx, rv.Type(), fieldErrs)
+}
+
+// validateScoped validates a scoped copy, dropping failures on values the
+// scope walker redacted: placeholders such as a denied false boolean are not
+// the caller's data and may not satisfy the original rules
+func validateScoped(ctx context.Context, scoped any, format string, redacted map[string]bool) error {
+	err := ValidateContext(ctx, scoped)
+	var failures ValidationErrors
+	if len(redacted) == 0 || !errors.As(err, &failures) {
+		return err
+	}
+
+	t := reflect.TypeOf(scoped)
+	var kept ValidationErrors
+	for _, failure := range failures {
+		if !underRedacted(namespacePath(t, failure.namespace, format), redacted) {
+			kept = append(kept, failure)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// underRedacted reports whether path is a redacted value or lies inside one
+func underRedacted(path string, redacted map[string]bool) bool {
+	for {
+		if redacted[path] {
+			return true
+		}
+		cut := strings.LastIndexAny(path, ".[")
+		if cut < 0 {
+			return false
+		}
+		path = path[:cut]
+	}
 }
 
 // ValidationError wraps validation errors with context
 type ValidationError struct {
 	Field   string `json:"field"`
+	Pointer string `json:"pointer,omitempty"` // JSON pointer to the field, e.g. /items/0/price
 	Value   string `json:"value"`
 	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
 	Message string `json:"message"`
+
+	namespace string // Validator namespace, e.g. Order.Items[0].Price
 }
 
 func (e ValidationError) Error() string {
 	return fmt.Sprintf("validation failed on field '%s' with tag '%s': %s", e.Field, e.Tag, e.Message)
 }
 
+// ValidationErrors lists every rule a value failed
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
 // FormatValidationErrors converts validator errors to our format
 func FormatValidationErrors(err error) []ValidationError {
-	var errors []ValidationError
+	var formatted ValidationErrors
+	if errors.As(err, &formatted) {
+		return formatted
+	}
 	
-	if validationErrs, ok := err.(validator.ValidationErrors); ok {
-		for _, e := range validationErrs {
-			errors = append(errors, ValidationError{
-				Field:   e.Field(),
-				Value:   fmt.Sprintf("%v", e.Value()),
-				Tag:     e.Tag(),
-				Message: getErrorMessage(e),
-			})
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return formatFieldErrors(context.Background(), nil, validationErrs)
+	}
+	return nil
+}
+
+// formatFieldErrors converts validator errors, pointing them into t (when
+// known) and localizing messages for the context's locale
+func formatFieldErrors(ctx context.Context, t reflect.Type, fieldErrs validator.ValidationErrors) ValidationErrors {
+	locale := LocaleFrom(ctx)
+	formatted := make(ValidationErrors, 0, len(fieldErrs))
+	for _, e := range fieldErrs {
+		err := ValidationError{
+			Field: e.Field(),
+			Value: fmt.Sprintf("%v", e.Value()),
+			Tag:   e.Tag(),
+			Param: e.Param(),
+		}
+		if t != nil {
+			err.Pointer = validationPointer(t, e.StructNamespace())
 		}
+		err.namespace = e.StructNamespace()
+		err.Message = Message(locale, err)
+		formatted = append(formatted, err)
 	}
-	
-	return errors
-}
-
-// getErrorMessage provides human-readable error messages
-func getErrorMessage(e validator.FieldError) string {
-	switch e.Tag() {
-	case "required":
-		return "field is required"
-	case "email":
-		return "must be a valid email address"
-	case "min":
-		return fmt.Sprintf("must be at least %s characters long", e.Param())
-	case "max":
-		return fmt.Sprintf("must be at most %s characters long", e.Param())
-	case "len":
-		return fmt.Sprintf("must be exactly %s characters long", e.Param())
-	case "gt":
-		return fmt.Sprintf("must be greater than %s", e.Param())
-	case "gte":
-		return fmt.Sprintf("must be greater than or equal to %s", e.Param())
-	case "lt":
-		return fmt.Sprintf("must be less than %s", e.Param())
-	case "lte":
-		return fmt.Sprintf("must be less than or equal to %s", e.Param())
-	case "oneof":
-		return fmt.Sprintf("must be one of: %s", e.Param())
-	case "url":
-		return "must be a valid URL"
-	case "uri":
-		return "must be a valid URI"
-	case "alpha":
-		return "must contain only alphabetic characters"
-	case "alphanum":
-		return "must contain only alphanumeric characters"
-	case "numeric":
-		return "must be a valid number"
-	case "uuid":
-		return "must be a valid UUID"
-	case "json":
-		return "must be valid JSON"
-	default:
-		return fmt.Sprintf("validation failed for tag '%s'", e.Tag())
-	}
-}
\ No newline at end of file
+	return formatted
+}
+
+// validationPointer turns a validator namespace such as
+// "Order.Items[0].Price" into a JSON pointer such as "/items/0/price",
+// following t to find each field's json name
+func validationPointer(t reflect.Type, namespace string) string {
+	var pointer strings.Builder
+	for _, step := range namespaceSteps(t, namespace, "json") {
+		pointer.WriteString("/" + escapePointerToken(step.name))
+	}
+	return pointer.String()
+}
+
+// namespacePath turns a validator namespace into the field path the scope
+// walker reports for format, such as "items[0].price"
+func namespacePath(t reflect.Type, namespace, format string) string {
+	path := ""
+	for _, step := range namespaceSteps(t, namespace, format) {
+		if step.index {
+			path += "[" + step.name + "]"
+		} else {
+			path = joinFieldPath(path, step.name)
+		}
+	}
+	return path
+}
+
+// namespaceStep is one field name or element key of a validator namespace
+type namespaceStep struct {
+	name  string
+	index bool // slice or array index; map keys are named like fields
+}
+
+// namespaceSteps follows a validator namespace through t, naming each struct
+// field the way format encodes it. The leading type name is dropped.
+func namespaceSteps(t reflect.Type, namespace, format string) []namespaceStep {
+	segments := splitNamespace(namespace)
+	if len(segments) == 0 {
+		return nil
+	}
+
+	var steps []namespaceStep
+	for _, segment := range segments[1:] {
+		name, keys, _ := strings.Cut(segment, "[")
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		if t.Kind() == reflect.Struct && name != "" {
+			if field, ok := t.FieldByName(name); ok {
+				if formatted, _ := formatFieldName(field, format); formatted != "" {
+					steps = append(steps, namespaceStep{name: formatted})
+				}
+				t = field.Type
+			} else {
+				steps = append(steps, namespaceStep{name: name})
+			}
+		}
+
+		// Element keys follow the name: Items[0], Labels[gift], Grid[1][2]
+		for keys != "" {
+			key, rest, _ := strings.Cut(keys, "]")
+			keys = strings.TrimPrefix(rest, "[")
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			step := namespaceStep{name: key}
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array:
+				step.index = true
+				t = t.Elem()
+			case reflect.Map:
+				t = t.Elem()
+			}
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// splitNamespace splits a validator namespace on dots outside map keys
+func splitNamespace(namespace string) []string {
+	var segments []string
+	depth, start := 0, 0
+	for i, r := range namespace {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segments = append(segments, namespace[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(namespace) {
+		segments = append(segments, namespace[start:])
+	}
+	return segments
+}
@@ -1,6 +1,10 @@
 package cereal
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -153,4 +157,143 @@ func TestValidation_NonStructTypes(t *testing.T) {
 	if result != data {
 		t.Errorf("Expected %s, got %s", data, result)
 	}
-}
\ No newline at end of file
+}
+
+type shipmentLine struct {
+	SKU string `json:"sku" validate:"required"`
+	Qty int    `json:"qty" validate:"gte=1"`
+}
+
+type shipmentParty struct {
+	Name string `json:"name"`
+}
+
+type shipment struct {
+	shipmentParty `json:",inline"`
+
+	Status   string                  `json:"status" validate:"oneof=draft shipped"`
+	Carrier  string                  `json:"carrier" validate:"required_if=Status shipped"`
+	Tracking string                  `json:"tracking_no" validate:"excluded_unless=Status shipped"`
+	Lines    []shipmentLine          `json:"lines" validate:"dive"`
+	Extras   map[string]shipmentLine `json:"extras" validate:"dive"`
+}
+
+func TestValidateContext_Pointers(t *testing.T) {
+	value := shipment{
+		Status:   "draft",
+		Tracking: "1Z999",
+		Lines:    []shipmentLine{{SKU: "a", Qty: 1}, {SKU: "", Qty: 0}},
+		Extras:   map[string]shipmentLine{"gift.wrap": {SKU: "w", Qty: 0}},
+	}
+
+	err := ValidateContext(context.Background(), &value)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Expected ValidationErrors, got %T %v", err, err)
+	}
+
+	pointers := map[string]string{}
+	for _, e := range validationErrs {
+		pointers[e.Pointer] = e.Tag
+	}
+	expected := map[string]string{
+		"/tracking_no":          "excluded_unless",
+		"/lines/1/sku":          "required",
+		"/lines/1/qty":          "gte",
+		"/extras/gift.wrap/qty": "gte",
+	}
+	if !reflect.DeepEqual(pointers, expected) {
+		t.Errorf("Expected %v, got %v", expected, pointers)
+	}
+
+	value.Status, value.Tracking, value.Lines, value.Extras = "shipped", "1Z999", nil, nil
+	err = Validate(value)
+	if formatted := FormatValidationErrors(err); len(formatted) != 1 || formatted[0].Pointer != "/carrier" || formatted[0].Message != "is required when Status shipped" {
+		t.Errorf("Expected required_if error at /carrier, got %+v", formatted)
+	}
+}
+
+type tenantInvoice struct {
+	Tenant string `json:"tenant"`
+	Amount int    `json:"amount"`
+}
+
+type invoiceBatch struct {
+	Invoices []tenantInvoice `json:"invoices" validate:"dive"`
+}
+
+func TestValidateContext_StructValidator(t *testing.T) {
+	err := RegisterStructValidator(func(ctx context.Context, invoice tenantInvoice, report func(field, tag, param string)) {
+		if invoice.Tenant != TenantFrom(ctx) {
+			report("Tenant", "tenant", TenantFrom(ctx))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected no error registering, got: %v", err)
+	}
+
+	batch := invoiceBatch{Invoices: []tenantInvoice{{Tenant: "acme", Amount: 1}, {Tenant: "globex", Amount: 2}}}
+	err = ValidateContext(WithTenant(context.Background(), "acme"), batch)
+	formatted := FormatValidationErrors(err)
+	if len(formatted) != 1 || formatted[0].Pointer != "/invoices/1/tenant" || formatted[0].Param != "acme" {
+		t.Errorf("Expected one tenant error at /invoices/1/tenant, got %+v", formatted)
+	}
+
+	if err := RegisterStructValidator[*tenantInvoice](func(context.Context, *tenantInvoice, func(string, string, string)) {}); err == nil {
+		t.Error("Expected pointer type to be rejected")
+	}
+}
+
+type localizedCatalog map[string]string
+
+func (c localizedCatalog) Message(locale string, err ValidationError) (string, bool) {
+	message, ok := c[locale+"/"+err.Tag]
+	return message, ok
+}
+
+func TestValidateContext_LocalizedMessages(t *testing.T) {
+	RegisterMessages("fr", map[string]string{
+		"required": "champ obligatoire",
+		"min":      "doit contenir au moins {param} caractères",
+	})
+
+	user := User{Name: "A", Email: ""}
+	err := ValidateContext(WithLocale(context.Background(), "fr_CA"), user)
+	messages := map[string]string{}
+	for _, e := range FormatValidationErrors(err) {
+		messages[e.Pointer] = e.Message
+	}
+	if messages["/name"] != "doit contenir au moins 2 caractères" || messages["/email"] != "champ obligatoire" {
+		t.Errorf("Expected French messages through the fr fallback, got %v", messages)
+	}
+
+	// Tags without a translation fall back to English
+	if message := Message("fr", ValidationError{Tag: "email"}); message != "must be a valid email address" {
+		t.Errorf("Expected English fallback, got %q", message)
+	}
+
+	SetMessageCatalog(localizedCatalog{"de/required": "Pflichtfeld"})
+	defer SetMessageCatalog(nil)
+	err = ValidateContext(WithLocale(context.Background(), "de"), user)
+	if !strings.Contains(err.Error(), "Pflichtfeld") {
+		t.Errorf("Expected custom catalog message, got %v", err)
+	}
+}
+
+func TestValidateContext_CustomValidatorReceivesContext(t *testing.T) {
+	var identity string
+	RegisterValidator("context_probe", func(ctx context.Context, field reflect.Value, param string) error {
+		identity = IdentityFrom(ctx)
+		return nil
+	}, "")
+
+	type probe struct {
+		Value string `json:"value" validate:"context_probe"`
+	}
+	if err := ValidateContext(WithIdentity(context.Background(), "user-7"), probe{Value: "x"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if identity != "user-7" {
+		t.Errorf("Expected validator to see identity user-7, got %q", identity)
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"zbz/catalog"
@@ -21,6 +22,9 @@ type CoreService interface {
 	DeleteByURI(ctx context.Context, uri ResourceURI) error
 	ExistsByURI(ctx context.Context, uri ResourceURI) (bool, error)
 	CountByURI(ctx context.Context, pattern ResourceURI) (int64, error)
+	NewData() any                                 // Pointer to a zero T, for decoding request bodies
+	WrapData(data any) (ZbzModelInterface, error) // Wraps a T or *T, e.g. one decoded into NewData()
+	UpdateData(ctx context.Context, uri ResourceURI, data any, permissions ...string) (ZbzModelInterface, error) // Wraps data applied over the stored T, keeping fields the caller cannot see
 	
 	// Chain operations (non-generic)
 	GetChainByName(ctx context.Context, chainName string, params map[string]any) (ZbzModelInterface, error)
@@ -94,6 +98,11 @@ func (c *zCore) RegisterCore(typeName string, coreService CoreService) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
+	return c.registerCore(typeName, coreService)
+}
+
+// registerCore registers a core with c.mu held
+func (c *zCore) registerCore(typeName string, coreService CoreService) error {
 	// Register the core instance
 	c.registry[typeName] = coreService
 	
@@ -108,6 +117,43 @@ func (c *zCore) RegisterCore(typeName string, coreService CoreService) error {
 	return nil
 }
 
+// RegisterProvider makes a provider serve every resource URI with the given scheme
+func (c *zCore) RegisterProvider(scheme string, provider universal.Provider) error {
+	if scheme == "" || provider == nil {
+		return fmt.Errorf("provider registration needs a scheme and a provider")
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.providers[scheme] = provider
+	return nil
+}
+
+// GetProvider returns the provider registered for a scheme
+func (c *zCore) GetProvider(scheme string) (universal.Provider, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	provider, exists := c.providers[scheme]
+	if !exists {
+		return nil, fmt.Errorf("%w for scheme %s", ErrNoProvider, scheme)
+	}
+	return provider, nil
+}
+
+// Providers returns the registered providers by scheme
+func (c *zCore) Providers() map[string]universal.Provider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	providers := make(map[string]universal.Provider, len(c.providers))
+	for scheme, provider := range c.providers {
+		providers[scheme] = provider
+	}
+	return providers
+}
+
 // GetCoreServiceByTypeName retrieves a core service by type name (for cross-service access)
 func (c *zCore) GetCoreServiceByTypeName(typeName string) (CoreService, error) {
 	c.mu.RLock()
@@ -128,6 +174,15 @@ func (c *zCore) GetAPIContract(method, path string) (APIContract, CoreService, e
 	defer c.mu.RUnlock()
 	
 	contract, exists := c.apiRegistry[contractKey]
+	if !exists {
+		// Fall back to endpoint templates such as /api/users/{id}
+		for key, candidate := range c.apiRegistry {
+			if candidate.Method == method && matchEndpoint(candidate.Endpoint, path) {
+				contract, contractKey, exists = candidate, key, true
+				break
+			}
+		}
+	}
 	if !exists {
 		return APIContract{}, nil, fmt.Errorf("no contract found for %s", contractKey)
 	}
@@ -138,6 +193,23 @@ func (c *zCore) GetAPIContract(method, path string) (APIContract, CoreService, e
 	return contract, coreService, nil
 }
 
+// matchEndpoint reports whether path fits an endpoint whose {param}
+// segments match any single path segment
+func matchEndpoint(endpoint, path string) bool {
+	endpointParts := strings.Split(strings.Trim(endpoint, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(endpointParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range endpointParts {
+		isParam := strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")
+		if (isParam && pathParts[i] == "") || (!isParam && part != pathParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // ListCoreTypes returns all registered core type names
 func (c *zCore) ListCoreTypes() []string {
 	c.mu.RLock()
@@ -182,6 +254,12 @@ func GetAPIContract(method, path string) (APIContract, CoreService, error) {
 	return Service().GetAPIContract(method, path)
 }
 
+// RegisterProvider makes a provider serve every resource URI with the given
+// scheme, e.g. "db" for db://users/123
+func RegisterProvider(scheme string, provider universal.Provider) error {
+	return Service().RegisterProvider(scheme, provider)
+}
+
 // GetCoreServiceByTypeName gets a core service by string type name (for cross-service access)
 func GetCoreServiceByTypeName(typeName string) (CoreService, error) {
 	return Service().GetCoreServiceByTypeName(typeName)
@@ -243,6 +321,15 @@ func Count[T any](pattern ResourceURI) (int64, error) {
 	return core.Count(pattern)
 }
 
+// Update applies mutate to a resource, retrying on version conflicts
+func Update[T any](resource ResourceURI, mutate func(*ZbzModel[T]) error) (ZbzModel[T], error) {
+	core, err := GetCore[T]()
+	if err != nil {
+		return ZbzModel[T]{}, err
+	}
+	return core.Update(resource, mutate)
+}
+
 // Execute runs a complex operation
 func Execute[T any](operation OperationURI, params any) (any, error) {
 	core, err := GetCore[T]()
@@ -303,9 +390,13 @@ func GetCore[T any]() (Core[T], error) {
 	
 	// Create and register new core
 	newCore := NewCore[T]()
+	coreService, ok := newCore.(CoreService)
+	if !ok {
+		return nil, fmt.Errorf("core for type %s does not implement CoreService interface", typeName)
+	}
 	
-	// Register the core with the service
-	if err := RegisterCore(newCore); err != nil {
+	// Register the core with the service; the write lock is already held
+	if err := service.registerCore(typeName, coreService); err != nil {
 		return nil, fmt.Errorf("failed to register core: %w", err)
 	}
 	
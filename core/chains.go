@@ -142,7 +142,8 @@ func (c *coreImpl[T]) writeThroughBoth(chain ResourceChain, data ZbzModel[T], pa
 		return err
 	}
 	
-	// Write to caches (best effort)
+	// Write to caches (best effort); they copy the primary, whatever version they hold
+	data.ExpectVersion(0)
 	for _, fallback := range chain.Fallbacks {
 		templatedURI := fallback.WithParams(params)
 		go func(uri ResourceURI) {
@@ -193,6 +194,7 @@ func (c *coreImpl[T]) writeAndInvalidate(chain ResourceChain, data ZbzModel[T],
 
 func (c *coreImpl[T]) populateUpstreamCaches(chain ResourceChain, foundAtIndex int, foundURI ResourceURI, data ZbzModel[T], params map[string]any) {
 	// Populate caches that come before the one where we found the data
+	data.ExpectVersion(0)
 	for i := 0; i < foundAtIndex; i++ {
 		upstreamURI := chain.Fallbacks[i].WithParams(params)
 		c.Set(upstreamURI, data) // Ignore errors
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"zbz/universal"
+)
+
+// ErrVersionConflict is matched by every VersionConflictError. The version
+// check runs under a lock held by one core in one process, so it only
+// catches writers that share that core; processes writing the same store
+// through their own cores can still overwrite each other.
+var ErrVersionConflict = errors.New("version conflict")
+
+// updateAttempts is how many times Update re-reads and re-applies a mutation
+const updateAttempts = 5
+
+// VersionConflictError reports a Set of a model read at one version when the
+// store holds another. Stored is 0 when the resource no longer exists.
+type VersionConflictError struct {
+	Resource string
+	Expected int64
+	Stored   int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict on %s: expected version %d, stored version %d", e.Resource, e.Expected, e.Stored)
+}
+
+// Is makes errors.Is(err, ErrVersionConflict) match
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// checkVersion compares the version data was read at with the stored one;
+// models without a read version are written unconditionally
+func checkVersion[T any](resource ResourceURI, data, old ZbzModel[T], isCreate bool) error {
+	if data.readVersion == 0 {
+		return nil
+	}
+	stored := old.version
+	if isCreate {
+		stored = 0
+	}
+	if stored != data.readVersion {
+		return &VersionConflictError{Resource: resource.String(), Expected: data.readVersion, Stored: stored}
+	}
+	return nil
+}
+
+// Update reads resource, applies mutate and writes the result, re-reading
+// and re-applying mutate when another writer changed the resource in
+// between. It returns the model as written, or ErrVersionConflict once the
+// attempts run out; an error from mutate stops it without writing. Like
+// Set, it only sees writers going through this core (see ErrVersionConflict).
+func (c *coreImpl[T]) Update(resource ResourceURI, mutate func(*ZbzModel[T]) error) (ZbzModel[T], error) {
+	var err error
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		var model ZbzModel[T]
+		if model, err = c.Get(resource); err != nil {
+			return ZbzModel[T]{}, err
+		}
+		if err = mutate(&model); err != nil {
+			return ZbzModel[T]{}, err
+		}
+		if model, err = c.set(context.Background(), resource, model); !errors.Is(err, ErrVersionConflict) {
+			return model, err
+		}
+	}
+	return ZbzModel[T]{}, err
+}
+
+// resourceLocks serializes writes to the same resource within a core. It is
+// in-process only: providers have no conditional write to enforce versions.
+type resourceLocks struct {
+	mu    sync.Mutex
+	locks map[string]*resourceLock
+}
+
+type resourceLock struct {
+	sync.Mutex
+	waiters int
+}
+
+// lock holds the resource's lock until the returned func is called
+func (l *resourceLocks) lock(uri universal.ResourceURI) func() {
+	key := uri.Service() + "://" + uri.Path()
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*resourceLock)
+	}
+	lock, exists := l.locks[key]
+	if !exists {
+		lock = &resourceLock{}
+		l.locks[key] = lock
+	}
+	lock.waiters++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		if lock.waiters--; lock.waiters == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
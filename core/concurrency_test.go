@@ -0,0 +1,82 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCoreSetVersionConflict(t *testing.T) {
+	userCore, _ := newStoredUserCore(t)
+	resource := NewResourceURI("db://users/1")
+	userCore.Set(resource, Wrap(User{ID: 1, Name: "Ada", Email: "ada@example.com"}))
+
+	first, _ := userCore.Get(resource)
+	second, _ := userCore.Get(resource)
+
+	first.SetData(User{ID: 1, Name: "Ada Lovelace", Email: "ada@example.com"})
+	if err := userCore.Set(resource, first); err != nil {
+		t.Fatalf("Expected the first writer to win, got %v", err)
+	}
+
+	second.SetData(User{ID: 1, Name: "Ada Byron", Email: "ada@example.com"})
+	err := userCore.Set(resource, second)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict for the stale writer, got %v", err)
+	}
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != 1 || conflict.Stored != 2 {
+		t.Errorf("Expected a conflict between versions 1 and 2, got %+v", conflict)
+	}
+	if stored, _ := userCore.Get(resource); stored.Data().Name != "Ada Lovelace" {
+		t.Errorf("Expected the stale write to be rejected, got %q", stored.Data().Name)
+	}
+
+	// A model that was never read carries no expectation
+	if err := userCore.Set(resource, Wrap(User{ID: 1, Name: "Grace", Email: "grace@example.com"})); err != nil {
+		t.Errorf("Expected an unconditional write to succeed, got %v", err)
+	}
+
+	stale, _ := userCore.Get(resource)
+	userCore.Delete(resource)
+	if err := userCore.Set(resource, stale); !errors.As(err, &conflict) || conflict.Stored != 0 {
+		t.Errorf("Expected a conflict with a deleted resource, got %v", err)
+	}
+}
+
+func TestCoreUpdateRetriesConflicts(t *testing.T) {
+	userCore, _ := newStoredUserCore(t)
+	resource := NewResourceURI("db://users/1")
+	userCore.Set(resource, Wrap(User{ID: 1, Name: "", Email: "ada@example.com"}))
+
+	const writers = 4
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := userCore.Update(resource, func(user *ZbzModel[User]) error {
+				data := user.Data()
+				data.Name += "x"
+				user.SetData(data)
+				return nil
+			}); err != nil {
+				t.Errorf("Expected update to succeed, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, _ := userCore.Get(resource)
+	if stored.Data().Name != "xxxx" || stored.Version() != writers+1 {
+		t.Errorf("Expected every update applied once, got %q at version %d", stored.Data().Name, stored.Version())
+	}
+
+	stop := errors.New("stop")
+	if _, err := userCore.Update(resource, func(user *ZbzModel[User]) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("Expected the mutation error, got %v", err)
+	}
+	if _, err := userCore.Update(NewResourceURI("db://users/9"), func(user *ZbzModel[User]) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating a missing resource, got %v", err)
+	}
+}
@@ -7,13 +7,16 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"zbz/catalog"
+	"zbz/cereal"
 	"zbz/universal"
 	"zbz/zlog"
 )
 
-// ResourceURI represents a resource identifier (simplified for standalone testing)
+// ResourceURI represents a resource identifier; its scheme selects the
+// provider that stores the resource (see RegisterProvider)
 type ResourceURI struct {
 	URI string
 }
@@ -27,14 +30,19 @@ func (r ResourceURI) String() string {
 	return r.URI
 }
 
+// Service returns the URI scheme, which names the provider
 func (r ResourceURI) Service() string {
-	// Simplified - extract service from URI
-	return "test"
+	scheme, _, _ := strings.Cut(r.URI, "://")
+	return scheme
 }
 
+// WithParams fills {placeholder} templates in the URI
 func (r ResourceURI) WithParams(params map[string]any) ResourceURI {
-	// Simplified implementation for testing
-	return r
+	uri := r.URI
+	for key, value := range params {
+		uri = strings.ReplaceAll(uri, "{"+key+"}", fmt.Sprintf("%v", value))
+	}
+	return ResourceURI{URI: uri}
 }
 
 // OperationURI represents an operation identifier (simplified for standalone testing)
@@ -59,6 +67,9 @@ type Core[T any] interface {
 	Exists(resource ResourceURI) (bool, error)
 	Count(pattern ResourceURI) (int64, error)
 	
+	// Read-modify-write that retries on version conflicts
+	Update(resource ResourceURI, mutate func(*ZbzModel[T]) error) (ZbzModel[T], error)
+	
 	// Complex operations via OperationURI
 	Execute(operation OperationURI, params any) (any, error)
 	ExecuteMany(operations []Operation) ([]any, error)
@@ -143,6 +154,15 @@ type coreImpl[T any] struct {
 	// Chain registry
 	chains map[string]ResourceChain
 	
+	// Providers configured on this core, by scheme; the service's are the fallback
+	providers map[string]universal.Provider
+	
+	// Format models are stored in
+	format cereal.Format
+	
+	// Serializes writes per resource so version checks hold
+	writes resourceLocks
+	
 	// Mutex for thread safety
 	mu sync.RWMutex
 	
@@ -171,18 +191,71 @@ func NewCore[T any]() Core[T] {
 		beforeDeleteHooks: make(map[HookID]BeforeDeleteHook[T]),
 		afterDeleteHooks:  make(map[HookID]AfterDeleteHook[T]),
 		chains:            make(map[string]ResourceChain),
+		providers:         make(map[string]universal.Provider),
+		format:            storageFormat(),
 	}
 }
 
 // Basic CRUD operations
 
 func (c *coreImpl[T]) Get(resource ResourceURI) (ZbzModel[T], error) {
-	// Placeholder implementation for testing
-	return ZbzModel[T]{}, ErrNotFound
+	return c.get(context.Background(), resource)
+}
+
+func (c *coreImpl[T]) get(ctx context.Context, resource ResourceURI) (ZbzModel[T], error) {
+	provider, uri, err := c.resolve(resource)
+	if err != nil {
+		return ZbzModel[T]{}, err
+	}
+	
+	model, err := c.load(ctx, provider, uri)
+	if err != nil && err != ErrNotFound {
+		zlog.Error("Core Get failed",
+			zlog.String("type", c.typeName),
+			zlog.String("resource", resource.String()),
+			zlog.String("error", err.Error()),
+		)
+	}
+	if err == nil && model.IsDeleted() {
+		return ZbzModel[T]{}, ErrNotFound
+	}
+	return model, err
 }
 
 func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
-	isCreate := data.IsNew()
+	_, err := c.set(context.Background(), resource, data)
+	return err
+}
+
+// set writes data and returns it as written. A model read from the store is
+// only written while the store still holds the version it was read at.
+func (c *coreImpl[T]) set(ctx context.Context, resource ResourceURI, data ZbzModel[T]) (ZbzModel[T], error) {
+	provider, uri, err := c.resolve(resource)
+	if err != nil {
+		return ZbzModel[T]{}, err
+	}
+	
+	// Reading, checking and writing the resource is one step for this core
+	unlock := c.writes.lock(uri)
+	defer unlock()
+	
+	// The store decides between create and update, and supplies old; a
+	// deleted record is created again
+	old, err := c.load(ctx, provider, uri)
+	stored := err == nil
+	if err != nil && err != ErrNotFound {
+		return ZbzModel[T]{}, err
+	}
+	isCreate := !stored || old.IsDeleted()
+	if err := checkVersion(resource, data, old, isCreate); err != nil {
+		zlog.Warn("Core Set version conflict",
+			zlog.String("type", c.typeName),
+			zlog.String("resource", resource.String()),
+			zlog.Int64("expected", data.readVersion),
+			zlog.Int64("stored", old.version),
+		)
+		return ZbzModel[T]{}, err
+	}
 	operation := "update"
 	if isCreate {
 		operation = "create"
@@ -202,10 +275,15 @@ func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
 			zlog.String("resource", resource.String()),
 			zlog.String("error", err.Error()),
 		)
-		return err
+		return ZbzModel[T]{}, err
 	}
 	
-	var old ZbzModel[T]
+	// Lifecycle fields are maintained here, so hooks see what will be written
+	if stored {
+		data = stamp(data, &old, time.Now())
+	} else {
+		data = stamp(data, nil, time.Now())
+	}
 	
 	// Execute before hooks
 	c.mu.RLock()
@@ -220,7 +298,7 @@ func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
 					zlog.String("resource", resource.String()),
 					zlog.String("error", err.Error()),
 				)
-				return err
+				return ZbzModel[T]{}, err
 			}
 		}
 	} else {
@@ -233,7 +311,7 @@ func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
 					zlog.String("resource", resource.String()),
 					zlog.String("error", err.Error()),
 				)
-				return err
+				return ZbzModel[T]{}, err
 			}
 		}
 	}
@@ -245,19 +323,31 @@ func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
 		zlog.Int("hook_count", hookCount),
 	)
 	
-	// Placeholder - would perform the actual operation
+	// Write through the provider
+	encoded, err := c.encode(data)
+	if err != nil {
+		return ZbzModel[T]{}, err
+	}
+	if err := provider.Set(ctx, uri, encoded); err != nil {
+		zlog.Error("Core Set write failed",
+			zlog.String("type", c.typeName),
+			zlog.String("resource", resource.String()),
+			zlog.String("error", err.Error()),
+		)
+		return ZbzModel[T]{}, fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
 	
 	// Emit events and execute after hooks
 	if isCreate {
 		data.EmitEvent("created", resource.String(), c.typeName, nil)
-		
+	
 		c.mu.RLock()
 		afterHookCount := len(c.afterCreateHooks)
 		for _, hook := range c.afterCreateHooks {
 			go hook(data) // Execute after hooks asynchronously
 		}
 		c.mu.RUnlock()
-		
+	
 		zlog.Info("Core create operation completed",
 			zlog.String("type", c.typeName),
 			zlog.String("resource", resource.String()),
@@ -266,14 +356,14 @@ func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
 		)
 	} else {
 		data.EmitEvent("updated", resource.String(), c.typeName, &old)
-		
+	
 		c.mu.RLock()
 		afterHookCount := len(c.afterUpdateHooks)
 		for _, hook := range c.afterUpdateHooks {
 			go hook(old, data)
 		}
 		c.mu.RUnlock()
-		
+	
 		zlog.Info("Core update operation completed",
 			zlog.String("type", c.typeName),
 			zlog.String("resource", resource.String()),
@@ -282,39 +372,213 @@ func (c *coreImpl[T]) Set(resource ResourceURI, data ZbzModel[T]) error {
 		)
 	}
 	
-	return nil
+	data.readVersion = data.version
+	return data, nil
 }
 
 func (c *coreImpl[T]) Delete(resource ResourceURI) error {
-	// Placeholder implementation for testing
+	return c.delete(context.Background(), resource)
+}
+
+func (c *coreImpl[T]) delete(ctx context.Context, resource ResourceURI) error {
+	provider, uri, err := c.resolve(resource)
+	if err != nil {
+		return err
+	}
+	
+	unlock := c.writes.lock(uri)
+	defer unlock()
+	
+	// Hooks see the model being deleted
+	old, err := c.load(ctx, provider, uri)
+	if err != nil {
+		return err
+	}
+	if old.IsDeleted() {
+		return ErrNotFound
+	}
+	
+	c.mu.RLock()
+	for _, hook := range c.beforeDeleteHooks {
+		if err := hook(old); err != nil {
+			c.mu.RUnlock()
+			zlog.Error("Before delete hook failed",
+				zlog.String("type", c.typeName),
+				zlog.String("resource", resource.String()),
+				zlog.String("error", err.Error()),
+			)
+			return err
+		}
+	}
+	c.mu.RUnlock()
+	
+	// Deleting is soft: the record stays, marked with deleted_at, and reads skip it
+	deleted := old
+	now := time.Now()
+	deleted.deletedAt = &now
+	deleted.updatedAt = &now
+	deleted.version = old.version + 1
+	
+	encoded, err := c.encode(deleted)
+	if err != nil {
+		return err
+	}
+	if err := provider.Set(ctx, uri, encoded); err != nil {
+		return fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
+	
+	old.EmitEvent("deleted", resource.String(), c.typeName, nil)
+	
+	c.mu.RLock()
+	afterHookCount := len(c.afterDeleteHooks)
+	for _, hook := range c.afterDeleteHooks {
+		go hook(old)
+	}
+	c.mu.RUnlock()
+	
+	zlog.Info("Core delete operation completed",
+		zlog.String("type", c.typeName),
+		zlog.String("resource", resource.String()),
+		zlog.String("id", old.ID()),
+		zlog.Int("after_hooks", afterHookCount),
+	)
 	return nil
 }
 
 func (c *coreImpl[T]) List(pattern ResourceURI) ([]ZbzModel[T], error) {
-	// Placeholder implementation for testing
-	return []ZbzModel[T]{}, nil
+	return c.list(context.Background(), pattern)
+}
+
+func (c *coreImpl[T]) list(ctx context.Context, pattern ResourceURI) ([]ZbzModel[T], error) {
+	provider, uri, err := c.resolve(pattern)
+	if err != nil {
+		return nil, err
+	}
+	
+	items, err := provider.List(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
+	
+	models := make([]ZbzModel[T], 0, len(items))
+	for _, item := range items {
+		model, err := c.decode(item)
+		if err != nil {
+			return nil, err
+		}
+		if model.IsDeleted() {
+			continue
+		}
+		models = append(models, model)
+	}
+	return models, nil
 }
 
 func (c *coreImpl[T]) Exists(resource ResourceURI) (bool, error) {
-	// Placeholder implementation for testing
-	return false, nil
+	return c.exists(context.Background(), resource)
+}
+
+func (c *coreImpl[T]) exists(ctx context.Context, resource ResourceURI) (bool, error) {
+	provider, uri, err := c.resolve(resource)
+	if err != nil {
+		return false, err
+	}
+	
+	exists, err := provider.Exists(ctx, uri)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
+	if !exists {
+		return false, nil
+	}
+	
+	// Deleted records are still stored, so the matches themselves are checked
+	models, err := c.list(ctx, resource)
+	if err != nil {
+		return false, err
+	}
+	return len(models) > 0, nil
 }
 
 func (c *coreImpl[T]) Count(pattern ResourceURI) (int64, error) {
-	// Placeholder implementation for testing
-	return 0, nil
+	return c.count(context.Background(), pattern)
+}
+
+func (c *coreImpl[T]) count(ctx context.Context, pattern ResourceURI) (int64, error) {
+	// Deleted records are still stored, so only live matches are counted
+	models, err := c.list(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(models)), nil
 }
 
 // Complex operations
 
+// Execute runs a provider operation; params and the result go through the
+// storage format, so the result comes back as generic values
 func (c *coreImpl[T]) Execute(operation OperationURI, params any) (any, error) {
-	// Placeholder implementation for testing
-	return nil, nil
+	uri, err := universal.ParseOperationURI(operation.URI)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", universal.ErrInvalidURI, err)
+	}
+	provider, err := c.provider(uri.Service())
+	if err != nil {
+		return nil, err
+	}
+	
+	var encoded []byte
+	if params != nil {
+		if encoded, err = c.format.Encode(params); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+		}
+	}
+	
+	result, err := provider.Execute(context.Background(), uri, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
+	return c.decodeResult(result)
 }
 
+// ExecuteMany runs a batch on one provider; each operation's Target is a
+// resource URI and all of them must share a scheme
 func (c *coreImpl[T]) ExecuteMany(operations []Operation) ([]any, error) {
-	// Placeholder implementation for testing
-	return []any{}, nil
+	if len(operations) == 0 {
+		return []any{}, nil
+	}
+	
+	scheme := ""
+	batch := make([]universal.Operation, len(operations))
+	for i, operation := range operations {
+		target, err := universal.ParseResourceURI(operation.Target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w: %v", i, universal.ErrInvalidURI, err)
+		}
+		if i == 0 {
+			scheme = target.Service()
+		} else if target.Service() != scheme {
+			return nil, fmt.Errorf("operation %d: batch spans providers %s and %s", i, scheme, target.Service())
+		}
+		batch[i] = universal.Operation{Type: operation.Type, Target: operation.Target, Params: operation.Params}
+	}
+	
+	provider, err := c.provider(scheme)
+	if err != nil {
+		return nil, err
+	}
+	results, err := provider.ExecuteMany(context.Background(), batch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
+	
+	decoded := make([]any, len(results))
+	for i, result := range results {
+		if decoded[i], err = c.decodeResult(result); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
 }
 
 // Type metadata
@@ -331,7 +595,7 @@ func (c *coreImpl[T]) TypeName() string {
 // CoreService interface implementation (non-generic methods)
 
 func (c *coreImpl[T]) GetByURI(ctx context.Context, uri ResourceURI) (ZbzModelInterface, error) {
-	result, err := c.Get(uri)
+	result, err := c.get(ctx, uri)
 	if err != nil {
 		return nil, err
 	}
@@ -341,21 +605,67 @@ func (c *coreImpl[T]) GetByURI(ctx context.Context, uri ResourceURI) (ZbzModelIn
 func (c *coreImpl[T]) SetByURI(ctx context.Context, uri ResourceURI, data ZbzModelInterface) error {
 	// Convert ZbzModelInterface back to ZbzModel[T]
 	if zbzModel, ok := data.(*ZbzModel[T]); ok {
-		return c.Set(uri, *zbzModel)
+		written, err := c.set(ctx, uri, *zbzModel)
+		if err == nil {
+			*zbzModel = written // Callers see the lifecycle fields as stored
+		}
+		return err
 	}
 	return fmt.Errorf("invalid data type for SetByURI")
 }
 
 func (c *coreImpl[T]) DeleteByURI(ctx context.Context, uri ResourceURI) error {
-	return c.Delete(uri)
+	return c.delete(ctx, uri)
 }
 
 func (c *coreImpl[T]) ExistsByURI(ctx context.Context, uri ResourceURI) (bool, error) {
-	return c.Exists(uri)
+	return c.exists(ctx, uri)
 }
 
 func (c *coreImpl[T]) CountByURI(ctx context.Context, pattern ResourceURI) (int64, error) {
-	return c.Count(pattern)
+	return c.count(ctx, pattern)
+}
+
+func (c *coreImpl[T]) NewData() any {
+	return new(T)
+}
+
+func (c *coreImpl[T]) WrapData(data any) (ZbzModelInterface, error) {
+	switch typed := data.(type) {
+	case T:
+		model := Wrap(typed)
+		return &model, nil
+	case *T:
+		if typed != nil {
+			model := Wrap(*typed)
+			return &model, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid data type %T for %s", data, c.typeName)
+}
+
+// UpdateData applies data, a T or *T, over the T stored at uri with
+// cereal.ApplyUpdate, so fields hidden from the caller keep their stored
+// values. With nothing stored, data is wrapped as is.
+func (c *coreImpl[T]) UpdateData(ctx context.Context, uri ResourceURI, data any, permissions ...string) (ZbzModelInterface, error) {
+	model, err := c.WrapData(data)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := c.get(ctx, uri)
+	if errors.Is(err, ErrNotFound) {
+		return model, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	updated, _, err := cereal.ApplyUpdate(stored.Data(), model.(*ZbzModel[T]).Data(), permissions...)
+	if err != nil {
+		return nil, err
+	}
+	result := Wrap(updated)
+	return &result, nil
 }
 
 func (c *coreImpl[T]) GetChainByName(ctx context.Context, chainName string, params map[string]any) (ZbzModelInterface, error) {
@@ -373,14 +683,35 @@ func (c *coreImpl[T]) SetChainByName(ctx context.Context, chainName string, data
 	return fmt.Errorf("invalid data type for SetChainByName")
 }
 
+// ConfigureProviders sets providers for this core by URI scheme; they take
+// precedence over providers registered with the service
 func (c *coreImpl[T]) ConfigureProviders(providers map[string]universal.Provider) error {
-	// Implementation will be added when we work on provider configuration
-	return fmt.Errorf("provider configuration not yet implemented")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	for scheme, provider := range providers {
+		if provider == nil {
+			return fmt.Errorf("provider for scheme %s is nil", scheme)
+		}
+		c.providers[scheme] = provider
+	}
+	return nil
 }
 
+// GetProviderHealth reports the health of every provider this core can use
 func (c *coreImpl[T]) GetProviderHealth() map[string]ProviderHealth {
-	// Implementation will be added when we work on provider health
-	return make(map[string]ProviderHealth)
+	providers := Service().Providers()
+	c.mu.RLock()
+	for scheme, provider := range c.providers {
+		providers[scheme] = provider
+	}
+	c.mu.RUnlock()
+	
+	health := make(map[string]ProviderHealth, len(providers))
+	for scheme, provider := range providers {
+		health[scheme] = providerHealth(context.Background(), provider)
+	}
+	return health
 }
 
 // Metadata delegation to catalog (NO reflection in core!)
@@ -513,4 +844,5 @@ var (
 	ErrAlreadyExists = errors.New("resource already exists")
 	ErrInvalidData   = errors.New("invalid data")
 	ErrProviderError = errors.New("provider error")
+	ErrNoProvider    = errors.New("no provider configured")
 )
\ No newline at end of file
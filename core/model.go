@@ -18,6 +18,10 @@ type ZbzModel[T any] struct {
 	deletedAt *time.Time // Soft delete support
 	version   int64
 	metadata  map[string]any
+	
+	// readVersion is the version the model was read at; Set only writes
+	// while the store still holds it. 0 means no expectation (a new model).
+	readVersion int64
 }
 
 // ZbzModelInterface provides type-erased access to ZbzModel methods
@@ -32,6 +36,8 @@ type ZbzModelInterface interface {
 	SetUpdatedAt(*time.Time)
 	SetVersion(int64)
 	IncrementVersion()
+	ReadVersion() int64
+	ExpectVersion(int64)
 	SoftDelete()
 	Restore()
 	Metadata() map[string]any
@@ -128,6 +134,18 @@ func (m *ZbzModel[T]) IncrementVersion() {
 	m.updatedAt = &now
 }
 
+// ReadVersion returns the stored version this model was read at, or 0 for a
+// model that did not come from the store
+func (m ZbzModel[T]) ReadVersion() int64 {
+	return m.readVersion
+}
+
+// ExpectVersion makes the next Set of this model fail with ErrVersionConflict
+// unless the store holds version v; 0 removes the expectation
+func (m *ZbzModel[T]) ExpectVersion(v int64) {
+	m.readVersion = v
+}
+
 // Soft delete support
 
 func (m ZbzModel[T]) IsDeleted() bool {
@@ -183,17 +201,21 @@ func (m ZbzModel[T]) Validate() error {
 	return nil
 }
 
-// JSON serialization that preserves user type structure
-func (m ZbzModel[T]) MarshalJSON() ([]byte, error) {
-	wrapper := struct {
-		ID        string         `json:"id"`
-		CreatedAt *time.Time     `json:"created_at,omitempty"`
-		UpdatedAt *time.Time     `json:"updated_at,omitempty"`
-		DeletedAt *time.Time     `json:"deleted_at,omitempty"`
-		Version   int64          `json:"version"`
-		Data      T              `json:"data"`
-		Metadata  map[string]any `json:"metadata,omitempty"`
-	}{
+// modelRecord is the serialized form of a ZbzModel: the user type under
+// "data" with the lifecycle fields beside it
+type modelRecord[T any] struct {
+	ID        string         `json:"id"`
+	CreatedAt *time.Time     `json:"created_at,omitempty"`
+	UpdatedAt *time.Time     `json:"updated_at,omitempty"`
+	DeletedAt *time.Time     `json:"deleted_at,omitempty"`
+	Version   int64          `json:"version"`
+	Data      T              `json:"data"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// record returns the model's serialized form
+func (m ZbzModel[T]) record() modelRecord[T] {
+	record := modelRecord[T]{
 		ID:        m.ID(),
 		CreatedAt: m.createdAt,
 		UpdatedAt: m.updatedAt,
@@ -204,35 +226,38 @@ func (m ZbzModel[T]) MarshalJSON() ([]byte, error) {
 	
 	// Only include metadata if not empty
 	if len(m.metadata) > 0 {
-		wrapper.Metadata = m.metadata
+		record.Metadata = m.metadata
 	}
-	
-	return json.Marshal(wrapper)
+	return record
+}
+
+// fromRecord restores a model from its serialized form; the serialized
+// version becomes the version a later Set expects to find
+func fromRecord[T any](record modelRecord[T]) ZbzModel[T] {
+	return ZbzModel[T]{
+		data:        record.Data,
+		createdAt:   record.CreatedAt,
+		updatedAt:   record.UpdatedAt,
+		deletedAt:   record.DeletedAt,
+		version:     record.Version,
+		metadata:    record.Metadata,
+		readVersion: record.Version,
+	}
+}
+
+// JSON serialization that preserves user type structure
+func (m ZbzModel[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.record())
 }
 
 // JSON deserialization
 func (m *ZbzModel[T]) UnmarshalJSON(data []byte) error {
-	var wrapper struct {
-		ID        string         `json:"id"`
-		CreatedAt *time.Time     `json:"created_at,omitempty"`
-		UpdatedAt *time.Time     `json:"updated_at,omitempty"`
-		DeletedAt *time.Time     `json:"deleted_at,omitempty"`
-		Version   int64          `json:"version"`
-		Data      T              `json:"data"`
-		Metadata  map[string]any `json:"metadata,omitempty"`
-	}
-	
-	if err := json.Unmarshal(data, &wrapper); err != nil {
+	var record modelRecord[T]
+	if err := json.Unmarshal(data, &record); err != nil {
 		return err
 	}
 	
-	m.data = wrapper.Data
-	m.createdAt = wrapper.CreatedAt
-	m.updatedAt = wrapper.UpdatedAt
-	m.deletedAt = wrapper.DeletedAt
-	m.version = wrapper.Version
-	m.metadata = wrapper.Metadata
-	
+	*m = fromRecord(record)
 	return nil
 }
 
@@ -329,8 +354,9 @@ func (m *ZbzModel[T]) Touch() {
 // Clone creates a deep copy of the model
 func (m ZbzModel[T]) Clone() ZbzModel[T] {
 	clone := ZbzModel[T]{
-		data:    m.data, // Note: This is a shallow copy of T
-		version: m.version,
+		data:        m.data, // Note: This is a shallow copy of T
+		version:     m.version,
+		readVersion: m.readVersion,
 	}
 	
 	if m.createdAt != nil {
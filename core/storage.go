@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"zbz/cereal"
+	"zbz/universal"
+)
+
+// storageFormat is the cereal format models are written to providers in
+func storageFormat() cereal.Format {
+	info, _ := cereal.FormatByName("json")
+	return info.Format
+}
+
+// resolve parses a resource URI and finds the provider for its scheme
+func (c *coreImpl[T]) resolve(resource ResourceURI) (universal.Provider, universal.ResourceURI, error) {
+	uri, err := universal.ParseResourceURI(resource.URI)
+	if err != nil {
+		return nil, universal.ResourceURI{}, fmt.Errorf("%w: %v", universal.ErrInvalidURI, err)
+	}
+	provider, err := c.provider(uri.Service())
+	if err != nil {
+		return nil, universal.ResourceURI{}, err
+	}
+	return provider, uri, nil
+}
+
+// provider finds the provider for a scheme: providers configured on this
+// core take precedence over those registered with the service
+func (c *coreImpl[T]) provider(scheme string) (universal.Provider, error) {
+	c.mu.RLock()
+	provider, exists := c.providers[scheme]
+	c.mu.RUnlock()
+	if exists {
+		return provider, nil
+	}
+	return Service().GetProvider(scheme)
+}
+
+// load reads and decodes the model stored at uri
+func (c *coreImpl[T]) load(ctx context.Context, provider universal.Provider, uri universal.ResourceURI) (ZbzModel[T], error) {
+	data, err := provider.Get(ctx, uri)
+	if errors.Is(err, universal.ErrResourceNotFound) {
+		return ZbzModel[T]{}, ErrNotFound
+	}
+	if err != nil {
+		return ZbzModel[T]{}, fmt.Errorf("%w: %w", ErrProviderError, err)
+	}
+	return c.decode(data)
+}
+
+// encode serializes a model with its lifecycle fields
+func (c *coreImpl[T]) encode(model ZbzModel[T]) ([]byte, error) {
+	data, err := c.format.Encode(model.record())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return data, nil
+}
+
+// decode restores a model written by encode
+func (c *coreImpl[T]) decode(data []byte) (ZbzModel[T], error) {
+	var record modelRecord[T]
+	if err := c.format.Decode(data, &record); err != nil {
+		return ZbzModel[T]{}, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return fromRecord(record), nil
+}
+
+// decodeResult decodes an operation result into generic values
+func (c *coreImpl[T]) decodeResult(data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var result any
+	if err := c.format.Decode(data, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return result, nil
+}
+
+// stamp sets the lifecycle fields for a write: a create starts at version 1,
+// an update keeps the stored creation and deletion times and moves to the
+// next version. Writing over a deleted record recreates it, with versions
+// still counting up so readers from before the delete conflict.
+func stamp[T any](data ZbzModel[T], old *ZbzModel[T], now time.Time) ZbzModel[T] {
+	switch {
+	case old == nil:
+		data.createdAt = &now
+		data.deletedAt = nil
+		data.version = 1
+	case old.IsDeleted():
+		data.createdAt = &now
+		data.deletedAt = nil
+		data.version = old.version + 1
+	default:
+		data.createdAt = old.createdAt
+		data.deletedAt = old.deletedAt
+		data.version = old.version + 1
+	}
+	data.updatedAt = &now
+	return data
+}
+
+// providerHealth converts a provider's health report for CoreService
+func providerHealth(ctx context.Context, provider universal.Provider) ProviderHealth {
+	health, err := provider.Health(ctx)
+	if err != nil {
+		return ProviderHealth{
+			Status:    "unhealthy",
+			Message:   err.Error(),
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+	}
+	return ProviderHealth{
+		Status:    health.Status,
+		Message:   health.Message,
+		Details:   health.Metrics,
+		Timestamp: health.LastChecked.Format(time.RFC3339),
+	}
+}
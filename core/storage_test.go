@@ -0,0 +1,272 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"zbz/universal"
+)
+
+// mapProvider is a minimal universal.Provider over a map
+type mapProvider struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newMapProvider() *mapProvider {
+	return &mapProvider{items: make(map[string][]byte)}
+}
+
+func (p *mapProvider) Get(ctx context.Context, resource universal.ResourceURI) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, exists := p.items[resource.String()]
+	if !exists {
+		return nil, universal.ErrResourceNotFound
+	}
+	return data, nil
+}
+
+func (p *mapProvider) Set(ctx context.Context, resource universal.ResourceURI, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items[resource.String()] = data
+	return nil
+}
+
+func (p *mapProvider) Delete(ctx context.Context, resource universal.ResourceURI) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.items, resource.String())
+	return nil
+}
+
+func (p *mapProvider) List(ctx context.Context, pattern universal.ResourceURI) ([][]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var items [][]byte
+	for key, data := range p.items {
+		if matched, _ := path.Match(pattern.String(), key); matched {
+			items = append(items, data)
+		}
+	}
+	return items, nil
+}
+
+func (p *mapProvider) Exists(ctx context.Context, resource universal.ResourceURI) (bool, error) {
+	_, err := p.Get(ctx, resource)
+	return err == nil, nil
+}
+
+func (p *mapProvider) Count(ctx context.Context, pattern universal.ResourceURI) (int64, error) {
+	items, err := p.List(ctx, pattern)
+	return int64(len(items)), err
+}
+
+func (p *mapProvider) Execute(ctx context.Context, operation universal.OperationURI, params []byte) ([]byte, error) {
+	return params, nil // Echo
+}
+
+func (p *mapProvider) ExecuteMany(ctx context.Context, operations []universal.Operation) ([][]byte, error) {
+	results := make([][]byte, len(operations))
+	for i, operation := range operations {
+		results[i] = []byte(`"` + operation.Type + `"`)
+	}
+	return results, nil
+}
+
+func (p *mapProvider) Subscribe(ctx context.Context, pattern universal.ResourceURI, callback universal.ProviderChangeCallback) (universal.SubscriptionID, error) {
+	return "", errors.New("not supported")
+}
+
+func (p *mapProvider) Unsubscribe(ctx context.Context, id universal.SubscriptionID) error {
+	return errors.New("not supported")
+}
+
+func (p *mapProvider) GetProvider() string { return "map" }
+
+func (p *mapProvider) Health(ctx context.Context) (universal.ProviderHealth, error) {
+	return universal.ProviderHealth{Status: "healthy", LastChecked: time.Now()}, nil
+}
+
+func (p *mapProvider) Close() error { return nil }
+
+func (p *mapProvider) SetHookEmitter(emitter universal.HookEmitter) {}
+
+func newStoredUserCore(t *testing.T) (Core[User], *mapProvider) {
+	provider := newMapProvider()
+	userCore := NewCore[User]()
+	if err := userCore.(CoreService).ConfigureProviders(map[string]universal.Provider{"db": provider}); err != nil {
+		t.Fatalf("Failed to configure providers: %v", err)
+	}
+	return userCore, provider
+}
+
+func TestCoreCRUD(t *testing.T) {
+	userCore, _ := newStoredUserCore(t)
+	resource := NewResourceURI("db://users/1")
+
+	if _, err := userCore.Get(resource); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound before any write, got %v", err)
+	}
+
+	if err := userCore.Set(resource, Wrap(User{ID: 1, Name: "Ada", Email: "ada@example.com"})); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	created, err := userCore.Get(resource)
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if created.Data().Name != "Ada" || created.Version() != 1 || created.CreatedAt() == nil || created.UpdatedAt() == nil {
+		t.Errorf("Expected stored user with lifecycle fields, got %+v", created)
+	}
+
+	updated := created
+	updated.SetData(User{ID: 1, Name: "Ada Lovelace", Email: "ada@example.com"})
+	if err := userCore.Set(resource, updated); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	stored, _ := userCore.Get(resource)
+	if stored.Data().Name != "Ada Lovelace" || stored.Version() != 2 {
+		t.Errorf("Expected version 2 with the new name, got version %d %+v", stored.Version(), stored.Data())
+	}
+	if !stored.CreatedAt().Equal(*created.CreatedAt()) {
+		t.Errorf("Expected created_at to be preserved, got %v then %v", created.CreatedAt(), stored.CreatedAt())
+	}
+
+	userCore.Set(NewResourceURI("db://users/2"), Wrap(User{ID: 2, Name: "Grace", Email: "grace@example.com"}))
+	if count, _ := userCore.Count(NewResourceURI("db://users/*")); count != 2 {
+		t.Errorf("Expected 2 users, got %d", count)
+	}
+	if users, _ := userCore.List(NewResourceURI("db://users/*")); len(users) != 2 {
+		t.Errorf("Expected to list 2 users, got %d", len(users))
+	}
+
+	if err := userCore.Delete(resource); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if exists, _ := userCore.Exists(resource); exists {
+		t.Error("Expected user to be deleted")
+	}
+	if err := userCore.Delete(resource); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestCoreSoftDelete(t *testing.T) {
+	userCore, provider := newStoredUserCore(t)
+	resource := NewResourceURI("db://users/1")
+	userCore.Set(resource, Wrap(User{ID: 1, Name: "Ada", Email: "ada@example.com"}))
+	userCore.Set(NewResourceURI("db://users/2"), Wrap(User{ID: 2, Name: "Grace", Email: "grace@example.com"}))
+	stale, _ := userCore.Get(resource)
+
+	if err := userCore.Delete(resource); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+
+	// The record stays in the provider, marked deleted
+	uri, _ := universal.ParseResourceURI(resource.URI)
+	data, err := provider.Get(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("Expected the deleted record to stay stored, got %v", err)
+	}
+	if record, _ := userCore.(*coreImpl[User]).decode(data); !record.IsDeleted() || record.Version() != 2 {
+		t.Errorf("Expected stored record marked deleted at version 2, got %+v", record)
+	}
+
+	if _, err := userCore.Get(resource); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a deleted record, got %v", err)
+	}
+	if exists, _ := userCore.Exists(resource); exists {
+		t.Error("Expected a deleted record not to exist")
+	}
+	if count, _ := userCore.Count(NewResourceURI("db://users/*")); count != 1 {
+		t.Errorf("Expected 1 live user, got %d", count)
+	}
+	if users, _ := userCore.List(NewResourceURI("db://users/*")); len(users) != 1 || users[0].Data().Name != "Grace" {
+		t.Errorf("Expected only Grace listed, got %+v", users)
+	}
+
+	// A model marked deleted by the caller is not a delete
+	grace, _ := userCore.Get(NewResourceURI("db://users/2"))
+	grace.SoftDelete()
+	if err := userCore.Set(NewResourceURI("db://users/2"), grace); err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if stored, err := userCore.Get(NewResourceURI("db://users/2")); err != nil || stored.IsDeleted() {
+		t.Errorf("Expected deleted_at to be kept from the store on update, got %+v (%v)", stored, err)
+	}
+
+	// Writes from before the delete conflict; a fresh write recreates it
+	if err := userCore.Set(resource, stale); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected a stale write to conflict with the delete, got %v", err)
+	}
+	if err := userCore.Set(resource, Wrap(User{ID: 1, Name: "Ada", Email: "ada@example.com"})); err != nil {
+		t.Fatalf("Failed to recreate user: %v", err)
+	}
+	if recreated, err := userCore.Get(resource); err != nil || recreated.IsDeleted() || recreated.Version() != 3 {
+		t.Errorf("Expected a live record at version 3, got %+v (%v)", recreated, err)
+	}
+}
+
+func TestCoreSetHooksSeeStoredOld(t *testing.T) {
+	userCore, _ := newStoredUserCore(t)
+	resource := NewResourceURI("db://users/7")
+
+	var creates int
+	userCore.OnBeforeCreate(func(data ZbzModel[User]) error {
+		creates++
+		return nil
+	})
+	var oldName string
+	userCore.OnBeforeUpdate(func(old, new ZbzModel[User]) error {
+		oldName = old.Data().Name
+		if new.Data().Name == "blocked" {
+			return errors.New("blocked")
+		}
+		return nil
+	})
+
+	userCore.Set(resource, Wrap(User{ID: 7, Name: "Ada", Email: "ada@example.com"}))
+	userCore.Set(resource, Wrap(User{ID: 7, Name: "Grace", Email: "ada@example.com"}))
+	if creates != 1 || oldName != "Ada" {
+		t.Errorf("Expected one create and old name Ada, got %d creates and %q", creates, oldName)
+	}
+
+	if err := userCore.Set(resource, Wrap(User{ID: 7, Name: "blocked", Email: "ada@example.com"})); err == nil {
+		t.Error("Expected before hook to block the write")
+	}
+	if stored, _ := userCore.Get(resource); stored.Data().Name != "Grace" {
+		t.Errorf("Expected blocked write to leave Grace stored, got %q", stored.Data().Name)
+	}
+}
+
+func TestCoreProviderResolution(t *testing.T) {
+	userCore := NewCore[User]()
+	if _, err := userCore.Get(NewResourceURI("nowhere://users/1")); !errors.Is(err, ErrNoProvider) {
+		t.Errorf("Expected ErrNoProvider, got %v", err)
+	}
+
+	provider := newMapProvider()
+	if err := RegisterProvider("svc", provider); err != nil {
+		t.Fatalf("Failed to register provider: %v", err)
+	}
+	if err := userCore.Set(NewResourceURI("svc://users/1"), Wrap(User{ID: 1, Name: "Ada", Email: "ada@example.com"})); err != nil {
+		t.Fatalf("Expected the service provider to be used, got %v", err)
+	}
+	if health := userCore.(CoreService).GetProviderHealth(); health["svc"].Status != "healthy" {
+		t.Errorf("Expected svc to be healthy, got %+v", health)
+	}
+
+	result, err := userCore.Execute(OperationURI{URI: "svc://queries/echo"}, map[string]any{"limit": 10})
+	if echoed, _ := result.(map[string]any); err != nil || echoed["limit"] != float64(10) {
+		t.Errorf("Expected params echoed back, got %v (%v)", result, err)
+	}
+	if _, err := userCore.ExecuteMany([]Operation{{Type: "get", Target: "svc://users/1"}, {Type: "get", Target: "db://users/1"}}); err == nil {
+		t.Error("Expected a batch across providers to be rejected")
+	}
+}
@@ -1,9 +1,11 @@
 package rocco
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"zbz/cereal"
@@ -84,14 +86,59 @@ func (a *zAuth) handleAPIRequest(w http.ResponseWriter, r *http.Request, contrac
 	case "list":
 		// For list operations, we'd use a pattern URI
 		result, err = coreService.GetByURI(r.Context(), resourceURI)
-	case "create", "update":
+	case "create":
 		// TODO: Parse request body and call SetByURI
-		a.writeError(w, http.StatusNotImplemented, "Create/Update operations not yet implemented")
+		a.writeError(w, http.StatusNotImplemented, "Create operations not yet implemented")
 		return
+	case "update":
+		// The body is the bare model, decoded through cereal so write
+		// scopes, decryption and validation apply. It replaces the stored
+		// model except for the fields the caller cannot see, which the
+		// write scopes zeroed in the body.
+		permissions := inputPermissions(identity)
+		data := coreService.NewData()
+		if err := cereal.Decode(r, data, permissions...); err != nil {
+			var negotiationErr *cereal.NegotiationError
+			if errors.As(err, &negotiationErr) {
+				a.writeError(w, negotiationErr.StatusCode(), negotiationErr.Error())
+				return
+			}
+			a.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		model, updateErr := coreService.UpdateData(r.Context(), resourceURI, data, permissions...)
+		if updateErr != nil {
+			var patchErr *cereal.PatchError
+			if errors.As(updateErr, &patchErr) {
+				a.writeError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			zlog.Error("Update merge failed",
+				zlog.String("resource_uri", resourceURI.URI),
+				zlog.String("error", updateErr.Error()),
+			)
+			a.writeError(w, http.StatusInternalServerError, "Operation failed")
+			return
+		}
+		matched, matchErr := a.applyIfMatch(r, model, coreService, resourceURI)
+		if matchErr != nil {
+			zlog.Error("If-Match lookup failed",
+				zlog.String("resource_uri", resourceURI.URI),
+				zlog.String("error", matchErr.Error()),
+			)
+			a.writeError(w, http.StatusInternalServerError, "Operation failed")
+			return
+		}
+		if !matched {
+			a.writeError(w, http.StatusPreconditionFailed, "Resource has been modified")
+			return
+		}
+		err = coreService.SetByURI(r.Context(), resourceURI, model)
+		result = model
 	case "delete":
 		err = coreService.DeleteByURI(r.Context(), resourceURI)
 		if err == nil {
-			a.writeJSON(w, map[string]string{"status": "deleted"})
+			a.writeResponse(w, r, map[string]string{"status": "deleted"})
 			return
 		}
 	default:
@@ -104,9 +151,15 @@ func (a *zAuth) handleAPIRequest(w http.ResponseWriter, r *http.Request, contrac
 			zlog.String("operation", contract.Operation),
 			zlog.String("error", err.Error()),
 		)
-		a.writeError(w, http.StatusInternalServerError, "Operation failed")
+		status, message := operationStatus(err)
+		a.writeError(w, status, message)
 		return
 	}
+	
+	// Versioned results carry an ETag for later If-Match requests
+	if model, ok := result.(core.ZbzModelInterface); ok && model != nil {
+		w.Header().Set("ETag", etag(model.Version()))
+	}
 
 	// 6. Apply security filtering if we have an identity
 	if identity != nil && result != nil {
@@ -122,8 +175,93 @@ func (a *zAuth) handleAPIRequest(w http.ResponseWriter, r *http.Request, contrac
 		result = filteredResult
 	}
 
-	// 7. Return JSON response
-	a.writeJSON(w, result)
+	// 7. Return the response in the format the client accepts
+	a.writeResponse(w, r, result)
+}
+
+// applyIfMatch sets the version an update expects from the If-Match header.
+// It returns false when the precondition already fails: "*" requires the
+// resource to exist and any other value must list an ETag this layer wrote.
+// A list matches when the stored version is one of its tags.
+func (a *zAuth) applyIfMatch(r *http.Request, model core.ZbzModelInterface, coreService core.CoreService, resourceURI core.ResourceURI) (bool, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch ifMatch {
+	case "":
+		return true, nil
+	case "*":
+		return coreService.ExistsByURI(r.Context(), resourceURI)
+	}
+	
+	versions := parseETags(ifMatch)
+	switch len(versions) {
+	case 0:
+		return false, nil
+	case 1:
+		model.ExpectVersion(versions[0])
+		return true, nil
+	}
+	
+	stored, err := coreService.GetByURI(r.Context(), resourceURI)
+	if errors.Is(err, core.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, version := range versions {
+		if version == stored.Version() {
+			model.ExpectVersion(version)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// operationStatus maps a core error to an HTTP status and message
+func operationStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, core.ErrVersionConflict):
+		return http.StatusPreconditionFailed, "Resource has been modified"
+	case errors.Is(err, core.ErrNotFound):
+		return http.StatusNotFound, "Resource not found"
+	default:
+		return http.StatusInternalServerError, "Operation failed"
+	}
+}
+
+// etag formats a model version as a strong entity tag
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETags reads the versions from a comma-separated If-Match list,
+// skipping entries that cannot match
+func parseETags(header string) []int64 {
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		if version, ok := parseETag(strings.TrimSpace(tag)); ok {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// parseETag reads the version back from a tag written by etag; weak tags
+// never match for If-Match
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil && version > 0
+}
+
+// inputPermissions lists the permissions a request body is decoded with
+func inputPermissions(identity *Identity) []string {
+	if identity == nil {
+		return nil
+	}
+	return NewSecurityContext(identity).buildPermissions()
 }
 
 // extractIdentity gets identity from request (without requiring it)
@@ -219,17 +357,19 @@ func (a *zAuth) extractPathParams(path, pattern string) map[string]string {
 	return params
 }
 
-// writeJSON writes a JSON response
-func (a *zAuth) writeJSON(w http.ResponseWriter, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	
-	jsonBytes, err := cereal.JSON.Marshal(data)
-	if err != nil {
-		a.writeError(w, http.StatusInternalServerError, "JSON encoding failed")
+// writeResponse writes data in the format negotiated from the Accept header
+func (a *zAuth) writeResponse(w http.ResponseWriter, r *http.Request, data any) {
+	err := cereal.Encode(w, r, http.StatusOK, data)
+	if err == nil {
 		return
 	}
 	
-	w.Write(jsonBytes)
+	var negotiationErr *cereal.NegotiationError
+	if errors.As(err, &negotiationErr) {
+		a.writeError(w, negotiationErr.StatusCode(), negotiationErr.Error())
+		return
+	}
+	a.writeError(w, http.StatusInternalServerError, "Response encoding failed")
 }
 
 // writeError writes an error response
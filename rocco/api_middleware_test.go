@@ -0,0 +1,126 @@
+package rocco
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zbz/core"
+	"zbz/universal"
+	"zbz/universal/providers/memory"
+)
+
+type Gadget struct {
+	ID     string `json:"id" zbz:"id"`
+	Name   string `json:"name"`
+	Serial string `json:"serial" scope:"gadget:admin"`
+}
+
+func newGadgetAPI(t *testing.T) (http.Handler, core.Core[Gadget]) {
+	provider, _ := memory.NewProvider(universal.DataProviderConfig{})
+	gadgets := core.NewCore[Gadget]()
+	gadgets.(core.CoreService).ConfigureProviders(map[string]universal.Provider{"db": provider})
+	if err := core.RegisterCore(gadgets); err != nil {
+		t.Fatalf("Failed to register core: %v", err)
+	}
+	if err := gadgets.Set(core.NewResourceURI("db://gadgets/1"), core.Wrap(Gadget{ID: "1", Name: "lamp", Serial: "S-1"})); err != nil {
+		t.Fatalf("Failed to seed gadget: %v", err)
+	}
+
+	identity := &Identity{ID: "u1", Permissions: []string{"gadget:read", "gadget:write"}}
+	api := Default().(*zAuth).APIMiddleware()(http.NotFoundHandler())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	}), gadgets
+}
+
+// unavailableStore fails every existence check
+type unavailableStore struct {
+	universal.Provider
+}
+
+func (unavailableStore) Exists(ctx context.Context, resource universal.ResourceURI) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func putGadget(handler http.Handler, ifMatch, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/gadgets/1", strings.NewReader(body)).WithContext(context.Background())
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIMiddlewareIfMatch(t *testing.T) {
+	handler, _ := newGadgetAPI(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/gadgets/1", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("Expected 200 with ETag \"1\", got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	body := `{"id":"1","name":"desk lamp"}`
+	rec = putGadget(handler, `"1"`, body)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("Expected 200 with ETag \"2\", got %d %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+
+	// The first ETag is stale now
+	if rec = putGadget(handler, `"1"`, body); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale If-Match, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec = putGadget(handler, `W/"2"`, body); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a weak If-Match, got %d", rec.Code)
+	}
+	if rec = putGadget(handler, "*", body); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Errorf("Expected If-Match * to update an existing gadget, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec = putGadget(handler, "", body); rec.Code != http.StatusOK {
+		t.Errorf("Expected an update without If-Match to succeed, got %d", rec.Code)
+	}
+}
+
+func TestAPIMiddlewareUpdateEnforcesWriteScopes(t *testing.T) {
+	handler, gadgets := newGadgetAPI(t)
+
+	rec := putGadget(handler, "", `{"id":"1","name":"desk lamp","serial":"forged"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	stored, err := gadgets.Get(core.NewResourceURI("db://gadgets/1"))
+	if err != nil {
+		t.Fatalf("Failed to read gadget: %v", err)
+	}
+	if stored.Data().Name != "desk lamp" {
+		t.Errorf("Expected the permitted field to be written, got %q", stored.Data().Name)
+	}
+	if stored.Data().Serial != "S-1" {
+		t.Errorf("Expected the unscoped serial to keep its stored value, got %q", stored.Data().Serial)
+	}
+}
+
+func TestAPIMiddlewareIfMatchLists(t *testing.T) {
+	handler, gadgets := newGadgetAPI(t)
+	body := `{"id":"1","name":"desk lamp"}`
+
+	if rec := putGadget(handler, `"3", "1"`, body); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("Expected a list holding the stored ETag to match, got %d %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+	if rec := putGadget(handler, `"1", W/"2", "3"`, body); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a list without a strong current ETag, got %d", rec.Code)
+	}
+
+	provider, _ := memory.NewProvider(universal.DataProviderConfig{})
+	gadgets.(core.CoreService).ConfigureProviders(map[string]universal.Provider{"db": unavailableStore{provider}})
+	if rec := putGadget(handler, "*", body); rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when the existence check fails, got %d", rec.Code)
+	}
+}
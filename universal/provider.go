@@ -129,6 +129,13 @@ func (e *UniversalError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Code, e.Message)
 }
 
+// Is matches errors by code, so errors annotated WithProvider still match
+// the sentinel they came from
+func (e *UniversalError) Is(target error) bool {
+	other, ok := target.(*UniversalError)
+	return ok && other.Code == e.Code
+}
+
 // WithProvider adds provider context to the error
 func (e *UniversalError) WithProvider(provider string) error {
 	return &UniversalError{
@@ -152,4 +159,20 @@ var (
 		Code:    "SERIALIZATION_FAILED",
 		Message: "failed to serialize/deserialize data",
 	}
+	ErrResourceNotFound = &UniversalError{
+		Code:    "RESOURCE_NOT_FOUND",
+		Message: "resource not found",
+	}
+	ErrOperationNotSupported = &UniversalError{
+		Code:    "OPERATION_NOT_SUPPORTED",
+		Message: "operation not supported by provider",
+	}
+	ErrSubscriptionNotFound = &UniversalError{
+		Code:    "SUBSCRIPTION_NOT_FOUND",
+		Message: "subscription not found",
+	}
+	ErrProviderClosed = &UniversalError{
+		Code:    "PROVIDER_CLOSED",
+		Message: "provider is closed",
+	}
 )
\ No newline at end of file
@@ -0,0 +1,376 @@
+// Package file is a durable single-node universal.Provider that keeps each
+// resource in its own file under a root directory. Writes go to a temporary
+// file that is synced and renamed into place, so a crash leaves either the
+// old or the new value. One process should own a root at a time.
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"zbz/universal"
+	"zbz/universal/providers/internal/changefeed"
+)
+
+// tempDir holds in-flight writes; service names cannot start with a dot, so
+// it never collides with a service directory
+const tempDir = ".tmp"
+
+// FileProvider implements universal.Provider over a directory tree laid out
+// as <root>/<service>/<escaped path>
+type FileProvider struct {
+	mu      sync.RWMutex
+	root    string
+	closed  bool
+	started time.Time
+
+	feed            *changefeed.Feed
+	instrumentation *universal.ProviderInstrumentation
+}
+
+// NewProvider opens (creating if needed) the store rooted at config.Database,
+// or at config.ConnectionString with an optional file:// prefix
+func NewProvider(config universal.DataProviderConfig) (universal.Provider, error) {
+	root := config.Database
+	if root == "" {
+		root = strings.TrimPrefix(config.ConnectionString, "file://")
+	}
+	if root == "" {
+		return nil, fmt.Errorf("file provider requires a root directory in Database or ConnectionString")
+	}
+	if err := os.MkdirAll(filepath.Join(root, tempDir), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create file provider root: %w", err)
+	}
+	return &FileProvider{
+		root:    root,
+		started: time.Now(),
+		feed:    changefeed.New("file"),
+	}, nil
+}
+
+// Get reads the data stored at resource
+func (p *FileProvider) Get(ctx context.Context, resource universal.ResourceURI) (data []byte, err error) {
+	defer p.observe(ctx, "get", resource, time.Now(), &err)
+
+	name, err := p.fileName(resource)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, universal.ErrProviderClosed
+	}
+	return readResource(name, resource)
+}
+
+// Set durably writes data to resource and notifies subscribers
+func (p *FileProvider) Set(ctx context.Context, resource universal.ResourceURI, data []byte) (err error) {
+	defer p.observe(ctx, "set", resource, time.Now(), &err)
+
+	name, err := p.fileName(resource)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return universal.ErrProviderClosed
+	}
+	old, err := readResource(name, resource)
+	existed := err == nil
+	if err != nil && !errors.Is(err, universal.ErrResourceNotFound) {
+		p.mu.Unlock()
+		return err
+	}
+	if err = p.write(name, data); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	eventType := "created"
+	if existed {
+		eventType = "updated"
+	}
+	p.feed.Enqueue(changefeed.Event(eventType, "file", resource, old, append([]byte{}, data...)))
+	p.mu.Unlock()
+
+	p.feed.Flush()
+	return nil
+}
+
+// Delete removes the file for resource and notifies subscribers
+func (p *FileProvider) Delete(ctx context.Context, resource universal.ResourceURI) (err error) {
+	defer p.observe(ctx, "delete", resource, time.Now(), &err)
+
+	name, err := p.fileName(resource)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return universal.ErrProviderClosed
+	}
+	old, err := readResource(name, resource)
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	if err = os.Remove(name); err != nil {
+		p.mu.Unlock()
+		return fmt.Errorf("failed to delete %s: %w", resource.String(), err)
+	}
+	if err = syncDir(filepath.Dir(name)); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.feed.Enqueue(changefeed.Event("deleted", "file", resource, old, nil))
+	p.mu.Unlock()
+
+	p.feed.Flush()
+	return nil
+}
+
+// List returns the data of every resource matching pattern, ordered by URI
+func (p *FileProvider) List(ctx context.Context, pattern universal.ResourceURI) (items [][]byte, err error) {
+	defer p.observe(ctx, "list", pattern, time.Now(), &err)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, universal.ErrProviderClosed
+	}
+	matches, err := p.matching(pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		data, err := readResource(match.name, match.uri)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	return items, nil
+}
+
+// Exists reports whether resource is stored, or for a pattern whether any
+// stored resource matches it
+func (p *FileProvider) Exists(ctx context.Context, resource universal.ResourceURI) (bool, error) {
+	count, err := p.Count(ctx, resource)
+	return count > 0, err
+}
+
+// Count returns the number of resources matching pattern without reading them
+func (p *FileProvider) Count(ctx context.Context, pattern universal.ResourceURI) (int64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return 0, universal.ErrProviderClosed
+	}
+	matches, err := p.matching(pattern)
+	return int64(len(matches)), err
+}
+
+// Execute is not supported: the file provider has no query language
+func (p *FileProvider) Execute(ctx context.Context, operation universal.OperationURI, params []byte) ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s", universal.ErrOperationNotSupported.WithProvider("file"), operation.String())
+}
+
+// ExecuteMany is not supported: writes to separate files cannot be made
+// atomic as a batch
+func (p *FileProvider) ExecuteMany(ctx context.Context, operations []universal.Operation) ([][]byte, error) {
+	return nil, fmt.Errorf("%w: batches", universal.ErrOperationNotSupported.WithProvider("file"))
+}
+
+// Subscribe calls callback for every change made through this provider to a
+// resource matching pattern
+func (p *FileProvider) Subscribe(ctx context.Context, pattern universal.ResourceURI, callback universal.ProviderChangeCallback) (universal.SubscriptionID, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return "", universal.ErrProviderClosed
+	}
+	return p.feed.Subscribe(pattern, callback)
+}
+
+// Unsubscribe stops a subscription
+func (p *FileProvider) Unsubscribe(ctx context.Context, id universal.SubscriptionID) error {
+	return p.feed.Unsubscribe(id)
+}
+
+// GetProvider returns the provider type
+func (p *FileProvider) GetProvider() string {
+	return "file"
+}
+
+// Health checks that the root directory is still reachable
+func (p *FileProvider) Health(ctx context.Context) (universal.ProviderHealth, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	health := universal.ProviderHealth{
+		Status:      "healthy",
+		Message:     "file store at " + p.root,
+		LastChecked: time.Now(),
+		Metrics: map[string]any{
+			"root":          p.root,
+			"subscriptions": p.feed.Len(),
+			"uptime":        time.Since(p.started).String(),
+		},
+	}
+	if p.closed {
+		health.Status = "unhealthy"
+		health.Message = "provider is closed"
+	} else if _, err := os.Stat(filepath.Join(p.root, tempDir)); err != nil {
+		health.Status = "unhealthy"
+		health.Message = err.Error()
+	}
+	return health, nil
+}
+
+// Close drops subscriptions; data stays on disk and later calls fail with
+// universal.ErrProviderClosed
+func (p *FileProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.feed.Close()
+	return nil
+}
+
+// SetHookEmitter enables DataOperation hooks for CRUD calls
+func (p *FileProvider) SetHookEmitter(emitter universal.HookEmitter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if emitter == nil {
+		p.instrumentation = nil
+		return
+	}
+	p.instrumentation = universal.NewProviderInstrumentation("file", emitter)
+}
+
+// fileName maps a resource to its file. The whole path is escaped into one
+// file name, so nesting and traversal are impossible and a resource never
+// collides with another's parent.
+func (p *FileProvider) fileName(resource universal.ResourceURI) (string, error) {
+	if resource.IsPattern() {
+		return "", fmt.Errorf("%w: %s is a pattern, not a resource", universal.ErrInvalidURI, resource.String())
+	}
+	switch resource.Path() {
+	case "", ".", "..":
+		return "", fmt.Errorf("%w: %s has no resource path", universal.ErrInvalidURI, resource.String())
+	}
+	return filepath.Join(p.root, resource.Service(), url.PathEscape(resource.Path())), nil
+}
+
+// write replaces name with data via a synced temporary file
+func (p *FileProvider) write(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	temp, err := os.CreateTemp(filepath.Join(p.root, tempDir), "write-*")
+	if err != nil {
+		return fmt.Errorf("failed to stage write: %w", err)
+	}
+	defer os.Remove(temp.Name()) // no-op once renamed
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to stage write: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to sync write: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to stage write: %w", err)
+	}
+	if err := os.Rename(temp.Name(), name); err != nil {
+		return fmt.Errorf("failed to commit write: %w", err)
+	}
+	return syncDir(dir)
+}
+
+type match struct {
+	name string
+	uri  universal.ResourceURI
+}
+
+// matching finds the stored resources matching pattern, ordered by URI;
+// callers hold the lock
+func (p *FileProvider) matching(pattern universal.ResourceURI) ([]match, error) {
+	dir := filepath.Join(p.root, pattern.Service())
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", pattern.String(), err)
+	}
+
+	var matches []match
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue // not written by this provider
+		}
+		uri, err := universal.ParseResourceURI(pattern.Service() + "://" + path)
+		if err != nil || !pattern.Matches(uri) {
+			continue
+		}
+		matches = append(matches, match{name: filepath.Join(dir, entry.Name()), uri: uri})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].uri.Path() < matches[j].uri.Path()
+	})
+	return matches, nil
+}
+
+// readResource reads a resource file, mapping a missing file to
+// universal.ErrResourceNotFound
+func readResource(name string, resource universal.ResourceURI) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", universal.ErrResourceNotFound, resource.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", resource.String(), err)
+	}
+	return data, nil
+}
+
+// syncDir flushes a directory so renames and removals in it survive a crash
+func syncDir(dir string) error {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	defer handle.Close()
+	if err := handle.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// observe emits a DataOperation hook when a hook emitter is set
+func (p *FileProvider) observe(ctx context.Context, operation string, resource universal.ResourceURI, start time.Time, err *error) {
+	p.mu.RLock()
+	instrumentation := p.instrumentation
+	p.mu.RUnlock()
+	if instrumentation != nil {
+		instrumentation.EmitOperation(ctx, operation, resource.String(), time.Since(start), *err)
+	}
+}
@@ -0,0 +1,63 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"zbz/universal"
+	"zbz/universal/providertest"
+)
+
+func openProvider(t *testing.T, root string) universal.Provider {
+	provider, err := NewProvider(universal.DataProviderConfig{Database: root})
+	if err != nil {
+		t.Fatalf("Failed to open provider: %v", err)
+	}
+	return provider
+}
+
+func TestCompliance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) universal.Provider {
+		return openProvider(t, t.TempDir())
+	})
+}
+
+func TestDurableAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	provider := openProvider(t, root)
+	provider.Set(ctx, universal.NewResourceURI("db://users/1"), []byte("ada"))
+	provider.Set(ctx, universal.NewResourceURI("db://users/admins/2"), []byte("grace"))
+	provider.Set(ctx, universal.NewResourceURI("db://users/../../escape"), []byte("contained"))
+	provider.Close()
+
+	reopened, err := NewProvider(universal.DataProviderConfig{ConnectionString: "file://" + root})
+	if err != nil {
+		t.Fatalf("Failed to reopen provider: %v", err)
+	}
+	defer reopened.Close()
+	if data, err := reopened.Get(ctx, universal.NewResourceURI("db://users/1")); err != nil || string(data) != "ada" {
+		t.Errorf("Expected data to survive a reopen, got %s (%v)", data, err)
+	}
+	if count, _ := reopened.Count(ctx, universal.NewResourceURI("db://users/**")); count != 3 {
+		t.Errorf("Expected 3 users after reopening, got %d", count)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(root, tempDir))
+	if len(entries) != 0 {
+		t.Errorf("Expected no staged writes left behind, got %d", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "escape")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected resource paths to stay inside the root")
+	}
+}
+
+func TestRequiresRoot(t *testing.T) {
+	if _, err := NewProvider(universal.DataProviderConfig{}); err == nil {
+		t.Error("Expected an error without a root directory")
+	}
+}
@@ -0,0 +1,136 @@
+// Package changefeed fans provider change events out to subscriptions
+package changefeed
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"zbz/universal"
+)
+
+// Feed holds the subscriptions of one provider. Events are delivered on a
+// publishing goroutine, so callbacks should hand off slow work.
+//
+// Providers Enqueue events while they still hold the lock that orders their
+// writes and Flush them once it is released, so subscribers see changes in
+// the order they were committed.
+type Feed struct {
+	mu            sync.RWMutex
+	source        string
+	next          uint64
+	subscriptions map[universal.SubscriptionID]subscription
+
+	queueMu    sync.Mutex
+	queue      []universal.ProviderChangeEvent
+	delivering bool // A goroutine is flushing the queue
+}
+
+type subscription struct {
+	pattern  universal.ResourceURI
+	callback universal.ProviderChangeCallback
+}
+
+// New creates an empty feed whose subscription IDs are prefixed by source
+func New(source string) *Feed {
+	return &Feed{
+		source:        source,
+		subscriptions: make(map[universal.SubscriptionID]subscription),
+	}
+}
+
+// Subscribe registers callback for changes to resources matching pattern
+func (f *Feed) Subscribe(pattern universal.ResourceURI, callback universal.ProviderChangeCallback) (universal.SubscriptionID, error) {
+	if callback == nil {
+		return "", fmt.Errorf("subscription callback is required")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next++
+	id := universal.SubscriptionID(fmt.Sprintf("%s-%d", f.source, f.next))
+	f.subscriptions[id] = subscription{pattern: pattern, callback: callback}
+	return id, nil
+}
+
+// Unsubscribe removes a subscription
+func (f *Feed) Unsubscribe(id universal.SubscriptionID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.subscriptions[id]; !exists {
+		return fmt.Errorf("%w: %s", universal.ErrSubscriptionNotFound, id)
+	}
+	delete(f.subscriptions, id)
+	return nil
+}
+
+// Publish delivers event to every subscription whose pattern matches its URI
+func (f *Feed) Publish(event universal.ProviderChangeEvent) {
+	f.mu.RLock()
+	var callbacks []universal.ProviderChangeCallback
+	for _, sub := range f.subscriptions {
+		if sub.pattern.Matches(event.URI) {
+			callbacks = append(callbacks, sub.callback)
+		}
+	}
+	f.mu.RUnlock()
+
+	for _, callback := range callbacks {
+		callback(event)
+	}
+}
+
+// Enqueue queues events for Flush. Call it under the provider's write lock,
+// which fixes their order.
+func (f *Feed) Enqueue(events ...universal.ProviderChangeEvent) {
+	f.queueMu.Lock()
+	defer f.queueMu.Unlock()
+	f.queue = append(f.queue, events...)
+}
+
+// Flush publishes queued events in order. If another goroutine is already
+// flushing, it delivers them instead and Flush returns at once; that also
+// covers writes made from a callback, whose events follow the current one.
+func (f *Feed) Flush() {
+	f.queueMu.Lock()
+	if f.delivering {
+		f.queueMu.Unlock()
+		return
+	}
+	f.delivering = true
+	for len(f.queue) > 0 {
+		event := f.queue[0]
+		f.queue = f.queue[1:]
+		f.queueMu.Unlock()
+		f.Publish(event)
+		f.queueMu.Lock()
+	}
+	f.delivering = false
+	f.queueMu.Unlock()
+}
+
+// Event builds a change event of eventType ("created", "updated" or
+// "deleted") raised by source
+func Event(eventType, source string, resource universal.ResourceURI, old, data []byte) universal.ProviderChangeEvent {
+	return universal.ProviderChangeEvent{
+		Type:      eventType,
+		URI:       resource,
+		OldData:   old,
+		NewData:   data,
+		Timestamp: time.Now(),
+		Source:    source,
+	}
+}
+
+// Len returns the number of active subscriptions
+func (f *Feed) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.subscriptions)
+}
+
+// Close drops every subscription
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscriptions = make(map[universal.SubscriptionID]subscription)
+}
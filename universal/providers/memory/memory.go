@@ -0,0 +1,354 @@
+// Package memory is an in-process universal.Provider backed by a map. It
+// supports glob patterns, change subscriptions and atomic batches, which
+// makes it the provider for tests, examples and single-process apps.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"zbz/universal"
+	"zbz/universal/providers/internal/changefeed"
+)
+
+// MemoryProvider implements universal.Provider over an in-memory map
+type MemoryProvider struct {
+	mu      sync.RWMutex
+	items   map[string]entry // canonical URI -> entry
+	closed  bool
+	started time.Time
+
+	feed            *changefeed.Feed
+	instrumentation *universal.ProviderInstrumentation
+}
+
+type entry struct {
+	uri  universal.ResourceURI
+	data []byte
+}
+
+// NewProvider creates an empty memory provider. The config is accepted for
+// ProviderFunction compatibility; nothing in it is required.
+func NewProvider(config universal.DataProviderConfig) (universal.Provider, error) {
+	return &MemoryProvider{
+		items:   make(map[string]entry),
+		started: time.Now(),
+		feed:    changefeed.New("memory"),
+	}, nil
+}
+
+// Get returns a copy of the data stored at resource
+func (p *MemoryProvider) Get(ctx context.Context, resource universal.ResourceURI) (data []byte, err error) {
+	defer p.observe(ctx, "get", resource, time.Now(), &err)
+
+	key, err := keyOf(resource)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, universal.ErrProviderClosed
+	}
+	item, exists := p.items[key]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", universal.ErrResourceNotFound, key)
+	}
+	return clone(item.data), nil
+}
+
+// Set stores a copy of data at resource and notifies subscribers
+func (p *MemoryProvider) Set(ctx context.Context, resource universal.ResourceURI, data []byte) (err error) {
+	defer p.observe(ctx, "set", resource, time.Now(), &err)
+
+	key, err := keyOf(resource)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return universal.ErrProviderClosed
+	}
+	old, existed := p.items[key]
+	p.items[key] = entry{uri: resource, data: clone(data)}
+	p.feed.Enqueue(changefeed.Event(writeEvent(existed), "memory", resource, clone(old.data), clone(data)))
+	p.mu.Unlock()
+
+	p.feed.Flush()
+	return nil
+}
+
+// Delete removes the data stored at resource and notifies subscribers
+func (p *MemoryProvider) Delete(ctx context.Context, resource universal.ResourceURI) (err error) {
+	defer p.observe(ctx, "delete", resource, time.Now(), &err)
+
+	key, err := keyOf(resource)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return universal.ErrProviderClosed
+	}
+	old, existed := p.items[key]
+	if !existed {
+		p.mu.Unlock()
+		return fmt.Errorf("%w: %s", universal.ErrResourceNotFound, key)
+	}
+	delete(p.items, key)
+	p.feed.Enqueue(changefeed.Event("deleted", "memory", resource, clone(old.data), nil))
+	p.mu.Unlock()
+
+	p.feed.Flush()
+	return nil
+}
+
+// List returns the data of every resource matching pattern, ordered by URI
+func (p *MemoryProvider) List(ctx context.Context, pattern universal.ResourceURI) (items [][]byte, err error) {
+	defer p.observe(ctx, "list", pattern, time.Now(), &err)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, universal.ErrProviderClosed
+	}
+	for _, key := range p.matching(pattern) {
+		items = append(items, clone(p.items[key].data))
+	}
+	return items, nil
+}
+
+// Exists reports whether resource is stored, or for a pattern whether any
+// stored resource matches it
+func (p *MemoryProvider) Exists(ctx context.Context, resource universal.ResourceURI) (bool, error) {
+	count, err := p.Count(ctx, resource)
+	return count > 0, err
+}
+
+// Count returns the number of resources matching pattern
+func (p *MemoryProvider) Count(ctx context.Context, pattern universal.ResourceURI) (int64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return 0, universal.ErrProviderClosed
+	}
+	return int64(len(p.matching(pattern))), nil
+}
+
+// Execute is not supported: the memory provider has no query language
+func (p *MemoryProvider) Execute(ctx context.Context, operation universal.OperationURI, params []byte) ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s", universal.ErrOperationNotSupported.WithProvider("memory"), operation.String())
+}
+
+// ExecuteMany applies get, set, delete and exists operations atomically:
+// either every operation succeeds or none of the writes are kept. Set takes
+// its data from Params as []byte, json.RawMessage or string. Results hold
+// the data for get, true/false for exists and nil for writes.
+func (p *MemoryProvider) ExecuteMany(ctx context.Context, operations []universal.Operation) ([][]byte, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, universal.ErrProviderClosed
+	}
+
+	staged := make(map[string]*entry) // nil marks a staged delete
+	lookup := func(key string) (entry, bool) {
+		if item, exists := staged[key]; exists {
+			if item == nil {
+				return entry{}, false
+			}
+			return *item, true
+		}
+		item, exists := p.items[key]
+		return item, exists
+	}
+
+	results := make([][]byte, len(operations))
+	var events []universal.ProviderChangeEvent
+	for i, operation := range operations {
+		resource, err := universal.ParseResourceURI(operation.Target)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("operation %d: %w: %v", i, universal.ErrInvalidURI, err)
+		}
+		key, err := keyOf(resource)
+		if err != nil {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		current, exists := lookup(key)
+
+		switch operation.Type {
+		case "get":
+			if !exists {
+				p.mu.Unlock()
+				return nil, fmt.Errorf("operation %d: %w: %s", i, universal.ErrResourceNotFound, key)
+			}
+			results[i] = clone(current.data)
+		case "exists":
+			results[i] = []byte(fmt.Sprint(exists))
+		case "set":
+			data, err := paramBytes(operation.Params)
+			if err != nil {
+				p.mu.Unlock()
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			staged[key] = &entry{uri: resource, data: data}
+			events = append(events, changefeed.Event(writeEvent(exists), "memory", resource, clone(current.data), clone(data)))
+		case "delete":
+			if !exists {
+				p.mu.Unlock()
+				return nil, fmt.Errorf("operation %d: %w: %s", i, universal.ErrResourceNotFound, key)
+			}
+			staged[key] = nil
+			events = append(events, changefeed.Event("deleted", "memory", resource, clone(current.data), nil))
+		default:
+			p.mu.Unlock()
+			return nil, fmt.Errorf("operation %d: %w: %s", i, universal.ErrOperationNotSupported.WithProvider("memory"), operation.Type)
+		}
+	}
+
+	for key, item := range staged {
+		if item == nil {
+			delete(p.items, key)
+		} else {
+			p.items[key] = *item
+		}
+	}
+	p.feed.Enqueue(events...)
+	p.mu.Unlock()
+
+	p.feed.Flush()
+	return results, nil
+}
+
+// Subscribe calls callback for every change to a resource matching pattern
+func (p *MemoryProvider) Subscribe(ctx context.Context, pattern universal.ResourceURI, callback universal.ProviderChangeCallback) (universal.SubscriptionID, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return "", universal.ErrProviderClosed
+	}
+	return p.feed.Subscribe(pattern, callback)
+}
+
+// Unsubscribe stops a subscription
+func (p *MemoryProvider) Unsubscribe(ctx context.Context, id universal.SubscriptionID) error {
+	return p.feed.Unsubscribe(id)
+}
+
+// GetProvider returns the provider type
+func (p *MemoryProvider) GetProvider() string {
+	return "memory"
+}
+
+// Health reports item and subscription counts
+func (p *MemoryProvider) Health(ctx context.Context) (universal.ProviderHealth, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	health := universal.ProviderHealth{
+		Status:      "healthy",
+		Message:     "in-memory store",
+		LastChecked: time.Now(),
+		Metrics: map[string]any{
+			"items":         len(p.items),
+			"subscriptions": p.feed.Len(),
+			"uptime":        time.Since(p.started).String(),
+		},
+	}
+	if p.closed {
+		health.Status = "unhealthy"
+		health.Message = "provider is closed"
+	}
+	return health, nil
+}
+
+// Close drops all data and subscriptions; later calls fail with
+// universal.ErrProviderClosed
+func (p *MemoryProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.items = make(map[string]entry)
+	p.feed.Close()
+	return nil
+}
+
+// SetHookEmitter enables DataOperation hooks for CRUD calls
+func (p *MemoryProvider) SetHookEmitter(emitter universal.HookEmitter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if emitter == nil {
+		p.instrumentation = nil
+		return
+	}
+	p.instrumentation = universal.NewProviderInstrumentation("memory", emitter)
+}
+
+// matching returns the keys matching pattern in order; callers hold the lock
+func (p *MemoryProvider) matching(pattern universal.ResourceURI) []string {
+	var keys []string
+	for key, item := range p.items {
+		if pattern.Matches(item.uri) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// observe emits a DataOperation hook when a hook emitter is set
+func (p *MemoryProvider) observe(ctx context.Context, operation string, resource universal.ResourceURI, start time.Time, err *error) {
+	p.mu.RLock()
+	instrumentation := p.instrumentation
+	p.mu.RUnlock()
+	if instrumentation != nil {
+		instrumentation.EmitOperation(ctx, operation, resource.String(), time.Since(start), *err)
+	}
+}
+
+// keyOf canonicalizes a URI that addresses a single resource
+func keyOf(resource universal.ResourceURI) (string, error) {
+	if resource.IsPattern() {
+		return "", fmt.Errorf("%w: %s is a pattern, not a resource", universal.ErrInvalidURI, resource.String())
+	}
+	if resource.Path() == "" {
+		return "", fmt.Errorf("%w: %s has no resource path", universal.ErrInvalidURI, resource.String())
+	}
+	return resource.Service() + "://" + resource.Path(), nil
+}
+
+// writeEvent names a write by whether the resource existed before
+func writeEvent(existed bool) string {
+	if existed {
+		return "updated"
+	}
+	return "created"
+}
+
+// paramBytes extracts the data a batched set writes
+func paramBytes(params any) ([]byte, error) {
+	switch value := params.(type) {
+	case []byte:
+		return clone(value), nil
+	case json.RawMessage:
+		return clone(value), nil
+	case string:
+		return []byte(value), nil
+	default:
+		return nil, fmt.Errorf("%w: set params must be []byte, json.RawMessage or string, got %T", universal.ErrSerializationFailed, params)
+	}
+}
+
+func clone(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte{}, data...)
+}
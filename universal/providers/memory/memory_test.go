@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"zbz/universal"
+	"zbz/universal/providertest"
+)
+
+func newTestProvider(t *testing.T) universal.Provider {
+	provider, err := NewProvider(universal.DefaultDataProviderConfig())
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	return provider
+}
+
+func TestCompliance(t *testing.T) {
+	providertest.Run(t, newTestProvider)
+}
+
+func TestExecuteManyIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestProvider(t)
+	provider.Set(ctx, universal.NewResourceURI("db://users/1"), []byte("ada"))
+
+	var events int
+	provider.Subscribe(ctx, universal.NewResourceURI("db://users/*"), func(event universal.ProviderChangeEvent) {
+		events++
+	})
+
+	results, err := provider.ExecuteMany(ctx, []universal.Operation{
+		{Type: "set", Target: "db://users/2", Params: "grace"},
+		{Type: "get", Target: "db://users/2"},
+		{Type: "delete", Target: "db://users/1"},
+		{Type: "exists", Target: "db://users/1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to execute batch: %v", err)
+	}
+	if string(results[1]) != "grace" || string(results[3]) != "false" {
+		t.Errorf("Expected batch reads to see earlier writes, got %q", results)
+	}
+	if events != 2 {
+		t.Errorf("Expected 2 change events, got %d", events)
+	}
+
+	_, err = provider.ExecuteMany(ctx, []universal.Operation{
+		{Type: "set", Target: "db://users/3", Params: []byte("linus")},
+		{Type: "get", Target: "db://users/9"},
+	})
+	if !errors.Is(err, universal.ErrResourceNotFound) {
+		t.Errorf("Expected the failed get to fail the batch, got %v", err)
+	}
+	if exists, _ := provider.Exists(ctx, universal.NewResourceURI("db://users/3")); exists {
+		t.Error("Expected the failed batch to keep none of its writes")
+	}
+
+	if _, err := provider.Execute(ctx, universal.NewOperationURI("db://queries/recent"), nil); !errors.Is(err, universal.ErrOperationNotSupported) {
+		t.Errorf("Expected ErrOperationNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+// Package providertest is the compliance suite for universal.Provider
+// implementations. A provider package runs it from its own tests:
+//
+//	func TestCompliance(t *testing.T) {
+//		providertest.Run(t, func(t *testing.T) universal.Provider {
+//			provider, err := NewProvider(universal.DataProviderConfig{})
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return provider
+//		})
+//	}
+package providertest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"zbz/universal"
+)
+
+// Factory returns a fresh, empty provider for one subtest. Run closes it
+// when the subtest ends.
+type Factory func(t *testing.T) universal.Provider
+
+// eventTimeout bounds how long the suite waits for a change event
+const eventTimeout = 2 * time.Second
+
+// Run checks the behaviour every provider shares: CRUD with
+// universal.ErrResourceNotFound for missing resources, glob List, Count and
+// Exists, change subscriptions delivered in commit order and lifecycle
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, provider universal.Provider)
+	}{
+		{"CRUD", testCRUD},
+		{"Isolation", testIsolation},
+		{"Patterns", testPatterns},
+		{"RejectsPatternWrites", testRejectsPatternWrites},
+		{"Subscribe", testSubscribe},
+		{"Unsubscribe", testUnsubscribe},
+		{"EventOrder", testEventOrder},
+		{"Concurrency", testConcurrency},
+		{"Lifecycle", testLifecycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := factory(t)
+			t.Cleanup(func() { provider.Close() })
+			tt.test(t, provider)
+		})
+	}
+}
+
+func testCRUD(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	resource := universal.NewResourceURI("db://users/1")
+
+	if _, err := provider.Get(ctx, resource); !errors.Is(err, universal.ErrResourceNotFound) {
+		t.Fatalf("Expected ErrResourceNotFound before any write, got %v", err)
+	}
+	if exists, err := provider.Exists(ctx, resource); err != nil || exists {
+		t.Errorf("Expected resource not to exist, got %v (%v)", exists, err)
+	}
+
+	if err := provider.Set(ctx, resource, []byte(`{"name":"Ada"}`)); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if data, err := provider.Get(ctx, resource); err != nil || string(data) != `{"name":"Ada"}` {
+		t.Errorf("Expected the stored data, got %s (%v)", data, err)
+	}
+	if exists, err := provider.Exists(ctx, resource); err != nil || !exists {
+		t.Errorf("Expected resource to exist, got %v (%v)", exists, err)
+	}
+
+	if err := provider.Set(ctx, resource, []byte(`{"name":"Grace"}`)); err != nil {
+		t.Fatalf("Failed to overwrite: %v", err)
+	}
+	if data, _ := provider.Get(ctx, resource); string(data) != `{"name":"Grace"}` {
+		t.Errorf("Expected the overwritten data, got %s", data)
+	}
+
+	if err := provider.Delete(ctx, resource); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := provider.Get(ctx, resource); !errors.Is(err, universal.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound after delete, got %v", err)
+	}
+	if err := provider.Delete(ctx, resource); !errors.Is(err, universal.ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound deleting twice, got %v", err)
+	}
+}
+
+func testIsolation(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	resource := universal.NewResourceURI("db://users/1")
+
+	data := []byte("original")
+	provider.Set(ctx, resource, data)
+	copy(data, "mutated!")
+	stored, _ := provider.Get(ctx, resource)
+	if string(stored) != "original" {
+		t.Errorf("Expected stored data to be independent of the caller's slice, got %s", stored)
+	}
+	copy(stored, "mutated!")
+	if again, _ := provider.Get(ctx, resource); string(again) != "original" {
+		t.Errorf("Expected returned data to be a copy, got %s", again)
+	}
+
+	// Services are separate namespaces
+	provider.Set(ctx, universal.NewResourceURI("cache://users/1"), []byte("cached"))
+	if stored, _ := provider.Get(ctx, resource); string(stored) != "original" {
+		t.Errorf("Expected db and cache resources to be separate, got %s", stored)
+	}
+}
+
+func testPatterns(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	for uri, data := range map[string]string{
+		"db://users/1":             "ada",
+		"db://users/2":             "grace",
+		"db://users/admins/3":      "linus",
+		"db://posts/1":             "hello",
+		"db://posts/draft.md":      "draft",
+		"db://config.json":         "{}",
+		"cache://users/1":          "cached",
+		"db://posts/2024/intro.md": "intro",
+	} {
+		if err := provider.Set(ctx, universal.NewResourceURI(uri), []byte(data)); err != nil {
+			t.Fatalf("Failed to set %s: %v", uri, err)
+		}
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"db://users/*", []string{"ada", "grace"}},
+		{"db://users/**", []string{"ada", "grace", "linus"}},
+		{"db://**/1", []string{"ada", "hello"}},
+		{"db://posts/*.md", []string{"draft"}},
+		{"db://**/*.md", []string{"draft", "intro"}},
+		{"db://*", []string{"{}"}},
+		{"db://users/1", []string{"ada"}},
+		{"db://users/9", nil},
+		{"cache://**", []string{"cached"}},
+		{"search://**", nil},
+	}
+	for _, tt := range tests {
+		pattern := universal.NewResourceURI(tt.pattern)
+
+		items, err := provider.List(ctx, pattern)
+		if err != nil {
+			t.Errorf("List(%s) failed: %v", tt.pattern, err)
+			continue
+		}
+		got := make([]string, len(items))
+		for i, item := range items {
+			got[i] = string(item)
+		}
+		sort.Strings(got)
+		if !equal(got, tt.want) {
+			t.Errorf("List(%s): expected %v, got %v", tt.pattern, tt.want, got)
+		}
+
+		if count, err := provider.Count(ctx, pattern); err != nil || count != int64(len(tt.want)) {
+			t.Errorf("Count(%s): expected %d, got %d (%v)", tt.pattern, len(tt.want), count, err)
+		}
+		if exists, err := provider.Exists(ctx, pattern); err != nil || exists != (len(tt.want) > 0) {
+			t.Errorf("Exists(%s): expected %v, got %v (%v)", tt.pattern, len(tt.want) > 0, exists, err)
+		}
+	}
+}
+
+func testRejectsPatternWrites(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	pattern := universal.NewResourceURI("db://users/*")
+
+	if err := provider.Set(ctx, pattern, []byte("x")); !errors.Is(err, universal.ErrInvalidURI) {
+		t.Errorf("Expected ErrInvalidURI setting a pattern, got %v", err)
+	}
+	if _, err := provider.Get(ctx, pattern); !errors.Is(err, universal.ErrInvalidURI) {
+		t.Errorf("Expected ErrInvalidURI getting a pattern, got %v", err)
+	}
+	if err := provider.Delete(ctx, pattern); !errors.Is(err, universal.ErrInvalidURI) {
+		t.Errorf("Expected ErrInvalidURI deleting a pattern, got %v", err)
+	}
+}
+
+func testSubscribe(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	events := make(chan universal.ProviderChangeEvent, 16)
+	if _, err := provider.Subscribe(ctx, universal.NewResourceURI("db://users/*"), func(event universal.ProviderChangeEvent) {
+		events <- event
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	resource := universal.NewResourceURI("db://users/1")
+
+	provider.Set(ctx, resource, []byte("v1"))
+	expectEvent(t, events, "created", resource, "", "v1")
+
+	provider.Set(ctx, resource, []byte("v2"))
+	expectEvent(t, events, "updated", resource, "v1", "v2")
+
+	// Changes outside the pattern are not delivered: the next event is the
+	// matching delete, not the posts write
+	provider.Set(ctx, universal.NewResourceURI("db://posts/1"), []byte("post"))
+	provider.Set(ctx, universal.NewResourceURI("db://users/admins/1"), []byte("nested"))
+	provider.Delete(ctx, resource)
+	expectEvent(t, events, "deleted", resource, "v2", "")
+}
+
+func testUnsubscribe(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	pattern := universal.NewResourceURI("db://users/*")
+	stopped := make(chan universal.ProviderChangeEvent, 16)
+	id, err := provider.Subscribe(ctx, pattern, func(event universal.ProviderChangeEvent) {
+		stopped <- event
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	running := make(chan universal.ProviderChangeEvent, 16)
+	provider.Subscribe(ctx, pattern, func(event universal.ProviderChangeEvent) {
+		running <- event
+	})
+
+	if err := provider.Unsubscribe(ctx, id); err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+	resource := universal.NewResourceURI("db://users/1")
+	provider.Set(ctx, resource, []byte("v1"))
+	expectEvent(t, running, "created", resource, "", "v1")
+	if len(stopped) != 0 {
+		t.Errorf("Expected no events after unsubscribing, got %d", len(stopped))
+	}
+
+	if err := provider.Unsubscribe(ctx, id); err == nil {
+		t.Error("Expected an error unsubscribing twice")
+	}
+}
+
+// testEventOrder races writers on one resource: each event must start from
+// the data the previous one left, and the last must match what is stored
+func testEventOrder(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	const writers = 50
+	resource := universal.NewResourceURI("db://counters/shared")
+	events := make(chan universal.ProviderChangeEvent, writers)
+	if _, err := provider.Subscribe(ctx, resource, func(event universal.ProviderChangeEvent) {
+		events <- event
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := provider.Set(ctx, resource, []byte(strconv.Itoa(i))); err != nil {
+				t.Errorf("Concurrent set failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	previous := ""
+	for i := 0; i < writers; i++ {
+		select {
+		case event := <-events:
+			if string(event.OldData) != previous {
+				t.Fatalf("Expected event %d to follow %q, got %q -> %q", i, previous, event.OldData, event.NewData)
+			}
+			previous = string(event.NewData)
+		case <-time.After(eventTimeout):
+			t.Fatalf("Timed out waiting for event %d of %d", i+1, writers)
+		}
+	}
+	if stored, err := provider.Get(ctx, resource); err != nil || string(stored) != previous {
+		t.Errorf("Expected the last event to carry the stored data %q, got %q (%v)", stored, previous, err)
+	}
+}
+
+func testConcurrency(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	const writers = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resource := universal.NewResourceURI("db://counters/" + string(rune('a'+i)))
+			if err := provider.Set(ctx, resource, []byte{byte(i)}); err != nil {
+				t.Errorf("Concurrent set failed: %v", err)
+			}
+			provider.Get(ctx, resource)
+			provider.Count(ctx, universal.NewResourceURI("db://counters/*"))
+		}(i)
+	}
+	wg.Wait()
+
+	if count, err := provider.Count(ctx, universal.NewResourceURI("db://counters/*")); err != nil || count != writers {
+		t.Errorf("Expected %d resources after concurrent writes, got %d (%v)", writers, count, err)
+	}
+}
+
+func testLifecycle(t *testing.T, provider universal.Provider) {
+	ctx := context.Background()
+	if provider.GetProvider() == "" {
+		t.Error("Expected a provider type")
+	}
+	if health, err := provider.Health(ctx); err != nil || health.Status != "healthy" {
+		t.Errorf("Expected a healthy provider, got %+v (%v)", health, err)
+	}
+
+	if err := provider.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := provider.Get(ctx, universal.NewResourceURI("db://users/1")); !errors.Is(err, universal.ErrProviderClosed) {
+		t.Errorf("Expected ErrProviderClosed after close, got %v", err)
+	}
+	if err := provider.Set(ctx, universal.NewResourceURI("db://users/1"), []byte("x")); !errors.Is(err, universal.ErrProviderClosed) {
+		t.Errorf("Expected ErrProviderClosed after close, got %v", err)
+	}
+}
+
+// expectEvent waits for the next event and checks it; empty old or new data
+// means none is expected
+func expectEvent(t *testing.T, events <-chan universal.ProviderChangeEvent, eventType string, resource universal.ResourceURI, old, new string) {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.Type != eventType || event.URI.Path() != resource.Path() || event.URI.Service() != resource.Service() {
+			t.Errorf("Expected %s event for %s, got %s for %s", eventType, resource.String(), event.Type, event.URI.String())
+		}
+		if string(event.OldData) != old || string(event.NewData) != new {
+			t.Errorf("Expected %s event data %q -> %q, got %q -> %q", eventType, old, new, event.OldData, event.NewData)
+		}
+		if event.Source == "" || event.Timestamp.IsZero() {
+			t.Errorf("Expected event source and timestamp, got %+v", event)
+		}
+	case <-time.After(eventTimeout):
+		t.Fatalf("Timed out waiting for %s event for %s", eventType, resource.String())
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package universal
 import (
 	"fmt"
 	"net/url"
+	"path"
 	"regexp"
 	"strings"
 )
@@ -147,6 +148,33 @@ func (r ResourceURI) IsPattern() bool {
 	return r.isPattern
 }
 
+// Path returns the resource hierarchy and identifier joined with slashes,
+// without the scheme or query
+func (r ResourceURI) Path() string {
+	if len(r.resource) == 0 {
+		return r.identifier
+	}
+	return r.ResourcePath() + "/" + r.identifier
+}
+
+// Matches reports whether resource is addressed by r. Patterns match one
+// path segment per * segment glob and any number of segments for **; a URI
+// without wildcards matches only itself. Services must be equal.
+//
+// Examples:
+//   - db://users/* matches db://users/123 but not db://users/admins/1
+//   - db://users/** matches both
+//   - bucket://**/*.json matches bucket://a/b/config.json
+func (r ResourceURI) Matches(resource ResourceURI) bool {
+	if r.service != resource.service {
+		return false
+	}
+	if !r.isPattern && !strings.Contains(r.ResourcePath(), "*") {
+		return r.Path() == resource.Path()
+	}
+	return matchSegments(strings.Split(r.Path(), "/"), strings.Split(resource.Path(), "/"))
+}
+
 // HasTemplates returns true if the URI contains template placeholders
 func (r ResourceURI) HasTemplates() bool {
 	return len(r.templates) > 0
@@ -254,6 +282,29 @@ func (r ResourceURI) Join(segments ...string) ResourceURI {
 	return parsed
 }
 
+// matchSegments matches path segments against pattern segments, where **
+// stands for zero or more whole segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, err := path.Match(pattern[0], segments[0]); err != nil || !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
 // extractTemplates finds template placeholders in a string
 func extractTemplates(s string) map[string]string {
 	templates := make(map[string]string)
@@ -116,4 +116,27 @@ func TestResourceURIImmutability(t *testing.T) {
 	if modified.Identifier() != "123" {
 		t.Errorf("Modified URI identifier should be '123', got '%s'", modified.Identifier())
 	}
-}
\ No newline at end of file
+}
+func TestResourceURIMatches(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		resource string
+		want     bool
+	}{
+		{"db://users/*", "db://users/123", true},
+		{"db://users/*", "db://users/admins/1", false},
+		{"db://users/**", "db://users/admins/1", true},
+		{"db://users/**", "db://users/1", true},
+		{"bucket://**/*.json", "bucket://a/b/config.json", true},
+		{"bucket://**/*.json", "bucket://config.json", true},
+		{"bucket://content/*.md", "bucket://content/intro.txt", false},
+		{"db://users/123", "db://users/123", true},
+		{"db://users/123", "db://users/1234", false},
+		{"db://users/*", "cache://users/123", false},
+	}
+	for _, tt := range tests {
+		if got := NewResourceURI(tt.pattern).Matches(NewResourceURI(tt.resource)); got != tt.want {
+			t.Errorf("Expected %s matching %s to be %v, got %v", tt.pattern, tt.resource, tt.want, got)
+		}
+	}
+}
@@ -1,55 +1,51 @@
 package zlog
 
-import "os"
+import "runtime/debug"
 
 // Public API functions - Zero breaking changes
 
 // Debug logs a debug message with structured fields
 func Debug(msg string, fields ...Field) {
-	if !zlog.shouldLog(DEBUG) {
-		return
-	}
-	processedFields := zlog.processFields(fields)
-	writeConsole("DEBUG", msg, processedFields)
-	zlog.emitEvent("DEBUG", msg, processedFields)
+	zlog.log(DEBUG, msg, fields)
 }
 
 // Info logs an info message with structured fields
 func Info(msg string, fields ...Field) {
-	if !zlog.shouldLog(INFO) {
-		return
-	}
-	processedFields := zlog.processFields(fields)
-	writeConsole("INFO", msg, processedFields)
-	zlog.emitEvent("INFO", msg, processedFields)
+	zlog.log(INFO, msg, fields)
 }
 
 // Warn logs a warning message with structured fields
 func Warn(msg string, fields ...Field) {
-	if !zlog.shouldLog(WARN) {
-		return
-	}
-	processedFields := zlog.processFields(fields)
-	writeConsole("WARN", msg, processedFields)
-	zlog.emitEvent("WARN", msg, processedFields)
+	zlog.log(WARN, msg, fields)
 }
 
 // Error logs an error message with structured fields
 func Error(msg string, fields ...Field) {
-	if !zlog.shouldLog(ERROR) {
-		return
-	}
-	processedFields := zlog.processFields(fields)
-	writeConsole("ERROR", msg, processedFields)
-	zlog.emitEvent("ERROR", msg, processedFields)
+	zlog.log(ERROR, msg, fields)
 }
 
-// Fatal logs a fatal message with structured fields and exits
+// Fatal logs a fatal message with structured fields, flushes all sinks,
+// runs registered shutdown hooks and exits
 func Fatal(msg string, fields ...Field) {
-	processedFields := zlog.processFields(fields)
-	writeConsole("FATAL", msg, processedFields)
-	zlog.emitEvent("FATAL", msg, processedFields)
-	os.Exit(1)
+	zlog.log(FATAL, msg, fields)
+	Sync()
+	runShutdownHooks()
+	exit(1)
+}
+
+// RecoverAndLog logs a recovered panic with a full stack and re-panics.
+// It must be deferred directly: defer zlog.RecoverAndLog()
+func RecoverAndLog(fields ...Field) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	panicFields := append([]Field{Any("panic", r)}, fields...)
+	panicFields = append(panicFields, Field{Key: "stacktrace", Type: StackType, Value: string(debug.Stack())})
+	zlog.write(ERROR, "panic recovered", zlog.processFields(panicFields))
+	Sync()
+	panic(r)
 }
 
 // Configuration functions
@@ -67,3 +63,26 @@ func GetLevel() LogLevel {
 	defer zlog.mu.RUnlock()
 	return zlog.level
 }
+
+// SetCaller enables or disables caller annotation; skip adds extra frames
+// for callers that wrap zlog in their own helpers
+func SetCaller(enabled bool, skip int) {
+	zlog.mu.Lock()
+	defer zlog.mu.Unlock()
+	zlog.addCaller = enabled
+	zlog.callerSkip = skip
+}
+
+// SetStacktraceLevel attaches a stacktrace to every message at or above level
+func SetStacktraceLevel(level LogLevel) {
+	zlog.mu.Lock()
+	defer zlog.mu.Unlock()
+	zlog.stacktraceLevel = level
+}
+
+// DisableStacktrace stops automatic stacktrace capture
+func DisableStacktrace() {
+	zlog.mu.Lock()
+	defer zlog.mu.Unlock()
+	zlog.stacktraceLevel = noStacktrace
+}
@@ -0,0 +1,69 @@
+package zlog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// callerDepth is the number of frames between runtime.Caller inside annotate
+// and the user's call site: annotate -> log -> public API function -> caller
+const callerDepth = 3
+
+// annotate appends caller and stacktrace fields according to configuration
+func (z *zZlog) annotate(level LogLevel, fields []Field) []Field {
+	z.mu.RLock()
+	addCaller := z.addCaller
+	skip := z.callerSkip
+	stackLevel := z.stacktraceLevel
+	z.mu.RUnlock()
+
+	if addCaller {
+		if _, file, line, ok := runtime.Caller(callerDepth + skip); ok {
+			fields = append(fields, Field{Key: "caller", Type: CallerType, Value: trimPath(file) + ":" + strconv.Itoa(line)})
+		}
+	}
+
+	if level >= stackLevel {
+		fields = append(fields, Field{Key: "stacktrace", Type: StackType, Value: takeStacktrace(callerDepth + skip)})
+	}
+
+	return fields
+}
+
+// takeStacktrace formats the current stack, skipping zlog's own frames
+func takeStacktrace(skip int) string {
+	pcs := make([]uintptr, 64)
+	// +2 skips runtime.Callers and takeStacktrace itself
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	for {
+		frame, more := frames.Next()
+		buf.WriteString(frame.Function)
+		buf.WriteString("\n\t")
+		buf.WriteString(frame.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(frame.Line))
+		buf.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
+
+// trimPath keeps the last package directory and file name (pkg/file.go)
+func trimPath(file string) string {
+	idx := strings.LastIndexByte(file, '/')
+	if idx == -1 {
+		return file
+	}
+	idx = strings.LastIndexByte(file[:idx], '/')
+	if idx == -1 {
+		return file
+	}
+	return file[idx+1:]
+}
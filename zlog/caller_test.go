@@ -0,0 +1,99 @@
+package zlog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// captureSink records emitted events
+type captureSink struct {
+	events []LogEvent
+}
+
+func (s *captureSink) EmitLogEvent(event LogEvent) {
+	s.events = append(s.events, event)
+}
+
+func capture(t *testing.T) *captureSink {
+	sink := &captureSink{}
+	SetEventSink(sink)
+	t.Cleanup(func() {
+		SetEventSink(nil)
+		SetCaller(false, 0)
+		SetStacktraceLevel(noStacktrace)
+	})
+	return sink
+}
+
+func fieldValue(event LogEvent, key string) (any, bool) {
+	for _, field := range event.Fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// nextLine returns the file:line after its call, in the form zlog reports
+func nextLine() string {
+	_, file, line, _ := runtime.Caller(1)
+	return trimPath(file) + ":" + strconv.Itoa(line+1)
+}
+
+// logThroughHelper wraps zlog the way an application helper would
+func logThroughHelper(msg string) {
+	Info(msg)
+}
+
+func TestCallerReportsCallSite(t *testing.T) {
+	sink := capture(t)
+	SetCaller(true, 0)
+
+	want := nextLine()
+	Info("direct")
+	wantError := nextLine()
+	Error("error")
+
+	if caller, _ := fieldValue(sink.events[0], "caller"); caller != want {
+		t.Errorf("Expected caller %s, got %v", want, caller)
+	}
+	if caller, _ := fieldValue(sink.events[1], "caller"); caller != wantError {
+		t.Errorf("Expected caller %s, got %v", wantError, caller)
+	}
+}
+
+func TestCallerSkipForWrappers(t *testing.T) {
+	sink := capture(t)
+	SetCaller(true, 1)
+
+	want := nextLine()
+	logThroughHelper("wrapped")
+
+	if caller, _ := fieldValue(sink.events[0], "caller"); caller != want {
+		t.Errorf("Expected the helper's caller %s, got %v", want, caller)
+	}
+}
+
+func TestStacktraceStartsAtCallSite(t *testing.T) {
+	sink := capture(t)
+	SetStacktraceLevel(ERROR)
+
+	Info("no stack")
+	Error("with stack")
+
+	if _, exists := fieldValue(sink.events[0], "stacktrace"); exists {
+		t.Error("Expected no stacktrace below the stacktrace level")
+	}
+	stack, _ := fieldValue(sink.events[1], "stacktrace")
+	first, _, _ := strings.Cut(stack.(string), "\n")
+	if !strings.HasSuffix(first, "TestStacktraceStartsAtCallSite") {
+		t.Errorf("Expected the stack to start at the test, got %q", first)
+	}
+}
+
+func TestNonStringStackField(t *testing.T) {
+	// A hand-built stack field with a non-string value must not panic
+	writeConsole("INFO", "odd stack", []Field{{Key: "stacktrace", Type: StackType, Value: 42}})
+}
@@ -16,6 +16,11 @@ type Config struct {
 	BufferSize int    `yaml:"buffer_size,omitempty" json:"buffer_size,omitempty"`
 	FlushLevel string `yaml:"flush_level,omitempty" json:"flush_level,omitempty"`
 
+	// Caller and stacktrace annotation
+	AddCaller       bool   `yaml:"add_caller,omitempty" json:"add_caller,omitempty"`             // Annotate lines with file:line
+	CallerSkip      int    `yaml:"caller_skip,omitempty" json:"caller_skip,omitempty"`           // Extra frames to skip for wrappers
+	StacktraceLevel string `yaml:"stacktrace_level,omitempty" json:"stacktrace_level,omitempty"` // Capture stacks at or above; empty disables
+
 	// Sampling configuration (for high-volume scenarios)
 	Sampling *SamplingConfig `yaml:"sampling,omitempty" json:"sampling,omitempty"`
 
@@ -43,38 +48,43 @@ type SamplingConfig struct {
 // DefaultConfig returns sensible defaults for zlog configuration
 func DefaultConfig() Config {
 	return Config{
-		Name:        "zlog",
-		Level:       INFO,
-		Format:      "json",
-		Development: false,
-		Console:     true,
-		BufferSize:  1024,
-		FlushLevel:  "error",
+		Name:            "zlog",
+		Level:           INFO,
+		Format:          "json",
+		Development:     false,
+		Console:         true,
+		BufferSize:      1024,
+		FlushLevel:      "error",
+		StacktraceLevel: "fatal",
 	}
 }
 
 // DevelopmentConfig returns configuration suitable for development
 func DevelopmentConfig() Config {
 	return Config{
-		Name:        "zlog-dev",
-		Level:       DEBUG,
-		Format:      "console",
-		Development: true,
-		Console:     true,
-		BufferSize:  512,
+		Name:            "zlog-dev",
+		Level:           DEBUG,
+		Format:          "console",
+		Development:     true,
+		Console:         true,
+		BufferSize:      512,
+		AddCaller:       true,
+		StacktraceLevel: "warn",
 	}
 }
 
 // ProductionConfig returns configuration suitable for production
 func ProductionConfig() Config {
 	return Config{
-		Name:        "zlog-prod",
-		Level:       INFO,
-		Format:      "json",
-		Development: false,
-		Console:     false, // Production usually goes to files/external storage
-		BufferSize:  4096,
-		FlushLevel:  "warn",
+		Name:            "zlog-prod",
+		Level:           INFO,
+		Format:          "json",
+		Development:     false,
+		Console:         false, // Production usually goes to files/external storage
+		BufferSize:      4096,
+		FlushLevel:      "warn",
+		AddCaller:       true,
+		StacktraceLevel: "error",
 	}
 }
 
@@ -75,3 +75,14 @@ func Strings(key string, value []string) Field {
 }
 
 
+
+// Annotation field types added by zlog itself
+const (
+	CallerType FieldType = "caller"
+	StackType  FieldType = "stack"
+)
+
+// Stack captures the current goroutine's stack under key
+func Stack(key string) Field {
+	return Field{Key: key, Type: StackType, Value: takeStacktrace(1)}
+}
@@ -1,5 +1,7 @@
 package zlog
 
+import "strings"
+
 // LogLevel represents universal log levels
 type LogLevel int
 
@@ -11,6 +13,9 @@ const (
 	FATAL
 )
 
+// noStacktrace disables automatic stacktrace capture
+const noStacktrace LogLevel = FATAL + 1
+
 // String returns the string representation of the log level
 func (l LogLevel) String() string {
 	switch l {
@@ -28,3 +33,21 @@ func (l LogLevel) String() string {
 		return "info"
 	}
 }
+
+// ParseLevel converts a level name ("debug", "info", ...) to a LogLevel
+func ParseLevel(name string) (LogLevel, bool) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return DEBUG, true
+	case "info":
+		return INFO, true
+	case "warn", "warning":
+		return WARN, true
+	case "error":
+		return ERROR, true
+	case "fatal":
+		return FATAL, true
+	default:
+		return INFO, false
+	}
+}
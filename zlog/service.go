@@ -20,6 +20,9 @@ type zZlog struct {
 	level         LogLevel                                     // Current log level
 	fieldContract *pipz.ServiceContract[FieldType, Field, []Field] // pipz contract for field processing
 	eventSink     EventSink                                    // Optional event emission
+	addCaller       bool                                       // Annotate entries with file:line
+	callerSkip      int                                        // Extra frames to skip when resolving caller
+	stacktraceLevel LogLevel                                   // Capture stacks at or above this level
 	mu            sync.RWMutex                                 // Protect concurrent access
 }
 
@@ -46,6 +49,12 @@ func Configure(config Config) {
 	
 	zlog.config = config
 	zlog.level = config.Level
+	zlog.addCaller = config.AddCaller
+	zlog.callerSkip = config.CallerSkip
+	zlog.stacktraceLevel = noStacktrace
+	if level, ok := ParseLevel(config.StacktraceLevel); ok {
+		zlog.stacktraceLevel = level
+	}
 }
 
 // SetEventSink enables optional event emission
@@ -62,6 +71,24 @@ func RegisterFieldProcessor(fieldType FieldType, processor FieldProcessor) {
 	zlog.fieldContract.Register(fieldType, pipzProcessor)
 }
 
+// log is the shared path for all level functions - callers must invoke it
+// directly from the public API so caller depth stays constant
+func (z *zZlog) log(level LogLevel, msg string, fields []Field) {
+	if level != FATAL && !z.shouldLog(level) {
+		return
+	}
+	processed := z.processFields(fields)
+	processed = z.annotate(level, processed)
+	z.write(level, msg, processed)
+}
+
+// write sends processed fields to console and the optional event sink
+func (z *zZlog) write(level LogLevel, msg string, fields []Field) {
+	name := strings.ToUpper(level.String())
+	writeConsole(name, msg, fields)
+	z.emitEvent(name, msg, fields)
+}
+
 // processFields processes fields through custom processors using pipz contract
 func (z *zZlog) processFields(fields []Field) []Field {
 	// Process fields by type using pipz contract
@@ -124,6 +151,9 @@ func putBuffer(buf *bytes.Buffer) {
 	bufferPool.Put(buf)
 }
 
+// consoleMu keeps multi-line entries (stacktraces) from interleaving
+var consoleMu sync.Mutex
+
 // writeConsole writes directly to stdout with zero allocation
 func writeConsole(level, msg string, fields []Field) {
 	buf := getBuffer()
@@ -136,15 +166,28 @@ func writeConsole(level, msg string, fields []Field) {
 	buf.WriteByte(' ')
 	buf.WriteString(msg)
 	
-	// Add fields
+	// Add fields - stacks go on their own lines after the entry
+	var stack string
 	for _, field := range fields {
+		if field.Type == StackType {
+			stack = fmt.Sprint(field.Value)
+			continue
+		}
 		buf.WriteByte(' ')
 		buf.WriteString(field.Key)
 		buf.WriteByte('=')
 		formatFieldValue(buf, field)
 	}
 	buf.WriteByte('\n')
+	if stack != "" {
+		buf.WriteString(stack)
+		if !strings.HasSuffix(stack, "\n") {
+			buf.WriteByte('\n')
+		}
+	}
 	
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
 	os.Stdout.Write(buf.Bytes())
 }
 
@@ -191,8 +234,9 @@ func formatFieldValue(buf *bytes.Buffer, field Field) {
 // init sets up default zlog with pipz contract
 func init() {
 	zlog = &zZlog{
-		config:        DefaultConfig(),
-		level:         INFO,
-		fieldContract: pipz.GetContract[FieldType, Field, []Field](),
+		config:          DefaultConfig(),
+		level:           INFO,
+		fieldContract:   pipz.GetContract[FieldType, Field, []Field](),
+		stacktraceLevel: FATAL,
 	}
 }
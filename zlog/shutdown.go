@@ -0,0 +1,61 @@
+package zlog
+
+import (
+	"os"
+	"sync"
+)
+
+// Flusher is implemented by sinks that buffer entries and need an explicit flush
+type Flusher interface {
+	Flush() error
+}
+
+// exit is a variable so Fatal can be exercised without terminating the process
+var exit = os.Exit
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func()
+)
+
+// OnShutdown registers a hook that Fatal runs after flushing sinks.
+// Hooks run in reverse registration order, like defers.
+func OnShutdown(hook func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// Sync flushes the console and any event sink that implements Flusher
+func Sync() error {
+	zlog.mu.RLock()
+	sink := zlog.eventSink
+	zlog.mu.RUnlock()
+
+	var firstErr error
+	if flusher, ok := sink.(Flusher); ok {
+		firstErr = flusher.Flush()
+	}
+
+	consoleMu.Lock()
+	os.Stdout.Sync() // Errors are expected when stdout is a pipe or terminal
+	consoleMu.Unlock()
+
+	return firstErr
+}
+
+// runShutdownHooks executes registered hooks, isolating panics so one bad
+// hook cannot prevent the rest from running
+func runShutdownHooks() {
+	shutdownMu.Lock()
+	hooks := make([]func(), len(shutdownHooks))
+	copy(hooks, shutdownHooks)
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		func() {
+			defer func() { recover() }()
+			hooks[i]()
+		}()
+	}
+}
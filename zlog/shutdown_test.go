@@ -0,0 +1,117 @@
+package zlog
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// flushSink records events and flushes into a shared call log
+type flushSink struct {
+	captureSink
+	calls *[]string
+}
+
+func (s *flushSink) EmitLogEvent(event LogEvent) {
+	s.captureSink.EmitLogEvent(event)
+	*s.calls = append(*s.calls, "log:"+event.Message)
+}
+
+func (s *flushSink) Flush() error {
+	*s.calls = append(*s.calls, "flush")
+	return nil
+}
+
+// fakeExit swaps exit for one that records its code instead of terminating
+func fakeExit(t *testing.T, calls *[]string) {
+	original := exit
+	t.Cleanup(func() {
+		exit = original
+		shutdownMu.Lock()
+		shutdownHooks = nil
+		shutdownMu.Unlock()
+		SetEventSink(nil)
+	})
+	exit = func(code int) {
+		*calls = append(*calls, "exit:"+strconv.Itoa(code))
+	}
+}
+
+func TestFatalFlushesThenRunsHooksThenExits(t *testing.T) {
+	var calls []string
+	fakeExit(t, &calls)
+	SetEventSink(&flushSink{calls: &calls})
+
+	OnShutdown(func() { calls = append(calls, "hook:first") })
+	OnShutdown(func() { calls = append(calls, "hook:second") })
+
+	Fatal("stopping")
+
+	want := []string{"log:stopping", "flush", "hook:second", "hook:first", "exit:1"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected %v, got %v", want, calls)
+	}
+}
+
+func TestShutdownHookPanicIsIsolated(t *testing.T) {
+	var calls []string
+	fakeExit(t, &calls)
+
+	OnShutdown(func() { calls = append(calls, "hook:first") })
+	OnShutdown(func() { panic("bad hook") })
+	OnShutdown(func() { calls = append(calls, "hook:third") })
+
+	Fatal("stopping")
+
+	want := []string{"hook:third", "hook:first", "exit:1"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected the panicking hook to be skipped, got %v", calls)
+	}
+}
+
+func TestRecoverAndLogRepanics(t *testing.T) {
+	var calls []string
+	sink := &flushSink{calls: &calls}
+	SetEventSink(sink)
+	t.Cleanup(func() { SetEventSink(nil) })
+
+	recovered := func() (r any) {
+		defer func() { r = recover() }()
+		func() {
+			defer RecoverAndLog(String("job", "import"))
+			panic("boom")
+		}()
+		return nil
+	}()
+
+	if recovered != "boom" {
+		t.Fatalf("Expected the original panic to propagate, got %v", recovered)
+	}
+	if want := []string{"log:panic recovered", "flush"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected %v, got %v", want, calls)
+	}
+	event := sink.events[0]
+	if value, _ := fieldValue(event, "panic"); value != "boom" {
+		t.Errorf("Expected the panic value to be logged, got %v", value)
+	}
+	if value, _ := fieldValue(event, "job"); value != "import" {
+		t.Errorf("Expected caller fields to be logged, got %v", value)
+	}
+	if stack, _ := fieldValue(event, "stacktrace"); stack == nil || stack == "" {
+		t.Error("Expected a stacktrace with the recovered panic")
+	}
+}
+
+func TestRecoverAndLogWithoutPanic(t *testing.T) {
+	var calls []string
+	SetEventSink(&flushSink{calls: &calls})
+	t.Cleanup(func() { SetEventSink(nil) })
+
+	func() {
+		defer RecoverAndLog()
+	}()
+
+	if len(calls) != 0 {
+		t.Errorf("Expected nothing logged without a panic, got %v", calls)
+	}
+}